@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ovinc/openwrt-metrics/collector"
+)
+
+// register the json api endpoints of the collectors that provide them
+func registerAPI(mux *http.ServeMux, collectors []collector.NamedCollector) {
+	for _, c := range collectors {
+		switch named := collector.Unwrap(c.Collector).(type) {
+		case *collector.DeviceCollector:
+			mux.Handle("/api/v1/devices", jsonHandler(func(ctx context.Context) (any, error) { return named.Devices(ctx) }))
+		case *collector.NetworkInterfaceCollector:
+			mux.Handle("/api/v1/interfaces", jsonHandler(func(ctx context.Context) (any, error) { return named.Interfaces(ctx) }))
+		}
+	}
+}
+
+// http handler serving the result of fetch as json, commands are cancelled when the client goes away
+func jsonHandler(fetch func(ctx context.Context) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := fetch(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	})
+}
@@ -0,0 +1,220 @@
+package collector
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// default acme.sh state directory on openwrt
+const defaultACMEStateDir = "/etc/acme"
+
+// acme.sh runs its renewal cron job once a day, so allow a day before calling a renewal failed
+const acmeRenewalGrace = 24 * time.Hour
+
+// acme.sh certificate renewal collector
+type ACMECollector struct {
+	nextRenewal    *prometheus.Desc
+	lastRenewal    *prometheus.Desc
+	expiry         *prometheus.Desc
+	renewalSuccess *prometheus.Desc
+	stateDir       string
+}
+
+// acme collector configuration
+type ACMEConfig struct {
+	StateDir string
+}
+
+// create a new acme collector
+func NewACMECollector(config *ACMEConfig) *ACMECollector {
+	labels := []string{"domain"}
+
+	return &ACMECollector{
+		nextRenewal: prometheus.NewDesc(
+			"openwrt_acme_certificate_next_renewal_timestamp_seconds",
+			"time acme.sh schedules the next renewal of the certificate",
+			labels, nil,
+		),
+		lastRenewal: prometheus.NewDesc(
+			"openwrt_acme_certificate_last_renewal_timestamp_seconds",
+			"time the certificate was last issued or renewed successfully",
+			labels, nil,
+		),
+		expiry: prometheus.NewDesc(
+			"openwrt_acme_certificate_expiry_timestamp_seconds",
+			"expiry time of the issued certificate",
+			labels, nil,
+		),
+		renewalSuccess: prometheus.NewDesc(
+			"openwrt_acme_certificate_renewal_success",
+			"whether the last scheduled renewal succeeded (0 = renewal overdue by more than a day)",
+			labels, nil,
+		),
+		stateDir: config.StateDir,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ACMECollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.nextRenewal
+	ch <- c.lastRenewal
+	ch <- c.expiry
+	ch <- c.renewalSuccess
+}
+
+// collect implements prometheus.Collector
+func (c *ACMECollector) Collect(ch chan<- prometheus.Metric) {
+	certs, err := getACMECertificates(c.stateDir)
+	if err != nil {
+		logCollectError("acme", err)
+		return
+	}
+
+	now := time.Now()
+	for _, cert := range certs {
+		if !cert.NextRenewal.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.nextRenewal,
+				prometheus.GaugeValue,
+				float64(cert.NextRenewal.Unix()),
+				cert.Domain,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.renewalSuccess,
+				prometheus.GaugeValue,
+				boolToFloat64(now.Before(cert.NextRenewal.Add(acmeRenewalGrace))),
+				cert.Domain,
+			)
+		}
+		if !cert.LastRenewal.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.lastRenewal,
+				prometheus.GaugeValue,
+				float64(cert.LastRenewal.Unix()),
+				cert.Domain,
+			)
+		}
+		if !cert.Expiry.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.expiry,
+				prometheus.GaugeValue,
+				float64(cert.Expiry.Unix()),
+				cert.Domain,
+			)
+		}
+	}
+}
+
+// load acme configuration from environment variables
+func loadACMEConfig() *ACMEConfig {
+	config := &ACMEConfig{
+		StateDir: defaultACMEStateDir,
+	}
+
+	// acme_state_dir: acme.sh state directory
+	if stateDir := os.Getenv("ACME_STATE_DIR"); stateDir != "" {
+		config.StateDir = stateDir
+	}
+
+	return config
+}
+
+// acme.sh certificate state
+type ACMECertificate struct {
+	Domain      string
+	NextRenewal time.Time
+	LastRenewal time.Time
+	Expiry      time.Time
+}
+
+// read certificate state from acme.sh domain directories (<domain>/ or <domain>_ecc/)
+func getACMECertificates(stateDir string) ([]ACMECertificate, error) {
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []ACMECertificate
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		domain := strings.TrimSuffix(entry.Name(), "_ecc")
+		dir := filepath.Join(stateDir, entry.Name())
+
+		conf, err := parseACMEConf(filepath.Join(dir, domain+".conf"))
+		if err != nil {
+			continue
+		}
+		if conf["Le_Domain"] != "" {
+			domain = conf["Le_Domain"]
+		}
+
+		cert := ACMECertificate{
+			Domain:      domain,
+			NextRenewal: parseUnixTime(conf["Le_NextRenewTime"]),
+			LastRenewal: parseUnixTime(conf["Le_CertCreateTime"]),
+			Expiry:      readCertificateExpiry(filepath.Join(dir, domain+".cer")),
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
+
+// parse an acme.sh domain config file of Key='value' lines
+func parseACMEConf(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	conf := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		conf[key] = strings.Trim(value, `'"`)
+	}
+
+	return conf, scanner.Err()
+}
+
+// parse a unix timestamp, returning the zero time if it is missing
+func parseUnixTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// read the expiry time of the first certificate in a pem file
+func readCertificateExpiry(path string) time.Time {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}
+	}
+	return cert.NotAfter
+}
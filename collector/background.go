@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// background work of collectors (probes, samplers, listeners) and the commands they run
+// stop once the context of the configuration passed to All is cancelled
+var (
+	backgroundCtx  = context.Background()
+	backgroundWait sync.WaitGroup
+)
+
+// replace the context bounding background work and commands (nil keeps the current one)
+func setBackgroundContext(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	backgroundCtx = ctx
+}
+
+// run a background loop of a collector with the current background context
+func goBackground(run func(ctx context.Context)) {
+	ctx := backgroundCtx
+	backgroundWait.Add(1)
+	go func() {
+		defer backgroundWait.Done()
+		run(ctx)
+	}()
+}
+
+// Wait blocks until the background work of all collectors has stopped, which happens
+// after the context of the configuration passed to All is cancelled
+func Wait() {
+	backgroundWait.Wait()
+}
+
+// wait for the next tick of a ticker, false once the context is cancelled
+func nextTick(ctx context.Context, ticker *time.Ticker) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-ticker.C:
+		return true
+	}
+}
+
+// sleep for a duration, false if the context is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
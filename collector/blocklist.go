@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runtime files written by adblock and banip, newest location first
+var (
+	adblockRuntimeFiles = []string{"/var/run/adb_runtime.json", "/tmp/adb_runtime.json"}
+	banIPRuntimeFiles   = []string{"/var/run/banip_runtime.json", "/tmp/ban_runtime.json"}
+)
+
+// nftables table used by banip
+const banIPTable = "banIP"
+
+// leading number of runtime counters such as "12345 (chains: 7, sets: 22)"
+var leadingNumberPattern = regexp.MustCompile(`^\s*(\d+)`)
+
+// adblock and banip list status collector
+type BlocklistCollector struct {
+	adblockEnabled *prometheus.Desc
+	adblockDomains *prometheus.Desc
+	adblockLastRun *prometheus.Desc
+	banIPActive    *prometheus.Desc
+	banIPElements  *prometheus.Desc
+	banIPLastRun   *prometheus.Desc
+	banIPPackets   *prometheus.Desc
+	banIPBytes     *prometheus.Desc
+}
+
+// create a new blocklist collector
+func NewBlocklistCollector() *BlocklistCollector {
+	return &BlocklistCollector{
+		adblockEnabled: prometheus.NewDesc(
+			"openwrt_adblock_enabled",
+			"whether adblock reports its status as enabled (1 = enabled)",
+			nil, nil,
+		),
+		adblockDomains: prometheus.NewDesc(
+			"openwrt_adblock_blocked_domains",
+			"number of blocked domains loaded by adblock",
+			nil, nil,
+		),
+		adblockLastRun: prometheus.NewDesc(
+			"openwrt_adblock_last_run_timestamp_seconds",
+			"time of the last adblock list refresh",
+			nil, nil,
+		),
+		banIPActive: prometheus.NewDesc(
+			"openwrt_banip_active",
+			"whether banip reports its status as active (1 = active)",
+			nil, nil,
+		),
+		banIPElements: prometheus.NewDesc(
+			"openwrt_banip_elements",
+			"number of ip addresses and prefixes loaded into banip sets",
+			nil, nil,
+		),
+		banIPLastRun: prometheus.NewDesc(
+			"openwrt_banip_last_run_timestamp_seconds",
+			"time of the last banip list refresh",
+			nil, nil,
+		),
+		banIPPackets: prometheus.NewDesc(
+			"openwrt_banip_blocked_packets_total",
+			"total number of packets blocked by rules matching a banip set",
+			[]string{"set"}, nil,
+		),
+		banIPBytes: prometheus.NewDesc(
+			"openwrt_banip_blocked_bytes_total",
+			"total number of bytes blocked by rules matching a banip set",
+			[]string{"set"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *BlocklistCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.adblockEnabled
+	ch <- c.adblockDomains
+	ch <- c.adblockLastRun
+	ch <- c.banIPActive
+	ch <- c.banIPElements
+	ch <- c.banIPLastRun
+	ch <- c.banIPPackets
+	ch <- c.banIPBytes
+}
+
+// collect implements prometheus.Collector
+func (c *BlocklistCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *BlocklistCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	if status, err := readBlocklistRuntime(adblockRuntimeFiles); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.adblockEnabled, prometheus.GaugeValue, boolToFloat64(status.string("adblock_status") == "enabled"))
+		if domains, ok := status.count("blocked_domains"); ok {
+			ch <- prometheus.MustNewConstMetric(c.adblockDomains, prometheus.GaugeValue, domains)
+		}
+		if lastRun := parseBlocklistRunTime(status.string("last_run")); !lastRun.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.adblockLastRun, prometheus.GaugeValue, float64(lastRun.Unix()))
+		}
+	} else {
+		logCollectError("adblock", err)
+	}
+
+	status, err := readBlocklistRuntime(banIPRuntimeFiles)
+	if err != nil {
+		logCollectError("banip", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.banIPActive, prometheus.GaugeValue, boolToFloat64(status.string("status") == "active"))
+	if elements, ok := status.count("element_count"); ok {
+		ch <- prometheus.MustNewConstMetric(c.banIPElements, prometheus.GaugeValue, elements)
+	}
+	if lastRun := parseBlocklistRunTime(status.string("last_run")); !lastRun.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.banIPLastRun, prometheus.GaugeValue, float64(lastRun.Unix()))
+	}
+
+	counters, err := getBanIPSetCounters(ctx)
+	if err != nil {
+		logCollectError("banip counter", err)
+		return
+	}
+	for set, counter := range counters {
+		ch <- prometheus.MustNewConstMetric(c.banIPPackets, prometheus.CounterValue, counter.Packets, set)
+		ch <- prometheus.MustNewConstMetric(c.banIPBytes, prometheus.CounterValue, counter.Bytes, set)
+	}
+}
+
+// adblock or banip runtime status
+type blocklistRuntime map[string]any
+
+// get a string field of the runtime status
+func (r blocklistRuntime) string(key string) string {
+	value, _ := r[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// get the leading number of a runtime counter field
+func (r blocklistRuntime) count(key string) (float64, bool) {
+	if number, ok := r[key].(float64); ok {
+		return number, true
+	}
+	match := leadingNumberPattern.FindStringSubmatch(r.string(key))
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(match[1], 64)
+	return number, err == nil
+}
+
+// read the first existing runtime file (older versions nest the status under "data")
+func readBlocklistRuntime(paths []string) (blocklistRuntime, error) {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var status blocklistRuntime
+		if err := json.Unmarshal(data, &status); err != nil {
+			return nil, err
+		}
+		if nested, ok := status["data"].(map[string]any); ok {
+			status = nested
+		}
+		return status, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// parse the date at the end of a last_run field, e.g.
+// "start, 0m 17s, 249/113/150, 2024-03-11T08:00:04+01:00" (adblock) or
+// "action: reload, ..., date: 2024-03-11 08:00:04" (banip)
+func parseBlocklistRunTime(lastRun string) time.Time {
+	if i := strings.LastIndex(lastRun, ","); i >= 0 {
+		lastRun = lastRun[i+1:]
+	}
+	lastRun = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lastRun), "date:"))
+
+	if parsed, err := time.Parse(time.RFC3339, lastRun); err == nil {
+		return parsed
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "02.01.2006 15:04:05"} {
+		if parsed, err := time.ParseInLocation(layout, lastRun, time.Local); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// sum counters of blocking rules per banip set (allowlist rules accept and are skipped)
+func getBanIPSetCounters(ctx context.Context) (map[string]NftCounter, error) {
+	rules, err := getNftRules(ctx, "inet", banIPTable)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]NftCounter)
+	for _, rule := range rules {
+		if rule.Counter == nil || rule.Verdict == "accept" {
+			continue
+		}
+		for _, set := range rule.Sets {
+			counter := counters[set]
+			counter.Packets += rule.Counter.Packets
+			counter.Bytes += rule.Counter.Bytes
+			counters[set] = counter
+		}
+	}
+	return counters, nil
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// size of struct __fdb_entry in /sys/class/net/<bridge>/brforward (linux/if_bridge.h)
+const fdbEntrySize = 16
+
+// bridge forwarding database collector
+type BridgeFDBCollector struct {
+	entries *prometheus.Desc
+	info    *prometheus.Desc
+}
+
+// create a new bridge fdb collector
+func NewBridgeFDBCollector() *BridgeFDBCollector {
+	return &BridgeFDBCollector{
+		entries: prometheus.NewDesc(
+			"openwrt_bridge_fdb_entries",
+			"number of mac addresses learned on a bridge port",
+			[]string{"bridge", "port"}, nil,
+		),
+		info: prometheus.NewDesc(
+			"openwrt_bridge_fdb_info",
+			"bridge port a mac address was learned on",
+			[]string{"bridge", "port", "mac"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *BridgeFDBCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.info
+}
+
+// collect implements prometheus.Collector
+func (c *BridgeFDBCollector) Collect(ch chan<- prometheus.Metric) {
+	bridges, err := getBridges()
+	if err != nil {
+		logCollectError("bridge fdb", err)
+		return
+	}
+
+	for _, bridge := range bridges {
+		entries, err := getBridgeFDB(bridge)
+		if err != nil {
+			slog.Warn("failed to read bridge fdb", "bridge", bridge, "err", err)
+			continue
+		}
+
+		counts := make(map[string]float64)
+		for _, entry := range entries {
+			counts[entry.Port]++
+			ch <- prometheus.MustNewConstMetric(
+				c.info,
+				prometheus.GaugeValue,
+				1,
+				bridge, entry.Port, privateMAC(entry.MAC),
+			)
+		}
+		for port, count := range counts {
+			ch <- prometheus.MustNewConstMetric(
+				c.entries,
+				prometheus.GaugeValue,
+				count,
+				bridge, port,
+			)
+		}
+	}
+}
+
+// mac address learned on a bridge port
+type BridgeFDBEntry struct {
+	MAC  string
+	Port string
+}
+
+// get the names of all bridge devices
+func getBridges() ([]string, error) {
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return nil, err
+	}
+
+	var bridges []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join("/sys/class/net", entry.Name(), "bridge")); err == nil {
+			bridges = append(bridges, entry.Name())
+		}
+	}
+	return bridges, nil
+}
+
+// get the learned (non-local) fdb entries of a bridge from /sys/class/net/<bridge>/brforward
+func getBridgeFDB(bridge string) ([]BridgeFDBEntry, error) {
+	// port numbers of the bridge member interfaces, e.g. brif/lan1/port_no = 0x1
+	ports := make(map[uint16]string)
+	members, err := os.ReadDir(filepath.Join("/sys/class/net", bridge, "brif"))
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		portNo := readSysfsString(filepath.Join("/sys/class/net", bridge, "brif", member.Name(), "port_no"))
+		if number, err := strconv.ParseUint(strings.TrimPrefix(portNo, "0x"), 16, 16); err == nil {
+			ports[uint16(number)] = member.Name()
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", bridge, "brforward"))
+	if err != nil {
+		return nil, err
+	}
+
+	// struct __fdb_entry: mac_addr[6], port_no, is_local, ageing_timer_value (u32), port_hi, pad, unused (u16)
+	var entries []BridgeFDBEntry
+	for offset := 0; offset+fdbEntrySize <= len(data); offset += fdbEntrySize {
+		entry := data[offset : offset+fdbEntrySize]
+		if entry[7] != 0 {
+			// the bridge's own addresses
+			continue
+		}
+
+		portNo := uint16(entry[12])<<8 | uint16(entry[6])
+		port, ok := ports[portNo]
+		if !ok {
+			port = strconv.Itoa(int(portNo))
+		}
+		entries = append(entries, BridgeFDBEntry{
+			MAC:  net.HardwareAddr(entry[0:6]).String(),
+			Port: port,
+		})
+	}
+
+	return entries, nil
+}
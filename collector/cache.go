@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// refresh intervals of expensive collectors, their metrics are served from a cache in between
+type CacheConfig struct {
+	// refresh intervals by collector name (0 = collected on every scrape)
+	TTLs map[string]time.Duration
+}
+
+// refresh intervals declared by expensive collectors
+var defaultCacheTTLs = map[string]time.Duration{
+	// arp table, dhcp leases and an 'iw station dump' per wireless interface
+	"device": 30 * time.Second,
+	// 'iw survey dump' per wireless interface
+	"wireless_survey": time.Minute,
+	// aggregation of the whole conntrack table
+	"conntrack":    15 * time.Second,
+	"nat_sessions": 15 * time.Second,
+	// upnp soap queries, one per port mapping, and stun lookups
+	"upnp":        time.Minute,
+	"external_ip": time.Minute,
+}
+
+// collector serving the metrics of another one from a cache until they are older than the ttl,
+// so router load does not grow with the scrape frequency
+type cachedCollector struct {
+	collector prometheus.Collector
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	metrics   []prometheus.Metric
+	refreshed time.Time
+}
+
+// wrap a collector with a cache, unless the ttl is 0
+func withCache(c prometheus.Collector, ttl time.Duration) prometheus.Collector {
+	if ttl <= 0 {
+		return c
+	}
+	return &cachedCollector{collector: c, ttl: ttl}
+}
+
+// unwrap returns the collector without the cache
+func (c *cachedCollector) Unwrap() prometheus.Collector {
+	return c.collector
+}
+
+// describe implements prometheus.Collector
+func (c *cachedCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+// collect implements prometheus.Collector
+func (c *cachedCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *cachedCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	// concurrent scrapes (e.g. of several views) wait for a single refresh
+	c.mu.Lock()
+	metrics := c.metrics
+	if c.refreshed.IsZero() || time.Since(c.refreshed) >= c.ttl {
+		metrics = c.refresh(ctx)
+	}
+	c.mu.Unlock()
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+}
+
+// collect the wrapped collector, caching the metrics unless ctx ended the collection early
+func (c *cachedCollector) refresh(ctx context.Context) []prometheus.Metric {
+	collected := make(chan prometheus.Metric)
+	go func() {
+		defer close(collected)
+		collectContext(ctx, c.collector, collected)
+	}()
+
+	var metrics []prometheus.Metric
+	for metric := range collected {
+		metrics = append(metrics, metric)
+	}
+
+	if ctx.Err() == nil {
+		c.metrics = metrics
+		c.refreshed = time.Now()
+	}
+	return metrics
+}
+
+// load collector refresh intervals from environment variables
+func loadCacheConfig() *CacheConfig {
+	config := &CacheConfig{TTLs: maps.Clone(defaultCacheTTLs)}
+
+	// collector_cache_ttls: comma-separated name=duration refresh intervals overriding the declared ones, 0 disables the cache
+	maps.Copy(config.TTLs, parseCollectorDurations("COLLECTOR_CACHE_TTLS"))
+
+	return config
+}
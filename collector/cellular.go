@@ -0,0 +1,436 @@
+//go:build !nomodem
+
+package collector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lte/5g modem collector
+type CellularCollector struct {
+	rsrp       *prometheus.Desc
+	rsrq       *prometheus.Desc
+	sinr       *prometheus.Desc
+	rssi       *prometheus.Desc
+	registered *prometheus.Desc
+	info       *prometheus.Desc
+	rxBytes    *prometheus.Desc
+	txBytes    *prometheus.Desc
+	config     *CellularConfig
+
+	signalSetup sync.Once
+}
+
+// cellular modem status, signal values are nil when the modem does not report them
+type CellularStatus struct {
+	RSRP         *float64
+	RSRQ         *float64
+	SINR         *float64
+	RSSI         *float64
+	Registration string
+	Registered   bool
+	Technology   string
+	Band         string
+	CellID       string
+	Operator     string
+	RxBytes      *float64
+	TxBytes      *float64
+}
+
+// create a new cellular collector
+func NewCellularCollector(config *CellularConfig) *CellularCollector {
+	return &CellularCollector{
+		rsrp: prometheus.NewDesc(
+			"openwrt_cellular_rsrp_dbm",
+			"lte/5g reference signal received power in dBm",
+			nil, nil,
+		),
+		rsrq: prometheus.NewDesc(
+			"openwrt_cellular_rsrq_db",
+			"lte/5g reference signal received quality in dB",
+			nil, nil,
+		),
+		sinr: prometheus.NewDesc(
+			"openwrt_cellular_sinr_db",
+			"lte/5g signal to interference plus noise ratio in dB",
+			nil, nil,
+		),
+		rssi: prometheus.NewDesc(
+			"openwrt_cellular_rssi_dbm",
+			"received signal strength indicator in dBm",
+			nil, nil,
+		),
+		registered: prometheus.NewDesc(
+			"openwrt_cellular_registered",
+			"whether the modem is registered to a home or roaming network (1 = registered)",
+			nil, nil,
+		),
+		info: prometheus.NewDesc(
+			"openwrt_cellular_info",
+			"cellular registration, technology, band and serving cell information",
+			[]string{"registration", "technology", "band", "cell_id", "operator"}, nil,
+		),
+		rxBytes: prometheus.NewDesc(
+			"openwrt_cellular_data_receive_bytes_total",
+			"total bytes received on the active data session",
+			nil, nil,
+		),
+		txBytes: prometheus.NewDesc(
+			"openwrt_cellular_data_transmit_bytes_total",
+			"total bytes transmitted on the active data session",
+			nil, nil,
+		),
+		config: config,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *CellularCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rsrp
+	ch <- c.rsrq
+	ch <- c.sinr
+	ch <- c.rssi
+	ch <- c.registered
+	ch <- c.info
+	ch <- c.rxBytes
+	ch <- c.txBytes
+}
+
+// collect implements prometheus.Collector
+func (c *CellularCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *CellularCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	var status *CellularStatus
+	var err error
+
+	switch c.config.Backend {
+	case "":
+		return
+	case "uqmi":
+		status, err = getUqmiStatus(ctx, c.config.Device)
+	case "mmcli":
+		// modemmanager only reports extended signal values after polling is enabled
+		c.signalSetup.Do(func() {
+			if _, err := runCommand(ctx, "mmcli", "-m", c.config.Device, "--signal-setup=10"); err != nil {
+				slog.Warn("failed to enable modem signal polling", "err", err)
+			}
+		})
+		status, err = getMmcliStatus(ctx, c.config.Device)
+	case "at":
+		status, err = getATStatus(c.config.Device)
+	default:
+		err = fmt.Errorf("unknown backend %q", c.config.Backend)
+	}
+	if err != nil {
+		logCollectError("cellular", err)
+		return
+	}
+
+	for _, metric := range []struct {
+		desc  *prometheus.Desc
+		value *float64
+	}{
+		{c.rsrp, status.RSRP},
+		{c.rsrq, status.RSRQ},
+		{c.sinr, status.SINR},
+		{c.rssi, status.RSSI},
+	} {
+		if metric.value != nil {
+			ch <- prometheus.MustNewConstMetric(metric.desc, prometheus.GaugeValue, *metric.value)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.registered, prometheus.GaugeValue, boolToFloat64(status.Registered))
+	ch <- prometheus.MustNewConstMetric(
+		c.info,
+		prometheus.GaugeValue,
+		1,
+		status.Registration, status.Technology, status.Band, status.CellID, status.Operator,
+	)
+
+	if status.RxBytes != nil {
+		ch <- prometheus.MustNewConstMetric(c.rxBytes, prometheus.CounterValue, *status.RxBytes)
+	}
+	if status.TxBytes != nil {
+		ch <- prometheus.MustNewConstMetric(c.txBytes, prometheus.CounterValue, *status.TxBytes)
+	}
+}
+
+// run uqmi against a qmi control device and decode its json output
+func runUqmi(ctx context.Context, device string, command string, v any) error {
+	output, err := runCommand(ctx, "uqmi", "-s", "-d", device, command)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(output, v)
+}
+
+// get modem status via uqmi (qmi modems)
+func getUqmiStatus(ctx context.Context, device string) (*CellularStatus, error) {
+	status := &CellularStatus{}
+
+	var signal map[string]any
+	if err := runUqmi(ctx, device, "--get-signal-info", &signal); err != nil {
+		return nil, err
+	}
+	status.Technology, _ = signal["type"].(string)
+	status.RSRP = jsonNumber(signal["rsrp"])
+	status.RSRQ = jsonNumber(signal["rsrq"])
+	status.SINR = jsonNumber(signal["snr"])
+	status.RSSI = jsonNumber(signal["rssi"])
+
+	var serving struct {
+		Registration string `json:"registration"`
+		Operator     string `json:"plmn_description"`
+	}
+	if err := runUqmi(ctx, device, "--get-serving-system", &serving); err == nil {
+		status.Registration = serving.Registration
+		status.Registered = serving.Registration == "registered"
+		status.Operator = serving.Operator
+	}
+
+	// serving cell id is reported per radio technology
+	var system map[string]map[string]any
+	if err := runUqmi(ctx, device, "--get-system-info", &system); err == nil {
+		if info, ok := system[status.Technology]; ok {
+			if cellID := jsonNumber(info["cell_id"]); cellID != nil {
+				status.CellID = strconv.FormatFloat(*cellID, 'f', -1, 64)
+			}
+		}
+	}
+
+	var carrier struct {
+		Primary map[string]any `json:"primary"`
+	}
+	if err := runUqmi(ctx, device, "--get-lte-cphy-ca-info", &carrier); err == nil {
+		if band, ok := carrier.Primary["band"]; ok && band != nil {
+			status.Band = fmt.Sprint(band)
+		}
+	}
+
+	return status, nil
+}
+
+// get modem status via mmcli (modemmanager, qmi and mbim modems)
+func getMmcliStatus(ctx context.Context, modem string) (*CellularStatus, error) {
+	output, err := runCommand(ctx, "mmcli", "-m", modem, "-J")
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Modem struct {
+			ThreeGPP struct {
+				RegistrationState string `json:"registration-state"`
+				OperatorName      string `json:"operator-name"`
+			} `json:"3gpp"`
+			Generic struct {
+				AccessTechnologies []string `json:"access-technologies"`
+				Bearers            []string `json:"bearers"`
+			} `json:"generic"`
+		} `json:"modem"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, err
+	}
+
+	state := info.Modem.ThreeGPP.RegistrationState
+	status := &CellularStatus{
+		Registration: state,
+		Registered:   state == "home" || state == "roaming",
+		Operator:     info.Modem.ThreeGPP.OperatorName,
+		Technology:   strings.Join(info.Modem.Generic.AccessTechnologies, ","),
+	}
+
+	// extended signal values, preferring 5g over lte
+	if output, err := runCommand(ctx, "mmcli", "-m", modem, "--signal-get", "-J"); err == nil {
+		var signal struct {
+			Modem struct {
+				Signal map[string]map[string]string `json:"signal"`
+			} `json:"modem"`
+		}
+		if err := json.Unmarshal(output, &signal); err == nil {
+			for _, technology := range []string{"5g", "lte"} {
+				values, ok := signal.Modem.Signal[technology]
+				if !ok || jsonNumber(values["rsrp"]) == nil {
+					continue
+				}
+				status.RSRP = jsonNumber(values["rsrp"])
+				status.RSRQ = jsonNumber(values["rsrq"])
+				status.SINR = jsonNumber(values["snr"])
+				status.RSSI = jsonNumber(values["rssi"])
+				break
+			}
+		}
+	}
+
+	if output, err := runCommand(ctx, "mmcli", "-m", modem, "--location-get", "-J"); err == nil {
+		var location struct {
+			Modem struct {
+				Location struct {
+					ThreeGPP struct {
+						CID string `json:"cid"`
+					} `json:"3gpp"`
+				} `json:"location"`
+			} `json:"modem"`
+		}
+		if err := json.Unmarshal(output, &location); err == nil && location.Modem.Location.ThreeGPP.CID != "--" {
+			status.CellID = location.Modem.Location.ThreeGPP.CID
+		}
+	}
+
+	// data session counters of the connected bearer
+	for _, bearer := range info.Modem.Generic.Bearers {
+		output, err := runCommand(ctx, "mmcli", "-b", bearer, "-J")
+		if err != nil {
+			continue
+		}
+		var stats struct {
+			Bearer struct {
+				Status struct {
+					Connected string `json:"connected"`
+				} `json:"status"`
+				Stats struct {
+					RxBytes string `json:"rx-bytes"`
+					TxBytes string `json:"tx-bytes"`
+				} `json:"stats"`
+			} `json:"bearer"`
+		}
+		if err := json.Unmarshal(output, &stats); err != nil || stats.Bearer.Status.Connected != "yes" {
+			continue
+		}
+		status.RxBytes = jsonNumber(stats.Bearer.Stats.RxBytes)
+		status.TxBytes = jsonNumber(stats.Bearer.Stats.TxBytes)
+		break
+	}
+
+	return status, nil
+}
+
+// get modem status via standard 3gpp at commands on a serial port
+func getATStatus(device string) (*CellularStatus, error) {
+	port, err := os.OpenFile(device, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = port.Close() }()
+
+	status := &CellularStatus{}
+
+	// +CESQ: <rxlev>,<ber>,<rscp>,<ecno>,<rsrq>,<rsrp>
+	if fields, err := sendATCommand(port, "AT+CESQ", "+CESQ:"); err == nil && len(fields) >= 6 {
+		if index, err := strconv.Atoi(fields[4]); err == nil && index != 255 {
+			rsrq := -20 + float64(index)*0.5
+			status.RSRQ = &rsrq
+		}
+		if index, err := strconv.Atoi(fields[5]); err == nil && index != 255 {
+			rsrp := -141 + float64(index)
+			status.RSRP = &rsrp
+		}
+	}
+
+	// +CSQ: <rssi>,<ber>
+	if fields, err := sendATCommand(port, "AT+CSQ", "+CSQ:"); err == nil && len(fields) >= 1 {
+		if index, err := strconv.Atoi(fields[0]); err == nil && index != 99 {
+			rssi := -113 + 2*float64(index)
+			status.RSSI = &rssi
+		}
+	}
+
+	// +CEREG: <n>,<stat>[,<tac>,<ci>,<act>], enabling location reporting first
+	_, _ = sendATCommand(port, "AT+CEREG=2", "")
+	fields, err := sendATCommand(port, "AT+CEREG?", "+CEREG:")
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) >= 2 {
+		registrations := map[string]string{
+			"0": "not_registered", "1": "home", "2": "searching",
+			"3": "denied", "4": "unknown", "5": "roaming",
+		}
+		status.Registration = registrations[fields[1]]
+		status.Registered = fields[1] == "1" || fields[1] == "5"
+	}
+	if len(fields) >= 4 {
+		status.CellID = strings.Trim(fields[3], `"`)
+	}
+	if len(fields) >= 5 {
+		technologies := map[string]string{"7": "lte", "10": "lte", "11": "5gnr", "12": "5gnr", "13": "lte"}
+		status.Technology = technologies[fields[4]]
+	}
+
+	// +COPS: <mode>,<format>,"<operator>",<act>
+	if fields, err := sendATCommand(port, "AT+COPS?", "+COPS:"); err == nil && len(fields) >= 3 {
+		status.Operator = strings.Trim(fields[2], `"`)
+	}
+
+	return status, nil
+}
+
+// send an at command and return the comma-separated fields of the response line with the given prefix
+func sendATCommand(port *os.File, command string, prefix string) ([]string, error) {
+	if err := port.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		return nil, err
+	}
+	if _, err := port.WriteString(command + "\r"); err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	reader := bufio.NewReader(port)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "OK":
+			return fields, nil
+		case line == "ERROR" || strings.HasPrefix(line, "+CME ERROR"):
+			return nil, fmt.Errorf("%s: %s", command, line)
+		case prefix != "" && strings.HasPrefix(line, prefix):
+			for _, field := range strings.Split(strings.TrimPrefix(line, prefix), ",") {
+				fields = append(fields, strings.TrimSpace(field))
+			}
+		}
+	}
+}
+
+// load cellular configuration from environment variables
+func loadCellularConfig() *CellularConfig {
+	config := &CellularConfig{
+		Backend: os.Getenv("CELLULAR_BACKEND"),
+		Device:  os.Getenv("CELLULAR_DEVICE"),
+	}
+
+	// cellular_device: default control device per backend
+	if config.Device == "" {
+		switch config.Backend {
+		case "uqmi":
+			config.Device = "/dev/cdc-wdm0"
+		case "mmcli":
+			config.Device = "0"
+		case "at":
+			config.Device = "/dev/ttyUSB2"
+		}
+	}
+
+	return config
+}
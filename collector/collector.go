@@ -0,0 +1,298 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// configuration of all collectors, for embedding them in other programs
+type Config struct {
+	// version reported by the update collector
+	Version string
+
+	// cancelling the context stops background work (probes, samplers, listeners) and the
+	// commands collectors run, Wait returns once everything stopped (default: never cancelled)
+	Context context.Context
+
+	Exec           *ExecConfig
+	Timeout        *TimeoutConfig
+	Cache          *CacheConfig
+	Network        *NetworkConfig
+	Privacy        *PrivacyConfig
+	WANUtilization *WANUtilizationConfig
+	// configurations of optional groups are not loaded when the group is compiled out
+	Modem          *ModemConfig
+	Cellular       *CellularConfig
+	Device         *DeviceConfig
+	Presence       *PresenceConfig
+	ProcessNetwork *ProcessNetworkConfig
+	Routing        *RoutingConfig
+	Ping           *PingConfig
+	LatencySegment *LatencySegmentConfig
+	Failover       *FailoverConfig
+	UPnP           *UPnPConfig
+	ExternalIP     *ExternalIPConfig
+	NATSession     *NATSessionConfig
+	Update         *UpdateConfig
+	ACME           *ACMEConfig
+	Push           *PushConfig
+	Energy         *EnergyConfig
+	NTP            *NTPConfig
+	Opkg           *OpkgConfig
+	Syslog         *SyslogConfig
+	Process        *ProcessConfig
+	ExecPlugin     *ExecPluginConfig
+}
+
+// collector registered under a stable name (used by scrape views)
+type NamedCollector struct {
+	Name      string
+	Collector prometheus.Collector
+}
+
+// load the configuration of all collectors from environment variables, using defaults for unset variables
+func LoadConfig(version string) *Config {
+	return (&Config{Version: version}).withDefaults()
+}
+
+// create all collectors from a configuration, in registration order
+// nil configuration fields are loaded from environment variables, and collectors with
+// background work (probes, samplers, listeners) start it here when enabled
+// collectors are wrapped to cache expensive ones and enforce scrape deadlines, Unwrap returns the concrete collector
+// the context and the exec, network and privacy settings are process-wide: they apply to every collector of the
+// process, so a second call replaces them for the collectors of the first one as well; call All once per process
+func All(cfg *Config) ([]NamedCollector, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid collector configuration: %w", err)
+	}
+	setBackgroundContext(cfg.Context)
+	setExecConfig(cfg.Exec)
+	setNetworkConfig(cfg.Network)
+	setPrivacyConfig(cfg.Privacy)
+
+	collectors := []NamedCollector{
+		{"network", NewNetworkCollector()},
+		{"network_role", NewNetworkRoleCollector()},
+		{"wan_utilization", NewWANUtilizationCollector(cfg.WANUtilization)},
+		{"modem", newOptionalCollector("modem", cfg)},
+		{"cellular", newOptionalCollector("cellular", cfg)},
+		{"device", NewDeviceCollector(cfg.Device)},
+		{"presence", NewPresenceCollector(cfg.Presence)},
+		{"nlbwmon", NewNlbwmonCollector()},
+		{"process_network", NewProcessNetworkCollector(cfg.ProcessNetwork)},
+		{"dnsmasq", NewDnsmasqCollector()},
+		{"network_interface", NewNetworkInterfaceCollector()},
+		{"interface_ip", NewInterfaceIPCollector()},
+		{"bridge_fdb", NewBridgeFDBCollector()},
+		{"vlan", NewVLANCollector()},
+		{"switch", NewSwitchCollector()},
+		{"poe", NewPoECollector()},
+		{"routing", NewRoutingCollector(cfg.Routing)},
+		{"ping", newOptionalCollector("ping", cfg)},
+		{"latency_segments", newOptionalCollector("latency_segments", cfg)},
+		{"failover", newOptionalCollector("failover", cfg)},
+		{"upnp", newOptionalCollector("upnp", cfg)},
+		{"external_ip", NewExternalIPCollector(cfg.ExternalIP, cfg.UPnP)},
+		{"port_forward", NewPortForwardCollector()},
+		{"nftables", NewNftablesCollector()},
+		{"firewall_zone", NewFirewallZoneCollector()},
+		{"blocklist", NewBlocklistCollector()},
+		{"ipv6_exposure", NewIPv6ExposureCollector()},
+		{"conntrack", NewConntrackCollector()},
+		{"nat_sessions", NewNATSessionCollector(cfg.NATSession)},
+		{"netstat", NewNetstatCollector()},
+		{"sockstat", NewSockstatCollector()},
+		{"wireless", newOptionalCollector("wireless", cfg)},
+		{"wireless_survey", newOptionalCollector("wireless_survey", cfg)},
+		{"roaming", newOptionalCollector("roaming", cfg)},
+		{"update", NewUpdateCollector(cfg.Version, cfg.Update)},
+		{"opkg", NewOpkgCollector(cfg.Opkg)},
+		{"acme", NewACMECollector(cfg.ACME)},
+		{"ntp", NewNTPCollector(cfg.NTP)},
+		{"push", NewPushCollector(cfg.Push)},
+		{"syslog", NewSyslogCollector(cfg.Syslog)},
+		{"kernel_crash", NewKernelCrashCollector()},
+		{"system", NewSystemCollector()},
+		{"memory", NewMemoryCollector()},
+		{"process", NewProcessCollector(cfg.Process)},
+		{"pressure", NewPressureCollector()},
+		{"interrupts", NewInterruptsCollector()},
+		{"filesystem", NewFilesystemCollector()},
+		{"flash", NewFlashCollector()},
+		{"thermal", NewThermalCollector()},
+		{"cpufreq", NewCPUFreqCollector()},
+		{"hwmon", NewHwmonCollector()},
+		{"energy", NewEnergyCollector(cfg.Energy)},
+		{"exec", NewExecPluginCollector(cfg.ExecPlugin)},
+	}
+
+	// drop collectors compiled out with build tags, cache expensive ones and bound all by their deadline
+	compiled := collectors[:0]
+	for _, c := range collectors {
+		if c.Collector != nil {
+			c.Collector = withCache(c.Collector, cfg.Cache.TTLs[c.Name])
+			c.Collector = withTimeout(c.Name, c.Collector, cfg.Timeout.timeout(c.Name))
+			compiled = append(compiled, c)
+		}
+	}
+	return compiled, nil
+}
+
+// check a configuration for values the collectors cannot run with, e.g. missing fields of a config built in code
+// (configurations loaded from environment variables fall back to defaults for invalid values)
+func (cfg *Config) validate() error {
+	var errs []error
+	positive := func(name string, enabled bool, value time.Duration) {
+		errs = append(errs, requirePositive(name, enabled, value))
+	}
+
+	if !ValidPrivacyMode(cfg.Privacy.Mode) {
+		errs = append(errs, fmt.Errorf("unknown privacy mode %q", cfg.Privacy.Mode))
+	}
+
+	positive("wan utilization sample interval", len(cfg.WANUtilization.Links) > 0, cfg.WANUtilization.SampleInterval)
+	positive("wan utilization window", len(cfg.WANUtilization.Links) > 0, cfg.WANUtilization.Window)
+	positive("presence interval", cfg.Presence.Enabled, cfg.Presence.Interval)
+	positive("opkg interval", cfg.Opkg.Enabled, cfg.Opkg.Interval)
+	positive("update interval", cfg.Update.Enabled, cfg.Update.Interval)
+	positive("energy sample interval", cfg.Energy.Model != nil, cfg.Energy.SampleInterval)
+
+	names := make(map[string]bool)
+	for i, plugin := range cfg.ExecPlugin.Plugins {
+		switch {
+		case plugin.Name == "":
+			errs = append(errs, fmt.Errorf("exec plugin %d has no name", i))
+		case len(plugin.Command) == 0:
+			errs = append(errs, fmt.Errorf("exec plugin %q has no command", plugin.Name))
+		case names[plugin.Name]:
+			errs = append(errs, fmt.Errorf("duplicate exec plugin %q", plugin.Name))
+		}
+		positive(fmt.Sprintf("timeout of exec plugin %q", plugin.Name), true, plugin.Timeout)
+		names[plugin.Name] = true
+	}
+
+	for _, validate := range optionalValidators {
+		errs = append(errs, validate(cfg)...)
+	}
+	return errors.Join(errs...)
+}
+
+// error for a duration that has to be positive while its feature is enabled, nil otherwise
+func requirePositive(name string, enabled bool, value time.Duration) error {
+	if enabled && value <= 0 {
+		return fmt.Errorf("%s must be positive", name)
+	}
+	return nil
+}
+
+// copy of the configuration with nil fields loaded from environment variables
+func (cfg *Config) withDefaults() *Config {
+	var loaded Config
+	if cfg != nil {
+		loaded = *cfg
+	}
+	if loaded.Exec == nil {
+		loaded.Exec = getExecConfig()
+	}
+	if loaded.Timeout == nil {
+		loaded.Timeout = loadTimeoutConfig()
+	}
+	if loaded.Cache == nil {
+		loaded.Cache = loadCacheConfig()
+	}
+	if loaded.Network == nil {
+		loaded.Network = getNetworkConfig()
+	}
+	if loaded.Privacy == nil {
+		loaded.Privacy = getPrivacyConfig()
+	}
+	if loaded.WANUtilization == nil {
+		loaded.WANUtilization = loadWANUtilizationConfig()
+	}
+	if loaded.Device == nil {
+		loaded.Device = loadDeviceConfig()
+	}
+	if loaded.Presence == nil {
+		loaded.Presence = loadPresenceConfig()
+	}
+	if loaded.ProcessNetwork == nil {
+		loaded.ProcessNetwork = loadProcessNetworkConfig()
+	}
+	if loaded.Routing == nil {
+		loaded.Routing = loadRoutingConfig()
+	}
+	if loaded.UPnP == nil {
+		loaded.UPnP = loadUPnPConfig()
+	}
+	if loaded.ExternalIP == nil {
+		loaded.ExternalIP = loadExternalIPConfig()
+	}
+	if loaded.NATSession == nil {
+		loaded.NATSession = loadNATSessionConfig()
+	}
+	if loaded.Update == nil {
+		loaded.Update = loadUpdateConfig()
+	}
+	if loaded.ACME == nil {
+		loaded.ACME = loadACMEConfig()
+	}
+	if loaded.Push == nil {
+		loaded.Push = loadPushConfig()
+	}
+	if loaded.Energy == nil {
+		loaded.Energy = loadEnergyConfig()
+	}
+	if loaded.NTP == nil {
+		loaded.NTP = loadNTPConfig()
+	}
+	if loaded.Opkg == nil {
+		loaded.Opkg = loadOpkgConfig()
+	}
+	if loaded.Syslog == nil {
+		loaded.Syslog = loadSyslogConfig()
+	}
+	if loaded.Process == nil {
+		loaded.Process = loadProcessConfig()
+	}
+	if loaded.ExecPlugin == nil {
+		loaded.ExecPlugin = loadExecPluginConfig()
+	}
+	for _, loadDefaults := range optionalDefaults {
+		loadDefaults(&loaded)
+	}
+	return &loaded
+}
+
+// whether an error only means the source of a collector is not installed or configured on this router,
+// e.g. a missing binary, ubus object or proc/config file, or a disabled igd
+func isSourceMissing(err error) bool {
+	return errors.Is(err, exec.ErrNotFound) ||
+		errors.Is(err, errUbusNotFound) ||
+		errors.Is(err, os.ErrNotExist) ||
+		errors.Is(err, errIGDUnavailable)
+}
+
+// whether an error needs no log of its own: the source is missing, or the command is not
+// allowlisted, which checkCommand already warned about once
+func isExpectedError(err error) bool {
+	return isSourceMissing(err) || errors.Is(err, errCommandNotAllowed)
+}
+
+// log an error of a collector, at debug level when its source is just missing or its command
+// not allowed so routers without it are not flooded with an error on every scrape
+func logCollectError(name string, err error, args ...any) {
+	args = append(args, "err", err)
+	if isExpectedError(err) {
+		slog.Debug("skipping "+name+" metrics, source not available", args...)
+		return
+	}
+	slog.Error("error collecting "+name+" metrics", args...)
+}
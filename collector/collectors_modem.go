@@ -0,0 +1,20 @@
+//go:build !nomodem
+
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// modem and cellular collectors, left out of builds with the nomodem tag
+func init() {
+	optionalCollectors["modem"] = func(cfg *Config) prometheus.Collector { return NewModemCollector(cfg.Modem) }
+	optionalCollectors["cellular"] = func(cfg *Config) prometheus.Collector { return NewCellularCollector(cfg.Cellular) }
+
+	optionalDefaults = append(optionalDefaults, func(cfg *Config) {
+		if cfg.Modem == nil {
+			cfg.Modem = loadModemConfig()
+		}
+		if cfg.Cellular == nil {
+			cfg.Cellular = loadCellularConfig()
+		}
+	})
+}
@@ -0,0 +1,63 @@
+//go:build !noprobes
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// active probe collectors, left out of builds with the noprobes tag
+func init() {
+	optionalCollectors["ping"] = func(cfg *Config) prometheus.Collector { return NewPingCollector(cfg.Ping) }
+	optionalCollectors["latency_segments"] = func(cfg *Config) prometheus.Collector {
+		return NewLatencySegmentCollector(cfg.LatencySegment, cfg.Ping)
+	}
+	optionalCollectors["failover"] = func(cfg *Config) prometheus.Collector { return NewFailoverCollector(cfg.Failover, cfg.Ping) }
+
+	optionalDefaults = append(optionalDefaults, func(cfg *Config) {
+		if cfg.Ping == nil {
+			cfg.Ping = loadPingConfig()
+		}
+		if cfg.LatencySegment == nil {
+			cfg.LatencySegment = loadLatencySegmentConfig()
+		}
+		if cfg.Failover == nil {
+			cfg.Failover = loadFailoverConfig()
+		}
+	})
+	optionalValidators = append(optionalValidators, validateProbeConfigs)
+}
+
+// check the ping, latency segment and failover configurations
+func validateProbeConfigs(cfg *Config) []error {
+	var errs []error
+
+	switch cfg.Ping.Mode {
+	case pingModeAuto, pingModePrivileged, pingModeUnprivileged:
+	default:
+		errs = append(errs, fmt.Errorf("unknown ping mode %q", cfg.Ping.Mode))
+	}
+	pinging := len(cfg.Ping.Targets) > 0
+	if pinging && cfg.Ping.Count <= 0 {
+		errs = append(errs, errors.New("ping count must be positive"))
+	}
+	errs = append(errs,
+		requirePositive("ping interval", pinging, cfg.Ping.Interval),
+		requirePositive("ping timeout", pinging, cfg.Ping.Timeout),
+	)
+
+	latency := cfg.LatencySegment.Anchor != ""
+	if latency && (cfg.LatencySegment.Count <= 0 || cfg.LatencySegment.MaxHops <= 0) {
+		errs = append(errs, errors.New("latency segment count and max hops must be positive"))
+	}
+	errs = append(errs,
+		requirePositive("latency segment interval", latency, cfg.LatencySegment.Interval),
+		requirePositive("latency segment timeout", latency, cfg.LatencySegment.Timeout),
+		requirePositive("failover probe interval", cfg.Failover.Target != "", cfg.Failover.Interval),
+	)
+
+	return errs
+}
@@ -0,0 +1,10 @@
+//go:build !noupnp
+
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// upnp collector, left out of builds with the noupnp tag
+func init() {
+	optionalCollectors["upnp"] = func(cfg *Config) prometheus.Collector { return NewUPnPCollector(cfg.UPnP) }
+}
@@ -0,0 +1,12 @@
+//go:build !nowifi
+
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// wireless collectors, left out of builds with the nowifi tag
+func init() {
+	optionalCollectors["wireless"] = func(*Config) prometheus.Collector { return NewWirelessCollector() }
+	optionalCollectors["wireless_survey"] = func(*Config) prometheus.Collector { return NewWirelessSurveyCollector() }
+	optionalCollectors["roaming"] = func(*Config) prometheus.Collector { return NewRoamingCollector() }
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collector running other collectors in parallel on a bounded number of workers, so a scrape
+// takes about as long as its slowest collector without starting a goroutine per collector
+type ConcurrentCollector struct {
+	collectors []prometheus.Collector
+	workers    int
+}
+
+// create a new concurrent collector running at most workers collectors at a time (at least one)
+func NewConcurrentCollector(collectors []prometheus.Collector, workers int) *ConcurrentCollector {
+	return &ConcurrentCollector{
+		collectors: collectors,
+		workers:    max(workers, 1),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ConcurrentCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors {
+		collector.Describe(ch)
+	}
+}
+
+// collect implements prometheus.Collector
+func (c *ConcurrentCollector) Collect(ch chan<- prometheus.Metric) {
+	queue := make(chan prometheus.Collector)
+	var wg sync.WaitGroup
+
+	// the workers fan the metrics of their collectors in to ch
+	for range min(c.workers, len(c.collectors)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for collector := range queue {
+				collector.Collect(ch)
+			}
+		}()
+	}
+
+	// collectors are started in registration order
+	for _, collector := range c.collectors {
+		queue <- collector
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// connection tracking metrics collector
+type ConntrackCollector struct {
+	entries     *prometheus.Desc
+	limit       *prometheus.Desc
+	connections *prometheus.Desc
+}
+
+// create a new conntrack collector
+func NewConntrackCollector() *ConntrackCollector {
+	return &ConntrackCollector{
+		entries: prometheus.NewDesc(
+			"openwrt_conntrack_entries",
+			"number of currently allocated connection tracking entries",
+			nil, nil,
+		),
+		limit: prometheus.NewDesc(
+			"openwrt_conntrack_entries_limit",
+			"maximum size of the connection tracking table",
+			nil, nil,
+		),
+		connections: prometheus.NewDesc(
+			"openwrt_conntrack_connections",
+			"number of tracked connections by protocol and state",
+			[]string{"family", "protocol", "state"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ConntrackCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.limit
+	ch <- c.connections
+}
+
+// collect implements prometheus.Collector
+func (c *ConntrackCollector) Collect(ch chan<- prometheus.Metric) {
+	if count, ok := readSysfsFloat("/proc/sys/net/netfilter/nf_conntrack_count"); ok {
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, count)
+	}
+	if limit, ok := readSysfsFloat("/proc/sys/net/netfilter/nf_conntrack_max"); ok {
+		ch <- prometheus.MustNewConstMetric(c.limit, prometheus.GaugeValue, limit)
+	}
+
+	entries, err := getConntrackEntries()
+	if err != nil {
+		logCollectError("conntrack", err)
+		return
+	}
+
+	// count connections per family, protocol and state
+	type key struct{ family, protocol, state string }
+	counts := make(map[key]float64)
+	for _, entry := range entries {
+		counts[key{entry.Family, entry.Protocol, entry.State}]++
+	}
+
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			c.connections,
+			prometheus.GaugeValue,
+			count,
+			k.family, k.protocol, k.state,
+		)
+	}
+}
+
+// connection tracking entry
+type ConntrackEntry struct {
+	Family   string
+	Protocol string
+	State    string
+	Src      string
+	Dst      string
+	SrcPort  string
+	DstPort  string
+	ReplySrc string
+	ReplyDst string
+}
+
+// get connection tracking entries from /proc/net/nf_conntrack
+func getConntrackEntries() ([]ConntrackEntry, error) {
+	file, err := os.Open("/proc/net/nf_conntrack")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []ConntrackEntry
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		if entry, ok := parseConntrackLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// parse a single nf_conntrack line
+// format: <l3proto> <l3num> <proto> <protonum> <timeout> [<state>] src=... dst=... [sport=... dport=...] ...
+func parseConntrackLine(line string) (ConntrackEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 {
+		return ConntrackEntry{}, false
+	}
+
+	entry := ConntrackEntry{
+		Family:   fields[0],
+		Protocol: fields[2],
+	}
+
+	// only tcp (and sctp/dccp) entries carry a state before the tuple
+	if !strings.Contains(fields[5], "=") {
+		entry.State = fields[5]
+	}
+
+	// the first tuple is the original direction, the second the reply direction
+	for _, field := range fields[5:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "src":
+			if entry.Src == "" {
+				entry.Src = value
+			} else if entry.ReplySrc == "" {
+				entry.ReplySrc = value
+			}
+		case "dst":
+			if entry.Dst == "" {
+				entry.Dst = value
+			} else if entry.ReplyDst == "" {
+				entry.ReplyDst = value
+			}
+		case "sport":
+			if entry.SrcPort == "" {
+				entry.SrcPort = value
+			}
+		case "dport":
+			if entry.DstPort == "" {
+				entry.DstPort = value
+			}
+		}
+	}
+
+	// protocol numbers are shown for protocols unknown to the kernel
+	if entry.Protocol == "unknown" {
+		if _, err := strconv.Atoi(fields[3]); err == nil {
+			entry.Protocol = fields[3]
+		}
+	}
+
+	return entry, true
+}
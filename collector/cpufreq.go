@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// matches per-cpu sysfs directories (cpu0, cpu1, ...)
+var cpuDirPattern = regexp.MustCompile(`^cpu[0-9]+$`)
+
+// cpu frequency scaling collector
+type CPUFreqCollector struct {
+	current    *prometheus.Desc
+	maximum    *prometheus.Desc
+	scalingMax *prometheus.Desc
+	info       *prometheus.Desc
+}
+
+// create a new cpufreq collector
+func NewCPUFreqCollector() *CPUFreqCollector {
+	return &CPUFreqCollector{
+		current: prometheus.NewDesc(
+			"openwrt_cpu_frequency_hertz",
+			"current cpu frequency in hertz",
+			[]string{"cpu"}, nil,
+		),
+		maximum: prometheus.NewDesc(
+			"openwrt_cpu_frequency_max_hertz",
+			"maximum frequency supported by the cpu in hertz",
+			[]string{"cpu"}, nil,
+		),
+		scalingMax: prometheus.NewDesc(
+			"openwrt_cpu_scaling_frequency_max_hertz",
+			"maximum frequency the governor may currently select in hertz, lowered by thermal throttling",
+			[]string{"cpu"}, nil,
+		),
+		info: prometheus.NewDesc(
+			"openwrt_cpu_frequency_info",
+			"frequency scaling governor and driver of a cpu",
+			[]string{"cpu", "governor", "driver"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *CPUFreqCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.current
+	ch <- c.maximum
+	ch <- c.scalingMax
+	ch <- c.info
+}
+
+// collect implements prometheus.Collector
+func (c *CPUFreqCollector) Collect(ch chan<- prometheus.Metric) {
+	cpus, err := getCPUFreqs()
+	if err != nil {
+		logCollectError("cpufreq", err)
+		return
+	}
+
+	for _, cpu := range cpus {
+		frequencies := []struct {
+			desc  *prometheus.Desc
+			value float64
+		}{
+			{c.current, cpu.Current},
+			{c.maximum, cpu.Maximum},
+			{c.scalingMax, cpu.ScalingMax},
+		}
+		for _, frequency := range frequencies {
+			if frequency.value == 0 {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				frequency.desc,
+				prometheus.GaugeValue,
+				frequency.value,
+				cpu.CPU,
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.info,
+			prometheus.GaugeValue,
+			1,
+			cpu.CPU, cpu.Governor, cpu.Driver,
+		)
+	}
+}
+
+// frequency scaling state of a cpu
+type CPUFreq struct {
+	CPU        string
+	Current    float64
+	Maximum    float64
+	ScalingMax float64
+	Governor   string
+	Driver     string
+}
+
+// get cpu frequencies from /sys/devices/system/cpu/cpu<n>/cpufreq, skipping cpus without frequency scaling
+func getCPUFreqs() ([]CPUFreq, error) {
+	entries, err := os.ReadDir("/sys/devices/system/cpu")
+	if err != nil {
+		return nil, err
+	}
+
+	var cpus []CPUFreq
+	for _, entry := range entries {
+		if !cpuDirPattern.MatchString(entry.Name()) {
+			continue
+		}
+		dir := filepath.Join("/sys/devices/system/cpu", entry.Name(), "cpufreq")
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+
+		cpus = append(cpus, CPUFreq{
+			CPU:        strings.TrimPrefix(entry.Name(), "cpu"),
+			Current:    readKilohertz(filepath.Join(dir, "scaling_cur_freq")),
+			Maximum:    readKilohertz(filepath.Join(dir, "cpuinfo_max_freq")),
+			ScalingMax: readKilohertz(filepath.Join(dir, "scaling_max_freq")),
+			Governor:   readSysfsString(filepath.Join(dir, "scaling_governor")),
+			Driver:     readSysfsString(filepath.Join(dir, "scaling_driver")),
+		})
+	}
+
+	return cpus, nil
+}
+
+// read a sysfs frequency in kHz and convert it to Hz (0 if missing)
+func readKilohertz(path string) float64 {
+	khz, err := strconv.ParseFloat(readSysfsString(path), 64)
+	if err != nil {
+		return 0
+	}
+	return khz * 1000
+}
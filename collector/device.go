@@ -2,11 +2,16 @@ package collector
 
 import (
 	"bufio"
-	"log"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"net"
 	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -17,19 +22,52 @@ type DeviceCollector struct {
 	deviceInfo        *prometheus.Desc
 	deviceOnlineTime  *prometheus.Desc
 	deviceLeaseRemain *prometheus.Desc
+	deviceReachable   *prometheus.Desc
+	deviceConnections *prometheus.Desc
+	deviceSignal      *prometheus.Desc
+	ipConflicts       *prometheus.Desc
+	gatewayInfo       *prometheus.Desc
+	gatewayMACChanges *prometheus.Desc
+	dhcpv6LeaseInfo   *prometheus.Desc
+	dhcpv6PrefixInfo  *prometheus.Desc
+	firstSeen         *prometheus.Desc
+	lastSeen          *prometheus.Desc
+	newDevices        *prometheus.Desc
+	config            *DeviceConfig
+	tracker           *deviceTracker
+
+	// mac-per-ip tracking state for spoofing detection
+	mu             sync.Mutex
+	conflicting    map[string]bool
+	conflictCounts map[string]float64
+	gatewayMACs    map[string]string
+	gatewayChanges map[string]float64
+
+	// oui database, loaded on the first scrape
+	ouiOnce sync.Once
+	vendors map[string]string
+}
+
+// device collector configuration
+type DeviceConfig struct {
+	FingerprintFile string
+	// oui database file, the files of common packages are tried when empty
+	OUIFile string
+	// file persisting first-seen/last-seen times, kept in memory only when empty
+	StateFile string
 }
 
 // create a new device collector
-func NewDeviceCollector() *DeviceCollector {
+func NewDeviceCollector(config *DeviceConfig) *DeviceCollector {
 	return &DeviceCollector{
 		deviceInfo: prometheus.NewDesc(
 			"openwrt_device_info",
 			"information about connected devices",
-			[]string{"hostname", "ip", "mac"}, nil,
+			[]string{"hostname", "ip", "mac", "device_type", "static", "interface", "connection_type", "ssid", "band", "vendor"}, nil,
 		),
 		deviceOnlineTime: prometheus.NewDesc(
 			"openwrt_device_online_seconds",
-			"device online time in seconds",
+			"device online time in seconds (wi-fi association time, or time continuously seen for wired devices)",
 			[]string{"hostname", "ip", "mac"}, nil,
 		),
 		deviceLeaseRemain: prometheus.NewDesc(
@@ -37,6 +75,67 @@ func NewDeviceCollector() *DeviceCollector {
 			"dhcp lease remaining time in seconds",
 			[]string{"hostname", "ip", "mac"}, nil,
 		),
+		deviceReachable: prometheus.NewDesc(
+			"openwrt_device_reachable",
+			"whether the neighbor entry of a device is confirmed reachable (1 = reachable, state is the nud state, e.g. STALE or FAILED)",
+			[]string{"hostname", "ip", "mac", "interface", "state"}, nil,
+		),
+		deviceConnections: prometheus.NewDesc(
+			"openwrt_device_connections",
+			"number of conntrack entries opened by or destined to a device",
+			[]string{"hostname", "ip", "mac"}, nil,
+		),
+		deviceSignal: prometheus.NewDesc(
+			"openwrt_device_wireless_signal_dbm",
+			"signal strength of an associated wireless device in dBm",
+			[]string{"hostname", "ip", "mac", "ssid", "band"}, nil,
+		),
+		ipConflicts: prometheus.NewDesc(
+			"openwrt_device_ip_conflicts_total",
+			"total number of times an ip address was seen with more than one mac address",
+			[]string{"ip"}, nil,
+		),
+		gatewayInfo: prometheus.NewDesc(
+			"openwrt_device_gateway_info",
+			"mac address currently observed for the default gateway",
+			[]string{"gateway", "mac"}, nil,
+		),
+		gatewayMACChanges: prometheus.NewDesc(
+			"openwrt_device_gateway_mac_changes_total",
+			"total number of times the mac address of the default gateway changed",
+			[]string{"gateway"}, nil,
+		),
+		dhcpv6LeaseInfo: prometheus.NewDesc(
+			"openwrt_device_dhcpv6_lease_info",
+			"information about dhcpv6 leases handed out by odhcpd",
+			[]string{"hostname", "mac", "duid", "iaid", "interface"}, nil,
+		),
+		dhcpv6PrefixInfo: prometheus.NewDesc(
+			"openwrt_device_dhcpv6_prefix_info",
+			"ipv6 prefixes delegated to a dhcpv6 client",
+			[]string{"hostname", "duid", "prefix"}, nil,
+		),
+		firstSeen: prometheus.NewDesc(
+			"openwrt_device_first_seen_timestamp_seconds",
+			"unix timestamp a device mac was first seen on the network",
+			[]string{"mac"}, nil,
+		),
+		lastSeen: prometheus.NewDesc(
+			"openwrt_device_last_seen_timestamp_seconds",
+			"unix timestamp a device mac was last seen on the network",
+			[]string{"mac"}, nil,
+		),
+		newDevices: prometheus.NewDesc(
+			"openwrt_device_new_total",
+			"total number of device macs seen on the network for the first time",
+			nil, nil,
+		),
+		config:         config,
+		tracker:        newDeviceTracker(config.StateFile),
+		conflicting:    make(map[string]bool),
+		conflictCounts: make(map[string]float64),
+		gatewayMACs:    make(map[string]string),
+		gatewayChanges: make(map[string]float64),
 	}
 }
 
@@ -45,27 +144,103 @@ func (c *DeviceCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.deviceInfo
 	ch <- c.deviceOnlineTime
 	ch <- c.deviceLeaseRemain
+	ch <- c.deviceReachable
+	ch <- c.deviceConnections
+	ch <- c.deviceSignal
+	ch <- c.ipConflicts
+	ch <- c.gatewayInfo
+	ch <- c.gatewayMACChanges
+	ch <- c.dhcpv6LeaseInfo
+	ch <- c.dhcpv6PrefixInfo
+	ch <- c.firstSeen
+	ch <- c.lastSeen
+	ch <- c.newDevices
 }
 
 // collect implements prometheus.Collector
 func (c *DeviceCollector) Collect(ch chan<- prometheus.Metric) {
-	devices, err := getConnectedDevices()
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *DeviceCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	leases, err := getDHCPv6Leases(ctx)
 	if err != nil {
-		log.Printf("error collecting device metrics: %v", err)
+		logCollectError("device", err, "source", "dhcpv6 leases")
+	}
+
+	devices, err := getConnectedDevices(ctx, leases, c.config.FingerprintFile)
+	if err != nil {
+		logCollectError("device", err)
 		return
 	}
 
+	// connection counts are skipped when conntrack is not loaded
+	connections, err := getConnectionCountsByIP()
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to read conntrack entries", "err", err)
+	}
+
+	now := time.Now()
+	seen, newTotal, err := c.tracker.observe(getPresentMACs(devices), now)
+	if err != nil {
+		slog.Warn("failed to persist device state", "path", c.config.StateFile, "err", err)
+	}
+
+	c.loadVendors()
+
 	for _, device := range devices {
+		ip, mac := privateIP(device.IP), privateMAC(device.MAC)
+
+		// wired devices have no association time, use how long they have been seen continuously
+		if tracked, ok := seen[strings.ToLower(device.MAC)]; ok && device.OnlineTime == 0 {
+			device.OnlineTime = float64(now.Unix() - tracked.OnlineSince)
+		}
+
 		// device info as a constant metric with value 1
 		ch <- prometheus.MustNewConstMetric(
 			c.deviceInfo,
 			prometheus.GaugeValue,
 			1,
 			device.Hostname,
-			device.IP,
-			device.MAC,
+			ip,
+			mac,
+			device.DeviceType,
+			strconv.FormatBool(device.Static),
+			device.Interface,
+			device.ConnectionType,
+			device.SSID,
+			device.Band,
+			lookupVendor(device.MAC, c.vendors),
 		)
 
+		if device.Signal != 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.deviceSignal,
+				prometheus.GaugeValue,
+				float64(device.Signal),
+				device.Hostname,
+				ip,
+				mac,
+				device.SSID,
+				device.Band,
+			)
+		}
+
+		// neighbor state, only known for devices in the neighbor table
+		if device.State != "" {
+			ch <- prometheus.MustNewConstMetric(
+				c.deviceReachable,
+				prometheus.GaugeValue,
+				boolToFloat64(reachableNeighborStates[device.State]),
+				device.Hostname,
+				ip,
+				mac,
+				device.Interface,
+				device.State,
+			)
+		}
+
 		// online time if available
 		if device.OnlineTime > 0 {
 			ch <- prometheus.MustNewConstMetric(
@@ -73,8 +248,19 @@ func (c *DeviceCollector) Collect(ch chan<- prometheus.Metric) {
 				prometheus.GaugeValue,
 				device.OnlineTime,
 				device.Hostname,
-				device.IP,
-				device.MAC,
+				ip,
+				mac,
+			)
+		}
+
+		if connections != nil && device.IP != "" {
+			ch <- prometheus.MustNewConstMetric(
+				c.deviceConnections,
+				prometheus.GaugeValue,
+				connections[normalizeIP(device.IP)],
+				device.Hostname,
+				ip,
+				mac,
 			)
 		}
 
@@ -85,32 +271,307 @@ func (c *DeviceCollector) Collect(ch chan<- prometheus.Metric) {
 				prometheus.GaugeValue,
 				device.LeaseRemain,
 				device.Hostname,
-				device.IP,
-				device.MAC,
+				ip,
+				mac,
+			)
+		}
+	}
+
+	for _, lease := range leases {
+		ch <- prometheus.MustNewConstMetric(
+			c.dhcpv6LeaseInfo,
+			prometheus.GaugeValue,
+			1,
+			lease.Hostname,
+			privateMAC(lease.MAC),
+			privateIP(lease.DUID),
+			lease.IAID,
+			lease.Interface,
+		)
+		for _, prefix := range lease.Prefixes {
+			ch <- prometheus.MustNewConstMetric(
+				c.dhcpv6PrefixInfo,
+				prometheus.GaugeValue,
+				1,
+				lease.Hostname,
+				privateIP(lease.DUID),
+				privateIP(prefix),
+			)
+		}
+	}
+
+	c.collectSpoofing(ch, devices)
+	c.collectSeen(ch, seen, newTotal)
+}
+
+// load the oui database on first use
+func (c *DeviceCollector) loadVendors() {
+	c.ouiOnce.Do(func() {
+		vendors, err := loadOUIDatabase(c.config.OUIFile)
+		if err != nil && (c.config.OUIFile != "" || !os.IsNotExist(err)) {
+			slog.Warn("failed to load oui database", "err", err)
+		}
+		c.vendors = vendors
+	})
+}
+
+// get the connected devices with their vendor, with the privacy mode applied to macs and ips
+func (c *DeviceCollector) Devices(ctx context.Context) ([]ConnectedDevice, error) {
+	leases, err := getDHCPv6Leases(ctx)
+	if err != nil {
+		logCollectError("device", err, "source", "dhcpv6 leases")
+	}
+
+	devices, err := getConnectedDevices(ctx, leases, c.config.FingerprintFile)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.loadVendors()
+	for i := range devices {
+		// online time of wired devices as of the last scrape
+		tracked, ok := c.tracker.lookup(strings.ToLower(devices[i].MAC))
+		if ok && devices[i].OnlineTime == 0 && now.Sub(time.Unix(tracked.LastSeen, 0)) <= deviceOfflineGrace {
+			devices[i].OnlineTime = float64(now.Unix() - tracked.OnlineSince)
+		}
+		devices[i].Vendor = lookupVendor(devices[i].MAC, c.vendors)
+		devices[i].IP = privateIP(devices[i].IP)
+		devices[i].MAC = privateMAC(devices[i].MAC)
+	}
+	return devices, nil
+}
+
+// track mac-per-ip mappings and export ip conflict and gateway mac change counters
+func (c *DeviceCollector) collectSpoofing(ch chan<- prometheus.Metric, devices []ConnectedDevice) {
+	// reservations are configuration, only leases and neighbors show which mac answers for an ip
+	ipMACs := make(map[string]map[string]bool)
+	for _, device := range devices {
+		if device.IP == "" || device.MAC == "" || device.reservation {
+			continue
+		}
+		if ipMACs[device.IP] == nil {
+			ipMACs[device.IP] = make(map[string]bool)
+		}
+		ipMACs[device.IP][strings.ToLower(device.MAC)] = true
+	}
+
+	gateways, err := getDefaultGateways()
+	if err != nil {
+		slog.Warn("failed to read default gateways", "err", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// count an ip conflict each time an ip starts resolving to more than one mac
+	for ip, macs := range ipMACs {
+		if len(macs) > 1 && !c.conflicting[ip] {
+			c.conflictCounts[ip]++
+		}
+	}
+	c.conflicting = make(map[string]bool)
+	for ip, macs := range ipMACs {
+		if len(macs) > 1 {
+			c.conflicting[ip] = true
+		}
+	}
+
+	for ip, count := range c.conflictCounts {
+		ch <- prometheus.MustNewConstMetric(
+			c.ipConflicts,
+			prometheus.CounterValue,
+			count,
+			privateIP(ip),
+		)
+	}
+
+	// a change is counted when the set of macs seen for a gateway differs from the previous scrape
+	for _, gateway := range gateways {
+		macs := make([]string, 0, len(ipMACs[gateway]))
+		for mac := range ipMACs[gateway] {
+			macs = append(macs, mac)
+		}
+		sort.Strings(macs)
+		if len(macs) > 0 {
+			set := strings.Join(macs, ",")
+			if last, ok := c.gatewayMACs[gateway]; ok && last != set {
+				c.gatewayChanges[gateway]++
+			}
+			c.gatewayMACs[gateway] = set
+		}
+
+		for _, mac := range macs {
+			ch <- prometheus.MustNewConstMetric(
+				c.gatewayInfo,
+				prometheus.GaugeValue,
+				1,
+				gateway,
+				privateMAC(mac),
 			)
 		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.gatewayMACChanges,
+			prometheus.CounterValue,
+			c.gatewayChanges[gateway],
+			gateway,
+		)
+	}
+}
+
+// export when present device macs were first and last seen and the new device counter
+func (c *DeviceCollector) collectSeen(ch chan<- prometheus.Metric, seen map[string]trackedDevice, newTotal float64) {
+	for mac, device := range seen {
+		ch <- prometheus.MustNewConstMetric(
+			c.firstSeen,
+			prometheus.GaugeValue,
+			float64(device.FirstSeen),
+			privateMAC(mac),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.lastSeen,
+			prometheus.GaugeValue,
+			float64(device.LastSeen),
+			privateMAC(mac),
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(c.newDevices, prometheus.CounterValue, newTotal)
+}
+
+// get the lowercase macs of devices currently on the network, leases and static hosts
+// outlive the device, so only associations, bridge fdb entries and live neighbor entries count
+func getPresentMACs(devices []ConnectedDevice) map[string]bool {
+	// the bridge forgets addresses it has not seen traffic from within the ageing time (300s by default)
+	learned := make(map[string]bool)
+	if bridges, err := getBridges(); err == nil {
+		for _, bridge := range bridges {
+			entries, err := getBridgeFDB(bridge)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				learned[entry.MAC] = true
+			}
+		}
+	}
+
+	present := make(map[string]bool)
+	for _, device := range devices {
+		mac := strings.ToLower(device.MAC)
+		if mac == "" {
+			continue
+		}
+		// /proc/net/arp entries have an interface but no nud state
+		if device.ConnectionType == "wireless" || learned[mac] || presentNeighborStates[device.State] ||
+			(device.State == "" && device.Interface != "") {
+			present[mac] = true
+		}
+	}
+	return present
+}
+
+// count conntrack entries per address, by original source and by the
+// reply source of destination nat (port forwarded) connections
+func getConnectionCountsByIP() (map[string]float64, error) {
+	entries, err := getConntrackEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]float64)
+	for _, entry := range entries {
+		src := normalizeIP(entry.Src)
+		counts[src]++
+		if replySrc := normalizeIP(entry.ReplySrc); replySrc != "" && replySrc != src {
+			counts[replySrc]++
+		}
+	}
+	return counts, nil
+}
+
+// canonical form of an ip address, nf_conntrack prints ipv6 addresses uncompressed
+func normalizeIP(address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
+// get ipv4 default gateways from /proc/net/route
+func getDefaultGateways() ([]string, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var gateways []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+
+	// skip header line
+	scanner.Scan()
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// route format: Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+
+		// gateway is a little-endian hex encoded ipv4 address
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+
+		gateway := ip.String()
+		if gateway != "0.0.0.0" && !seen[gateway] {
+			seen[gateway] = true
+			gateways = append(gateways, gateway)
+		}
 	}
+
+	return gateways, scanner.Err()
 }
 
 // connected device information
 type ConnectedDevice struct {
-	Hostname    string
-	IP          string
-	MAC         string
-	OnlineTime  float64
-	LeaseRemain float64
+	Hostname    string  `json:"hostname"`
+	IP          string  `json:"ip"`
+	MAC         string  `json:"mac"`
+	DeviceType  string  `json:"device_type"`
+	Static      bool    `json:"static"`
+	OnlineTime  float64 `json:"online_seconds"`
+	LeaseRemain float64 `json:"lease_remaining_seconds"`
+	// interface and nud state from the neighbor table
+	Interface string `json:"interface"`
+	State     string `json:"state"`
+	// "wireless" for associated stations, "wired" for other neighbors, empty if unknown
+	ConnectionType string `json:"connection_type"`
+	SSID           string `json:"ssid"`
+	Band           string `json:"band"`
+	Signal         int    `json:"signal_dbm,omitempty"`
+	// only set by DeviceCollector.Devices, the collector looks it up per scrape
+	Vendor string `json:"vendor"`
+	// only known from a static reservation, not seen in leases or the neighbor table
+	reservation bool
 }
 
-// get connected devices from dhcp leases and arp table
-func getConnectedDevices() ([]ConnectedDevice, error) {
+// get connected devices from dhcp leases, dhcpv6 leases and arp table
+func getConnectedDevices(ctx context.Context, dhcpv6Leases []DHCPv6Lease, fingerprintFile string) ([]ConnectedDevice, error) {
 
 	// use composite key (mac+ip) to support both ipv4 and ipv6
 	devices := make(map[string]*ConnectedDevice)
 
 	// read dhcp leases from /tmp/dhcp.leases or /var/dhcp.leases
 	dhcpDevices, err := parseDHCPLeases()
-	if err != nil {
-		log.Printf("warning: failed to read dhcp leases: %v", err)
+	if err != nil && !isExpectedError(err) {
+		slog.Warn("failed to read dhcp leases", "err", err)
 	} else {
 		for _, d := range dhcpDevices {
 			key := d.MAC + "|" + d.IP
@@ -118,25 +579,88 @@ func getConnectedDevices() ([]ConnectedDevice, error) {
 		}
 	}
 
+	// add addresses assigned by odhcpd, so ipv6-only clients are reported too
+	for _, lease := range dhcpv6Leases {
+		for _, address := range lease.Addresses {
+			key := lease.MAC + "|" + address
+			devices[key] = &ConnectedDevice{
+				Hostname:    lease.Hostname,
+				IP:          address,
+				MAC:         lease.MAC,
+				LeaseRemain: lease.LeaseRemain,
+			}
+		}
+	}
+
 	// read arp table to get additional connected devices
-	arpDevices, err := parseARPTable()
+	arpDevices, err := parseARPTable(ctx)
 	if err != nil {
-		log.Printf("warning: failed to read arp table: %v", err)
+		logCollectError("device", err, "source", "arp table")
+	}
+	neighbors := make(map[string]*ConnectedDevice, len(arpDevices))
+	for _, d := range arpDevices {
+		neighbors[d.IP] = d
+
+		// failed and incomplete entries have no mac and only carry the state of leased devices
+		if d.MAC == "" {
+			continue
+		}
+		key := d.MAC + "|" + d.IP
+		if _, ok := devices[key]; !ok {
+			devices[key] = d
+		}
+	}
+
+	// merge static dhcp reservations so statically-leased devices are reported too
+	staticDevices, err := parseStaticHosts()
+	if err != nil && !isExpectedError(err) {
+		slog.Warn("failed to read static dhcp hosts", "err", err)
 	} else {
-		for _, d := range arpDevices {
-			key := d.MAC + "|" + d.IP
-			if _, ok := devices[key]; !ok {
-				devices[key] = d
+		for _, d := range staticDevices {
+			found := false
+			for _, device := range devices {
+				if strings.EqualFold(device.MAC, d.MAC) {
+					found = true
+					device.Static = true
+					if device.Hostname == "" {
+						device.Hostname = d.Hostname
+					}
+				}
+			}
+			if !found {
+				d.reservation = true
+				devices[d.MAC+"|"+d.IP] = d
 			}
 		}
 	}
 
+	// classify devices from dhcp fingerprints recorded by the hotplug script
+	fingerprints := loadDHCPFingerprints(fingerprintFile)
+
+	stations := getWirelessAssociations(ctx)
+	names := loadDeviceNames()
+
 	// convert map to slice
 	var result []ConnectedDevice
 	for _, device := range devices {
 
 		// ensure we have at least ip or mac
 		if device.IP != "" || device.MAC != "" {
+			device.Hostname = friendlyHostname(device.Hostname, device.MAC, names)
+			if neighbor, ok := neighbors[device.IP]; ok && (neighbor.MAC == "" || strings.EqualFold(neighbor.MAC, device.MAC)) {
+				device.Interface = neighbor.Interface
+				device.State = neighbor.State
+			}
+			if station, ok := stations[strings.ToLower(device.MAC)]; ok {
+				device.ConnectionType = "wireless"
+				device.SSID = station.SSID
+				device.Band = station.Band
+				device.Signal = station.Signal
+				device.OnlineTime = station.ConnectedTime
+			} else if device.Interface != "" {
+				device.ConnectionType = "wired"
+			}
+			device.DeviceType = classifyDevice(fingerprints[strings.ToLower(device.MAC)])
 			result = append(result, *device)
 		}
 	}
@@ -144,6 +668,47 @@ func getConnectedDevices() ([]ConnectedDevice, error) {
 	return result, nil
 }
 
+// wireless association of a device
+type WirelessAssociation struct {
+	SSID          string
+	Band          string
+	Signal        int
+	ConnectedTime float64
+}
+
+// get wireless associations keyed by lowercase mac, with the ssid and band of the access point interface
+func getWirelessAssociations(ctx context.Context) map[string]WirelessAssociation {
+	associations := make(map[string]WirelessAssociation)
+
+	stations, err := getWirelessStations(ctx)
+	if err != nil && !isExpectedError(err) {
+		slog.Warn("failed to read wireless stations", "err", err)
+		return associations
+	}
+	if len(stations) == 0 {
+		return associations
+	}
+
+	// ssid and band are only known with rpcd-mod-iwinfo
+	radios := make(map[string]WirelessRadio)
+	if list, err := getWirelessRadios(ctx); err == nil {
+		for _, radio := range list {
+			radios[radio.Interface] = radio
+		}
+	}
+
+	for _, station := range stations {
+		radio := radios[station.Interface]
+		associations[station.MAC] = WirelessAssociation{
+			SSID:          radio.SSID,
+			Band:          radio.Band,
+			Signal:        station.Signal,
+			ConnectedTime: station.ConnectedTime,
+		}
+	}
+	return associations
+}
+
 // parse dhcp leases file
 func parseDHCPLeases() ([]*ConnectedDevice, error) {
 	// try common locations for dhcp leases file
@@ -205,10 +770,43 @@ func parseDHCPLeases() ([]*ConnectedDevice, error) {
 	return devices, scanner.Err()
 }
 
+// parse static dhcp host sections from /etc/config/dhcp
+func parseStaticHosts() ([]*ConnectedDevice, error) {
+	sections, err := loadUCIConfig("dhcp")
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []*ConnectedDevice
+	for _, section := range sections {
+		if section.Type != "host" {
+			continue
+		}
+
+		// ip may be "ignore" to deny the host a lease
+		ip := section.Option("ip")
+		if net.ParseIP(ip) == nil {
+			ip = ""
+		}
+
+		// a host may list several macs sharing one reservation
+		for _, mac := range section.List("mac") {
+			devices = append(devices, &ConnectedDevice{
+				Hostname: section.Option("name"),
+				IP:       ip,
+				MAC:      strings.ToLower(mac),
+				Static:   true,
+			})
+		}
+	}
+
+	return devices, nil
+}
+
 // parse arp table to get connected devices
-func parseARPTable() ([]*ConnectedDevice, error) {
+func parseARPTable(ctx context.Context) ([]*ConnectedDevice, error) {
 	// try to use 'ip neigh' command first (more modern)
-	output, err := exec.Command("ip", "neigh", "show").Output()
+	output, err := runCommand(ctx, "ip", "neigh", "show")
 	if err == nil {
 		return parseIPNeigh(string(output))
 	}
@@ -231,7 +829,7 @@ func parseARPTable() ([]*ConnectedDevice, error) {
 		fields := strings.Fields(line)
 
 		// arp format: IP HWtype Flags HWaddress Mask Device
-		if len(fields) >= 4 {
+		if len(fields) >= 6 {
 			ip := fields[0]
 			mac := fields[3]
 
@@ -240,12 +838,14 @@ func parseARPTable() ([]*ConnectedDevice, error) {
 				continue
 			}
 
+			// /proc/net/arp has no nud state
 			devices = append(devices, &ConnectedDevice{
 				Hostname:    "",
 				IP:          ip,
 				MAC:         mac,
 				LeaseRemain: 0,
 				OnlineTime:  0,
+				Interface:   fields[5],
 			})
 		}
 	}
@@ -262,30 +862,57 @@ func parseIPNeigh(output string) ([]*ConnectedDevice, error) {
 		line := scanner.Text()
 		fields := strings.Fields(line)
 
-		// format: <ip> dev <interface> lladdr <mac> <state>
-		if len(fields) >= 5 {
+		// format: <ip> dev <interface> [lladdr <mac>] [router] <state>
+		// failed and incomplete entries have no lladdr
+		if len(fields) >= 4 {
 			ip := fields[0]
 			mac := ""
+			iface := ""
 
-			// find lladdr (link layer address)
-			for i, field := range fields {
-				if field == "lladdr" && i+1 < len(fields) {
+			// find lladdr (link layer address) and device
+			for i, field := range fields[:len(fields)-1] {
+				switch field {
+				case "lladdr":
 					mac = fields[i+1]
-					break
+				case "dev":
+					iface = fields[i+1]
 				}
 			}
 
-			if mac != "" {
-				devices = append(devices, &ConnectedDevice{
-					Hostname:    "",
-					IP:          ip,
-					MAC:         mac,
-					LeaseRemain: 0,
-					OnlineTime:  0,
-				})
-			}
+			devices = append(devices, &ConnectedDevice{
+				Hostname:    "",
+				IP:          ip,
+				MAC:         mac,
+				LeaseRemain: 0,
+				OnlineTime:  0,
+				Interface:   iface,
+				State:       fields[len(fields)-1],
+			})
 		}
 	}
 
 	return devices, scanner.Err()
 }
+
+// load device configuration from environment variables
+func loadDeviceConfig() *DeviceConfig {
+	config := &DeviceConfig{
+		FingerprintFile: defaultFingerprintFile,
+		StateFile:       defaultDeviceStateFile,
+	}
+
+	// dhcp_fingerprint_file: fingerprint file written by the dhcp hotplug script
+	if path := os.Getenv("DHCP_FINGERPRINT_FILE"); path != "" {
+		config.FingerprintFile = path
+	}
+
+	// device_oui_file: oui database used for the vendor label
+	config.OUIFile = os.Getenv("DEVICE_OUI_FILE")
+
+	// device_state_file: file persisting first-seen/last-seen times, "" keeps them in memory only
+	if path, ok := os.LookupEnv("DEVICE_STATE_FILE"); ok {
+		config.StateFile = path
+	}
+
+	return config
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"regexp"
+	"strings"
+)
+
+// uci package holding friendly device names (/etc/config/openwrt_exporter)
+const deviceNamesConfig = "openwrt_exporter"
+
+// hostnames devices make up when the user never named them (android-9f3a..., ESP_1A2B3C, ...)
+var genericHostnamePattern = regexp.MustCompile(
+	`(?i)^((android|galaxy|esp|espressif|iphone|ipad|localhost|unknown|dhcpcd|wlan0?|tasmota)([-_][0-9a-f]+)?|[0-9a-f]{12})$`,
+)
+
+// load friendly device names keyed by lowercase mac from the device sections of /etc/config/openwrt_exporter
+//
+//	config device
+//		option name 'Living Room TV'
+//		list mac 'aa:bb:cc:dd:ee:ff'
+func loadDeviceNames() map[string]string {
+	names := make(map[string]string)
+
+	sections, err := loadUCIConfig(deviceNamesConfig)
+	if err != nil {
+		return names
+	}
+
+	for _, section := range sections {
+		name := section.Option("name")
+		if section.Type != "device" || name == "" {
+			continue
+		}
+		for _, mac := range section.List("mac") {
+			names[strings.ToLower(mac)] = name
+		}
+	}
+
+	return names
+}
+
+// get the friendly name of a device if its hostname is empty or generic
+func friendlyHostname(hostname, mac string, names map[string]string) string {
+	if hostname != "" && !genericHostnamePattern.MatchString(hostname) {
+		return hostname
+	}
+	if name, ok := names[strings.ToLower(mac)]; ok {
+		return name
+	}
+	return hostname
+}
@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// default device state file, /etc lives on the overlay so it survives reboots
+	defaultDeviceStateFile = "/etc/openwrt-exporter-devices.json"
+	// minimum interval between state file writes when only last-seen times changed, to spare the flash
+	deviceStateSaveInterval = time.Hour
+	// devices not seen for this long start a new online session when they come back
+	deviceOfflineGrace = 5 * time.Minute
+	// devices not seen for this long are forgotten, so randomized macs do not pile up
+	deviceStateRetention = 90 * 24 * time.Hour
+)
+
+// first and last time a mac address was seen and the start of its current online session, in unix seconds
+type trackedDevice struct {
+	FirstSeen   int64 `json:"first_seen"`
+	LastSeen    int64 `json:"last_seen"`
+	OnlineSince int64 `json:"online_since"`
+}
+
+// persisted device tracker state
+type deviceTrackerState struct {
+	NewTotal float64                   `json:"new_total"`
+	Devices  map[string]*trackedDevice `json:"devices"`
+}
+
+// first-seen/last-seen tracker of mac addresses, persisted to a state file if configured
+type deviceTracker struct {
+	path string
+
+	mu       sync.Mutex
+	loaded   bool
+	baseline bool
+	state    deviceTrackerState
+	lastSave time.Time
+}
+
+// create a new device tracker, state is only kept in memory if path is empty
+func newDeviceTracker(path string) *deviceTracker {
+	return &deviceTracker{
+		path: path,
+		state: deviceTrackerState{
+			Devices: make(map[string]*trackedDevice),
+		},
+	}
+}
+
+// record the macs seen in a scrape and return the tracked state of each of them
+func (t *deviceTracker) observe(macs map[string]bool, now time.Time) (map[string]trackedDevice, float64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var loadErr error
+	if !t.loaded {
+		t.loaded = true
+		loadErr = t.load()
+		// devices present when tracking starts are not new to the network
+		t.baseline = len(t.state.Devices) == 0
+	}
+
+	added := false
+	for mac := range macs {
+		device, ok := t.state.Devices[mac]
+		if !ok {
+			device = &trackedDevice{FirstSeen: now.Unix()}
+			t.state.Devices[mac] = device
+			if !t.baseline {
+				t.state.NewTotal++
+			}
+			added = true
+		}
+		if device.OnlineSince == 0 || now.Sub(time.Unix(device.LastSeen, 0)) > deviceOfflineGrace {
+			device.OnlineSince = now.Unix()
+		}
+		device.LastSeen = now.Unix()
+	}
+	t.baseline = false
+
+	for mac, device := range t.state.Devices {
+		if now.Sub(time.Unix(device.LastSeen, 0)) > deviceStateRetention {
+			delete(t.state.Devices, mac)
+		}
+	}
+
+	seen := make(map[string]trackedDevice, len(macs))
+	for mac := range macs {
+		seen[mac] = *t.state.Devices[mac]
+	}
+
+	var saveErr error
+	if t.path != "" && (added || now.Sub(t.lastSave) >= deviceStateSaveInterval) {
+		saveErr = t.save()
+		t.lastSave = now
+	}
+
+	return seen, t.state.NewTotal, errors.Join(loadErr, saveErr)
+}
+
+// get the tracked state of a mac without recording it as seen
+func (t *deviceTracker) lookup(mac string) (trackedDevice, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	device, ok := t.state.Devices[mac]
+	if !ok {
+		return trackedDevice{}, false
+	}
+	return *device, true
+}
+
+// load the state file, a missing file starts an empty state
+func (t *deviceTracker) load() error {
+	if t.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state deviceTrackerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Devices == nil {
+		state.Devices = make(map[string]*trackedDevice)
+	}
+	t.state = state
+	return nil
+}
+
+// write the state file atomically
+func (t *deviceTracker) save() error {
+	data, err := json.Marshal(t.state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
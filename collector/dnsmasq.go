@@ -0,0 +1,206 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dnsmasq ubus metrics exported as counters
+var dnsmasqCounters = map[string]string{
+	"dns_cache_inserted":    "total number of dns cache insertions",
+	"dns_cache_live_freed":  "total number of live dns cache entries evicted",
+	"dns_queries_forwarded": "total number of dns queries forwarded upstream",
+	"dns_auth_answered":     "total number of dns queries answered authoritatively",
+	"dns_local_answered":    "total number of dns queries answered locally",
+	"dns_stale_answered":    "total number of dns queries answered from stale cache",
+	"dns_unanswered":        "total number of dns queries left unanswered",
+}
+
+// dnsmasq ubus metrics exported as gauges (dnssec high-water marks)
+var dnsmasqGauges = map[string]string{
+	"dnssec_max_crypto_use": "maximum dnssec crypto operations used by a single query",
+	"dnssec_max_sig_fail":   "maximum dnssec signature failures seen by a single query",
+	"dnssec_max_work":       "maximum dnssec validation subqueries used by a single query",
+}
+
+// matches dnsmasq log-queries validation lines, e.g. "validation example.com is BOGUS"
+var dnssecValidationRegexp = regexp.MustCompile(`validation \S+ is (SECURE|INSECURE|BOGUS|ABANDONED)`)
+
+// dnsmasq dns and dnssec metrics collector
+type DnsmasqCollector struct {
+	metrics         map[string]*prometheus.Desc
+	dnssecEnabled   *prometheus.Desc
+	dnssecValidated *prometheus.Desc
+
+	mu          sync.Mutex
+	validations map[string]float64
+	follower    sync.Once
+}
+
+// create a new dnsmasq collector
+func NewDnsmasqCollector() *DnsmasqCollector {
+	c := &DnsmasqCollector{
+		metrics: make(map[string]*prometheus.Desc),
+		dnssecEnabled: prometheus.NewDesc(
+			"openwrt_dnsmasq_dnssec_enabled",
+			"whether dnssec validation is enabled in dnsmasq (1 = enabled)",
+			nil, nil,
+		),
+		dnssecValidated: prometheus.NewDesc(
+			"openwrt_dnsmasq_dnssec_validations_total",
+			"total number of dnssec validation results logged by dnsmasq",
+			[]string{"result"}, nil,
+		),
+		validations: make(map[string]float64),
+	}
+
+	for name, help := range dnsmasqCounters {
+		c.metrics[name] = prometheus.NewDesc("openwrt_dnsmasq_"+name+"_total", help, nil, nil)
+	}
+	for name, help := range dnsmasqGauges {
+		c.metrics[name] = prometheus.NewDesc("openwrt_dnsmasq_"+name, help, nil, nil)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *DnsmasqCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.metrics {
+		ch <- desc
+	}
+	ch <- c.dnssecEnabled
+	ch <- c.dnssecValidated
+}
+
+// collect implements prometheus.Collector
+func (c *DnsmasqCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *DnsmasqCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	// the dnssec metrics come from uci and the system log, so they are exported without ubus too
+	metrics, err := getDnsmasqMetrics(ctx)
+	if err != nil {
+		logCollectError("dnsmasq", err)
+	}
+
+	for name, value := range metrics {
+		desc, ok := c.metrics[name]
+		if !ok {
+			continue
+		}
+
+		valueType := prometheus.GaugeValue
+		if _, ok := dnsmasqCounters[name]; ok {
+			valueType = prometheus.CounterValue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, valueType, value)
+	}
+
+	enabled := isDnsmasqDNSSECEnabled()
+	ch <- prometheus.MustNewConstMetric(
+		c.dnssecEnabled,
+		prometheus.GaugeValue,
+		boolToFloat64(enabled),
+	)
+
+	if !enabled {
+		return
+	}
+
+	// validation results are only available from the query log
+	c.follower.Do(func() { goBackground(c.followValidations) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, result := range []string{"SECURE", "INSECURE", "BOGUS", "ABANDONED"} {
+		ch <- prometheus.MustNewConstMetric(
+			c.dnssecValidated,
+			prometheus.CounterValue,
+			c.validations[result],
+			result,
+		)
+	}
+}
+
+// follow the system log and count dnssec validation results, restarting logread if it exits
+func (c *DnsmasqCollector) followValidations(ctx context.Context) {
+	for {
+		if err := c.readValidations(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("error following dnsmasq log", "err", err)
+		}
+		if !sleepContext(ctx, 10*time.Second) {
+			return
+		}
+	}
+}
+
+// read dnssec validation results from 'logread -f' until it exits
+func (c *DnsmasqCollector) readValidations(ctx context.Context) error {
+	cmd, stdout, err := startCommand(ctx, "logread", "-f", "-e", "dnsmasq")
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		match := dnssecValidationRegexp.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		c.mu.Lock()
+		c.validations[match[1]]++
+		c.mu.Unlock()
+	}
+
+	return cmd.Wait()
+}
+
+// get dnsmasq metrics from ubus
+func getDnsmasqMetrics(ctx context.Context) (map[string]float64, error) {
+	var raw map[string]any
+	if err := ubusCall(ctx, "dnsmasq", "metrics", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]float64)
+	for name, value := range raw {
+		if number, ok := value.(float64); ok {
+			metrics[name] = number
+		}
+	}
+
+	return metrics, nil
+}
+
+// check whether any dnsmasq instance has dnssec enabled in uci
+func isDnsmasqDNSSECEnabled() bool {
+	sections, err := loadUCIConfig("dhcp")
+	if err != nil {
+		return false
+	}
+
+	for _, section := range sections {
+		if section.Type == "dnsmasq" && section.Option("dnssec") == "1" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// convert a boolean to a gauge value
+func boolToFloat64(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
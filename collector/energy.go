@@ -0,0 +1,296 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// estimated energy consumption collector based on a configurable board power model
+type EnergyCollector struct {
+	power  *prometheus.Desc
+	energy *prometheus.Desc
+	config *EnergyConfig
+
+	mu       sync.Mutex
+	watts    map[string]float64
+	kwh      float64
+	sampled  bool
+	lastTime time.Time
+	lastCPU  cpuTimes
+	lastTx   map[string]float64
+}
+
+// board power model in watts
+type PowerModel struct {
+	Base  float64
+	CPU   float64
+	Radio float64
+	Port  float64
+}
+
+// energy estimation configuration
+type EnergyConfig struct {
+	Model          *PowerModel
+	SampleInterval time.Duration
+}
+
+// aggregate cpu time counters from /proc/stat
+type cpuTimes struct {
+	busy  float64
+	total float64
+}
+
+// create a new energy collector
+func NewEnergyCollector(config *EnergyConfig) *EnergyCollector {
+	c := &EnergyCollector{
+		power: prometheus.NewDesc(
+			"openwrt_power_estimated_watts",
+			"estimated instantaneous power draw per component in watts",
+			[]string{"component"}, nil,
+		),
+		energy: prometheus.NewDesc(
+			"openwrt_energy_estimated_kwh_total",
+			"estimated energy consumed since the exporter started in kilowatt-hours",
+			nil, nil,
+		),
+		config: config,
+		watts:  make(map[string]float64),
+		lastTx: make(map[string]float64),
+	}
+
+	if c.config.Model != nil {
+		goBackground(c.sample)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *EnergyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.power
+	ch <- c.energy
+}
+
+// collect implements prometheus.Collector
+func (c *EnergyCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.config.Model == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for component, watts := range c.watts {
+		ch <- prometheus.MustNewConstMetric(
+			c.power,
+			prometheus.GaugeValue,
+			watts,
+			component,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.energy,
+		prometheus.CounterValue,
+		c.kwh,
+	)
+}
+
+// periodically estimate power draw and integrate it into energy
+func (c *EnergyCollector) sample(ctx context.Context) {
+	ticker := time.NewTicker(c.config.SampleInterval)
+	defer ticker.Stop()
+
+	for running := true; running; running = nextTick(ctx, ticker) {
+		now := time.Now()
+		cpu, err := readCPUTimes()
+		if err != nil {
+			slog.Error("error sampling cpu load", "err", err)
+			continue
+		}
+		tx := getRadioTransmitMs(ctx)
+		ports := countLinkedPorts()
+
+		c.mu.Lock()
+		if c.sampled {
+			seconds := now.Sub(c.lastTime).Seconds()
+			model := c.config.Model
+
+			// cpu utilization since the previous sample
+			cpuLoad := 0.0
+			if total := cpu.total - c.lastCPU.total; total > 0 {
+				cpuLoad = (cpu.busy - c.lastCPU.busy) / total
+			}
+
+			// sum of transmit duty cycles of all radios
+			radioDuty := 0.0
+			for phy, ms := range tx {
+				if last, ok := c.lastTx[phy]; ok && ms >= last && seconds > 0 {
+					radioDuty += min((ms-last)/1000/seconds, 1)
+				}
+			}
+
+			c.watts["base"] = model.Base
+			c.watts["cpu"] = model.CPU * cpuLoad
+			c.watts["radio"] = model.Radio * radioDuty
+			c.watts["ports"] = model.Port * float64(ports)
+
+			total := 0.0
+			for _, watts := range c.watts {
+				total += watts
+			}
+			c.kwh += total * seconds / 3600 / 1000
+		}
+		c.sampled = true
+		c.lastTime = now
+		c.lastCPU = cpu
+		c.lastTx = tx
+		c.mu.Unlock()
+	}
+}
+
+// read aggregate busy and total cpu time from /proc/stat
+func readCPUTimes() (cpuTimes, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// cpu format: cpu user nice system idle iowait irq softirq steal ...
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+
+		var times cpuTimes
+		for i, field := range fields[1:] {
+			value, _ := strconv.ParseFloat(field, 64)
+			times.total += value
+
+			// idle and iowait are not busy
+			if i != 3 && i != 4 {
+				times.busy += value
+			}
+		}
+		return times, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, err
+	}
+	return cpuTimes{}, fmt.Errorf("no cpu line in /proc/stat")
+}
+
+// get cumulative transmit time in milliseconds of the in-use channel per radio
+func getRadioTransmitMs(ctx context.Context) map[string]float64 {
+	tx := make(map[string]float64)
+
+	surveys, err := getWirelessSurveys(ctx)
+	if err != nil {
+		return tx
+	}
+
+	for _, survey := range surveys {
+		if !survey.InUse {
+			continue
+		}
+
+		// interfaces sharing a radio report the same survey, so key by phy
+		phy := readSysfsString(filepath.Join("/sys/class/net", survey.Interface, "phy80211", "name"))
+		if phy == "" {
+			phy = survey.Interface
+		}
+		if _, ok := tx[phy]; !ok {
+			tx[phy] = survey.TransmitMs
+		}
+	}
+
+	return tx
+}
+
+// count physical ethernet ports with link
+func countLinkedPorts() int {
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return 0
+	}
+
+	ports := 0
+	for _, entry := range entries {
+		base := filepath.Join("/sys/class/net", entry.Name())
+
+		// only physical devices, skipping wireless interfaces
+		if _, err := os.Stat(filepath.Join(base, "device")); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(base, "phy80211")); err == nil {
+			continue
+		}
+		if readSysfsString(filepath.Join(base, "type")) != "1" {
+			continue
+		}
+
+		if readSysfsString(filepath.Join(base, "carrier")) == "1" {
+			ports++
+		}
+	}
+
+	return ports
+}
+
+// load energy estimation configuration from environment variables
+func loadEnergyConfig() *EnergyConfig {
+	config := &EnergyConfig{
+		SampleInterval: 10 * time.Second,
+	}
+
+	// power_model: comma-separated list of base, cpu, radio and port watts
+	if modelEnv := os.Getenv("POWER_MODEL"); modelEnv != "" {
+		model := &PowerModel{}
+		for _, entry := range strings.Split(modelEnv, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			watts, err := strconv.ParseFloat(value, 64)
+			if !ok || err != nil || watts < 0 {
+				slog.Warn("invalid POWER_MODEL entry", "entry", entry)
+				continue
+			}
+
+			switch key {
+			case "base":
+				model.Base = watts
+			case "cpu":
+				model.CPU = watts
+			case "radio":
+				model.Radio = watts
+			case "port":
+				model.Port = watts
+			default:
+				slog.Warn("unknown POWER_MODEL component", "component", key)
+			}
+		}
+		config.Model = model
+	}
+
+	// power_sample_interval: interval between power estimates
+	if intervalEnv := os.Getenv("POWER_SAMPLE_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.SampleInterval = interval
+		}
+	}
+
+	return config
+}
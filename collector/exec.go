@@ -0,0 +1,177 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// command execution limits shared by all collectors
+type ExecConfig struct {
+	Timeout   time.Duration
+	MaxOutput int
+	Allowlist map[string]bool
+}
+
+// binaries collectors are allowed to run by default
+var defaultExecAllowlist = []string{"ip", "iw", "ubus", "nft", "tc", "logread", "nlbw", "uqmi", "mmcli", "chronyc", "ntpq", "opkg", "swconfig"}
+
+// returned when a collector runs a binary that is not on the allowlist
+var errCommandNotAllowed = errors.New("command is not in EXEC_ALLOWLIST")
+
+var (
+	execConfig     *ExecConfig
+	execConfigOnce sync.Once
+
+	// binaries already reported as not allowlisted, to warn once per binary
+	deniedCommands sync.Map
+)
+
+// get the exec configuration, loading it from environment variables on first use
+func getExecConfig() *ExecConfig {
+	execConfigOnce.Do(func() {
+		execConfig = loadExecConfig()
+	})
+	return execConfig
+}
+
+// replace the exec configuration used by all collectors (nil keeps the current one)
+func setExecConfig(config *ExecConfig) {
+	if config == nil {
+		return
+	}
+	execConfigOnce.Do(func() {})
+	execConfig = config
+}
+
+// load exec configuration from environment variables
+func loadExecConfig() *ExecConfig {
+	config := &ExecConfig{
+		Timeout:   5 * time.Second,
+		MaxOutput: 4 * 1024 * 1024,
+		Allowlist: make(map[string]bool),
+	}
+
+	// exec_timeout: maximum run time of a single command
+	if timeoutEnv := os.Getenv("EXEC_TIMEOUT"); timeoutEnv != "" {
+		if timeout, err := time.ParseDuration(timeoutEnv); err == nil && timeout > 0 {
+			config.Timeout = timeout
+		}
+	}
+
+	// exec_max_output: maximum stdout size of a single command in bytes
+	if maxEnv := os.Getenv("EXEC_MAX_OUTPUT"); maxEnv != "" {
+		if limit, err := strconv.Atoi(maxEnv); err == nil && limit > 0 {
+			config.MaxOutput = limit
+		}
+	}
+
+	// exec_allowlist: comma-separated list of binaries collectors may run
+	allowlist := defaultExecAllowlist
+	if allowlistEnv, ok := os.LookupEnv("EXEC_ALLOWLIST"); ok {
+		allowlist = strings.Split(allowlistEnv, ",")
+	}
+	for _, name := range allowlist {
+		if name = strings.TrimSpace(name); name != "" {
+			config.Allowlist[name] = true
+		}
+	}
+
+	return config
+}
+
+// check whether a binary is on the exec allowlist, warning the first time one is denied
+func checkCommand(name string) error {
+	if !getExecConfig().Allowlist[name] {
+		if _, warned := deniedCommands.LoadOrStore(name, true); !warned {
+			slog.Warn("command is not in EXEC_ALLOWLIST, metrics depending on it are skipped", "command", name)
+		}
+		return fmt.Errorf("%s: %w", name, errCommandNotAllowed)
+	}
+	return nil
+}
+
+// run an allowlisted command and return its stdout, enforcing the timeout and output cap
+// the command is also killed once ctx is done, e.g. at the deadline of the scrape
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if err := checkCommand(name); err != nil {
+		return nil, err
+	}
+	return runCommandTimeout(ctx, getExecConfig().Timeout, name, args...)
+}
+
+// run a command with its own timeout and return its stdout, enforcing the output cap
+func runCommandTimeout(parent context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	if err := parent.Err(); err != nil {
+		return nil, fmt.Errorf("%s not started: %w", name, err)
+	}
+	config := getExecConfig()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{max: config.MaxOutput, cancel: cancel}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if stdout.exceeded {
+		return nil, fmt.Errorf("output of %s exceeded %d bytes", name, config.MaxOutput)
+	}
+	if err := parent.Err(); err != nil {
+		return nil, fmt.Errorf("%s cancelled: %w", name, err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%s timed out after %s", name, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return stdout.buf.Bytes(), nil
+}
+
+// start a long-running allowlisted command without a timeout (e.g. 'logread -f') and return its output,
+// once ctx is cancelled the command is killed and the output closed, even if a child process still holds it
+func startCommand(ctx context.Context, name string, args ...string) (*exec.Cmd, io.Reader, error) {
+	if err := checkCommand(name); err != nil {
+		return nil, nil, err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	context.AfterFunc(ctx, func() { _ = stdout.Close() })
+	return cmd, stdout, nil
+}
+
+// buffer that kills the command once its output grows beyond max bytes
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+	cancel   context.CancelFunc
+}
+
+// write implements io.Writer
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.max {
+		b.exceeded = true
+		b.cancel()
+		return 0, fmt.Errorf("output exceeded %d bytes", b.max)
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,391 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// line of the key=value plugin output schema
+var execKeyValueRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*=\S+$`)
+
+// collector merging the output of user-configured commands into the exposition
+type ExecPluginCollector struct {
+	up       *prometheus.Desc
+	duration *prometheus.Desc
+	value    *prometheus.Desc
+	config   *ExecPluginConfig
+
+	mu    sync.Mutex
+	cache map[string]*execPluginResult
+}
+
+// exec plugin configuration
+type ExecPluginConfig struct {
+	Plugins []ExecPlugin
+}
+
+// command whose stdout is exposed, in prometheus text format or as key=value lines
+type ExecPlugin struct {
+	Name    string
+	Command []string
+	Timeout time.Duration
+	// reuse the last successful output for this long (0 runs the command on every scrape)
+	CacheTTL time.Duration
+}
+
+// last successful run of a plugin
+type execPluginResult struct {
+	families []execPluginFamily
+	duration float64
+	ran      time.Time
+}
+
+// metrics of one family printed by a plugin
+type execPluginFamily struct {
+	name    string
+	metrics []prometheus.Metric
+}
+
+// create a new exec plugin collector
+func NewExecPluginCollector(config *ExecPluginConfig) *ExecPluginCollector {
+	return &ExecPluginCollector{
+		up: prometheus.NewDesc(
+			"openwrt_exec_up",
+			"whether the last run of an exec plugin succeeded and its output could be parsed (1 = success)",
+			[]string{"plugin"}, nil,
+		),
+		duration: prometheus.NewDesc(
+			"openwrt_exec_duration_seconds",
+			"run time of the last run of an exec plugin in seconds",
+			[]string{"plugin"}, nil,
+		),
+		value: prometheus.NewDesc(
+			"openwrt_exec_value",
+			"value of a key printed by an exec plugin using the key=value schema",
+			[]string{"plugin", "key"}, nil,
+		),
+		config: config,
+		cache:  make(map[string]*execPluginResult),
+	}
+}
+
+// describe implements prometheus.Collector
+// plugin metrics are dynamic, so the collector is unchecked
+func (c *ExecPluginCollector) Describe(_ chan<- *prometheus.Desc) {}
+
+// collect implements prometheus.Collector
+func (c *ExecPluginCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *ExecPluginCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	if len(c.config.Plugins) == 0 {
+		return
+	}
+
+	// plugins run concurrently, so a slow one only delays the scrape by its own timeout
+	results := make([]*execPluginResult, len(c.config.Plugins))
+	errs := make([]error, len(c.config.Plugins))
+	var wg sync.WaitGroup
+	for i, plugin := range c.config.Plugins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.run(ctx, plugin)
+		}()
+	}
+	wg.Wait()
+
+	// metric families may only come from one plugin, duplicates would fail the whole scrape
+	// (key=value samples are told apart by their plugin label)
+	owners := make(map[string]string)
+	for i, plugin := range c.config.Plugins {
+		if errs[i] != nil {
+			slog.Error("error collecting exec plugin metrics", "plugin", plugin.Name, "err", errs[i])
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0, plugin.Name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1, plugin.Name)
+		ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, results[i].duration, plugin.Name)
+
+		for _, family := range results[i].families {
+			if owner, ok := owners[family.name]; ok && family.name != "openwrt_exec_value" {
+				slog.Warn("exec plugin metric is already exported by another plugin", "plugin", plugin.Name, "metric", family.name, "owner", owner)
+				continue
+			}
+			owners[family.name] = plugin.Name
+			for _, metric := range family.metrics {
+				ch <- metric
+			}
+		}
+	}
+}
+
+// run a plugin or return its cached result
+func (c *ExecPluginCollector) run(ctx context.Context, plugin ExecPlugin) (*execPluginResult, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	cached := c.cache[plugin.Name]
+	c.mu.Unlock()
+	if cached != nil && now.Sub(cached.ran) < plugin.CacheTTL {
+		return cached, nil
+	}
+
+	// All rejects plugins without a command, this covers collectors created with the constructor
+	if len(plugin.Command) == 0 {
+		return nil, errors.New("no command configured")
+	}
+	output, err := runCommandTimeout(ctx, plugin.Timeout, plugin.Command[0], plugin.Command[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	families, err := c.parseOutput(plugin.Name, output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &execPluginResult{
+		families: families,
+		duration: time.Since(now).Seconds(),
+		ran:      now,
+	}
+	c.mu.Lock()
+	c.cache[plugin.Name] = result
+	c.mu.Unlock()
+
+	return result, nil
+}
+
+// parse plugin output as key=value lines, or as prometheus text format otherwise
+func (c *ExecPluginCollector) parseOutput(plugin string, output []byte) ([]execPluginFamily, error) {
+	var lines []string
+	keyValue := true
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+		if !execKeyValueRegexp.MatchString(line) {
+			keyValue = false
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	if keyValue {
+		return c.parseKeyValues(plugin, lines)
+	}
+	return parseTextMetrics(output)
+}
+
+// parse "<key>=<number>" lines into openwrt_exec_value samples
+func (c *ExecPluginCollector) parseKeyValues(plugin string, lines []string) ([]execPluginFamily, error) {
+	family := execPluginFamily{name: "openwrt_exec_value"}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		key, raw, _ := strings.Cut(line, "=")
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for key %s", raw, key)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate key %s", key)
+		}
+		seen[key] = true
+		family.metrics = append(family.metrics, prometheus.MustNewConstMetric(c.value, prometheus.GaugeValue, value, plugin, key))
+	}
+	return []execPluginFamily{family}, nil
+}
+
+// parse prometheus text format into const metrics, rejecting output that would break the exposition
+func parseTextMetrics(output []byte) ([]execPluginFamily, error) {
+	parser := expfmt.NewTextParser(model.LegacyValidation)
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []execPluginFamily
+	for _, name := range names {
+		// built-in metric names are reserved, as for pushed metrics
+		if strings.HasPrefix(name, "openwrt_") {
+			return nil, fmt.Errorf("metric name prefix \"openwrt_\" is reserved (%s)", name)
+		}
+
+		family := families[name]
+		parsed := execPluginFamily{name: name}
+		var desc *prometheus.Desc
+		var labelNames []string
+		seen := make(map[string]bool)
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			// all samples of a family must share the label names of the first one
+			if desc == nil {
+				for key := range labels {
+					labelNames = append(labelNames, key)
+				}
+				sort.Strings(labelNames)
+				desc = prometheus.NewDesc(name, family.GetHelp(), labelNames, nil)
+			}
+			if len(labels) != len(labelNames) {
+				return nil, fmt.Errorf("label names of %s differ between samples", name)
+			}
+			labelValues := make([]string, 0, len(labelNames))
+			for _, key := range labelNames {
+				value, ok := labels[key]
+				if !ok {
+					return nil, fmt.Errorf("label names of %s differ between samples", name)
+				}
+				labelValues = append(labelValues, value)
+			}
+			series := strings.Join(labelValues, "\xff")
+			if seen[series] {
+				return nil, fmt.Errorf("duplicate sample of %s", name)
+			}
+			seen[series] = true
+
+			metric, err := newTextMetric(desc, family.GetType(), m, labelValues)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			parsed.metrics = append(parsed.metrics, metric)
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+// convert a parsed sample into a const metric of the same type
+func newTextMetric(desc *prometheus.Desc, metricType dto.MetricType, m *dto.Metric, labelValues []string) (prometheus.Metric, error) {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), labelValues...)
+	case dto.MetricType_GAUGE:
+		return prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), labelValues...)
+	case dto.MetricType_SUMMARY:
+		quantiles := make(map[float64]float64)
+		for _, q := range m.GetSummary().GetQuantile() {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+		return prometheus.NewConstSummary(desc, m.GetSummary().GetSampleCount(), m.GetSummary().GetSampleSum(), quantiles, labelValues...)
+	case dto.MetricType_HISTOGRAM:
+		buckets := make(map[float64]uint64)
+		for _, b := range m.GetHistogram().GetBucket() {
+			buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+		}
+		return prometheus.NewConstHistogram(desc, m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), buckets, labelValues...)
+	default:
+		return prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), labelValues...)
+	}
+}
+
+// load exec plugin configuration from environment variables
+func loadExecPluginConfig() *ExecPluginConfig {
+	config := &ExecPluginConfig{}
+
+	timeout := 10 * time.Second
+	var cacheTTL time.Duration
+
+	// exec_plugin_timeout: default maximum run time of a plugin command
+	if timeoutEnv := os.Getenv("EXEC_PLUGIN_TIMEOUT"); timeoutEnv != "" {
+		if parsed, err := time.ParseDuration(timeoutEnv); err == nil && parsed > 0 {
+			timeout = parsed
+		} else {
+			slog.Warn("invalid EXEC_PLUGIN_TIMEOUT", "value", timeoutEnv)
+		}
+	}
+
+	// exec_plugin_cache: default time the output of a plugin is reused for
+	if cacheEnv := os.Getenv("EXEC_PLUGIN_CACHE"); cacheEnv != "" {
+		if parsed, err := time.ParseDuration(cacheEnv); err == nil && parsed >= 0 {
+			cacheTTL = parsed
+		} else {
+			slog.Warn("invalid EXEC_PLUGIN_CACHE", "value", cacheEnv)
+		}
+	}
+
+	// exec_plugins: semicolon-separated list of <name>[:<timeout>[:<cache>]]=<command line>
+	if pluginsEnv := os.Getenv("EXEC_PLUGINS"); pluginsEnv != "" {
+		seen := make(map[string]bool)
+		for _, entry := range strings.Split(pluginsEnv, ";") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			plugin, err := parseExecPlugin(entry, timeout, cacheTTL)
+			if err != nil {
+				slog.Warn("invalid EXEC_PLUGINS entry", "entry", entry, "err", err)
+				continue
+			}
+			if seen[plugin.Name] {
+				slog.Warn("duplicate EXEC_PLUGINS name", "name", plugin.Name)
+				continue
+			}
+			seen[plugin.Name] = true
+			config.Plugins = append(config.Plugins, plugin)
+		}
+	}
+
+	return config
+}
+
+// parse a "<name>[:<timeout>[:<cache>]]=<command line>" plugin entry
+func parseExecPlugin(entry string, timeout, cacheTTL time.Duration) (ExecPlugin, error) {
+	spec, commandLine, ok := strings.Cut(entry, "=")
+	command := strings.Fields(commandLine)
+	if !ok || len(command) == 0 {
+		return ExecPlugin{}, fmt.Errorf("expected <name>=<command line>")
+	}
+
+	fields := strings.Split(spec, ":")
+	if len(fields) > 3 || !labelNameRegexp.MatchString(fields[0]) {
+		return ExecPlugin{}, fmt.Errorf("invalid name %q", spec)
+	}
+	plugin := ExecPlugin{Name: fields[0], Command: command, Timeout: timeout, CacheTTL: cacheTTL}
+
+	if len(fields) > 1 && fields[1] != "" {
+		parsed, err := time.ParseDuration(fields[1])
+		if err != nil || parsed <= 0 {
+			return ExecPlugin{}, fmt.Errorf("invalid timeout %q", fields[1])
+		}
+		plugin.Timeout = parsed
+	}
+	if len(fields) > 2 && fields[2] != "" {
+		parsed, err := time.ParseDuration(fields[2])
+		if err != nil || parsed < 0 {
+			return ExecPlugin{}, fmt.Errorf("invalid cache duration %q", fields[2])
+		}
+		plugin.CacheTTL = parsed
+	}
+
+	return plugin, nil
+}
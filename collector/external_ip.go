@@ -0,0 +1,282 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// stun binding request/response message types and magic cookie (rfc 5389)
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112a442
+	// stun attributes carrying the reflexive transport address
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXorMappedAddress = 0x0020
+	// number of binding requests sent before giving up, one per stunRetryInterval
+	stunAttempts      = 3
+	stunRetryInterval = time.Second
+)
+
+// external (public) ipv4 address collector
+type ExternalIPCollector struct {
+	info    *prometheus.Desc
+	cgnat   *prometheus.Desc
+	changes *prometheus.Desc
+	config  *ExternalIPConfig
+	upnp    *UPnPConfig
+
+	mu          sync.Mutex
+	lastAddress string
+	changeCount float64
+	// changes are counted from the exporter start
+	created time.Time
+}
+
+// external ip lookup configuration
+type ExternalIPConfig struct {
+	// stun server (host:port) asked for the mapped address, upnp is used when empty
+	STUNServer string
+}
+
+// create a new external ip collector
+func NewExternalIPCollector(config *ExternalIPConfig, upnp *UPnPConfig) *ExternalIPCollector {
+	return &ExternalIPCollector{
+		info: prometheus.NewDesc(
+			"openwrt_external_ip_info",
+			"external ipv4 address of the router and how it was discovered (stun or upnp)",
+			[]string{"address", "source"}, nil,
+		),
+		cgnat: prometheus.NewDesc(
+			"openwrt_external_ip_cgnat",
+			"whether the external ipv4 address differs from the wan interface address (1 = behind cgnat or an upstream nat)",
+			nil, nil,
+		),
+		changes: prometheus.NewDesc(
+			"openwrt_external_ip_changes_total",
+			"total number of external ipv4 address changes observed since the exporter started",
+			nil, nil,
+		),
+		config:  config,
+		upnp:    upnp,
+		created: time.Now(),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ExternalIPCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.cgnat
+	ch <- c.changes
+}
+
+// collect implements prometheus.Collector
+func (c *ExternalIPCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *ExternalIPCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	address, source, err := c.getExternalIP(ctx)
+	if err != nil {
+		// without a stun server and miniupnpd there is no way to learn the address
+		logCollectError("external ip", err)
+		return
+	}
+
+	c.mu.Lock()
+	if c.lastAddress != "" && c.lastAddress != address {
+		c.changeCount++
+	}
+	c.lastAddress = address
+	changes := c.changeCount
+	c.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(
+		c.info,
+		prometheus.GaugeValue,
+		1,
+		address, source,
+	)
+	ch <- newCreatedCounter(c.changes, changes, c.created)
+
+	wanAddresses, err := getWANIPv4Addresses(ctx)
+	if err != nil {
+		logCollectError("external ip", err, "source", "wan addresses")
+		return
+	}
+	if len(wanAddresses) == 0 {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.cgnat,
+		prometheus.GaugeValue,
+		boolToFloat64(!wanAddresses[address]),
+	)
+}
+
+// get the external address from the stun server if configured, otherwise from miniupnpd
+func (c *ExternalIPCollector) getExternalIP(ctx context.Context) (string, string, error) {
+	if c.config.STUNServer != "" {
+		address, err := getSTUNMappedAddress(ctx, c.config.STUNServer)
+		if err == nil {
+			return address, "stun", nil
+		}
+		slog.Warn("stun request failed", "server", c.config.STUNServer, "err", err)
+	}
+
+	// miniupnpd reports the wan address, or the stun result when ext_perform_stun is enabled
+	controlURL := c.upnp.ControlURL
+	if controlURL == "" {
+		var err error
+		if controlURL, err = getUPnPControlURL(ctx); err != nil {
+			return "", "", err
+		}
+	}
+
+	var response struct {
+		Address string `xml:"NewExternalIPAddress"`
+	}
+	client := &http.Client{Timeout: upnpSOAPTimeout}
+	if err := upnpSOAPCall(ctx, client, controlURL, "GetExternalIPAddress", "", &response); err != nil {
+		return "", "", err
+	}
+	if net.ParseIP(response.Address).To4() == nil {
+		return "", "", fmt.Errorf("igd returned invalid external address %q", response.Address)
+	}
+
+	return response.Address, "upnp", nil
+}
+
+// get the ipv4 addresses of the logical interfaces holding an ipv4 default route
+func getWANIPv4Addresses(ctx context.Context) (map[string]bool, error) {
+	interfaces, err := getUbusNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[string]bool)
+	for _, iface := range interfaces {
+		for _, route := range iface.Routes {
+			if route.Target != "0.0.0.0" || route.Mask != 0 {
+				continue
+			}
+			for _, address := range iface.IPv4Addresses {
+				addresses[address.Address] = true
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// get the ipv4 address a stun server sees our requests coming from
+func getSTUNMappedAddress(ctx context.Context, server string) (string, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "3478")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp4", server)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = conn.Close() }()
+
+	// header: type, length, magic cookie, 96-bit transaction id
+	request := make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	if _, err := rand.Read(request[8:20]); err != nil {
+		return "", err
+	}
+
+	response := make([]byte, 1500)
+	for attempt := 0; attempt < stunAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if _, err := conn.Write(request); err != nil {
+			return "", err
+		}
+		deadline := time.Now().Add(stunRetryInterval)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return "", err
+		}
+
+		n, err := conn.Read(response)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return "", err
+		}
+		if n < 20 || binary.BigEndian.Uint16(response[0:2]) != stunBindingResponse ||
+			!bytes.Equal(response[4:20], request[4:20]) {
+			continue
+		}
+		return parseSTUNMappedAddress(response[20:n])
+	}
+
+	return "", fmt.Errorf("no stun response after %d attempts", stunAttempts)
+}
+
+// get the ipv4 address from the (xor-)mapped-address attribute of a binding response
+func parseSTUNMappedAddress(attributes []byte) (string, error) {
+	var mapped net.IP
+	for len(attributes) >= 4 {
+		attrType := binary.BigEndian.Uint16(attributes[0:2])
+		length := int(binary.BigEndian.Uint16(attributes[2:4]))
+		if len(attributes) < 4+length {
+			break
+		}
+		value := attributes[4 : 4+length]
+
+		// value: reserved, family (1 = ipv4), port, address
+		if length == 8 && value[1] == 0x01 {
+			switch attrType {
+			case stunAttrXorMappedAddress:
+				address := binary.BigEndian.Uint32(value[4:8]) ^ stunMagicCookie
+				return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address)).String(), nil
+			case stunAttrMappedAddress:
+				mapped = net.IP(value[4:8])
+			}
+		}
+
+		// attributes are padded to a multiple of 4 bytes
+		padded := (length + 3) &^ 3
+		if len(attributes) < 4+padded {
+			break
+		}
+		attributes = attributes[4+padded:]
+	}
+
+	if mapped == nil {
+		return "", errors.New("stun response without mapped address")
+	}
+	return mapped.String(), nil
+}
+
+// load external ip configuration from environment variables
+func loadExternalIPConfig() *ExternalIPConfig {
+	return &ExternalIPConfig{
+		// external_ip_stun_server: stun server used to discover the external address, e.g. stun.l.google.com:19302
+		STUNServer: os.Getenv("EXTERNAL_IP_STUN_SERVER"),
+	}
+}
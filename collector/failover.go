@@ -0,0 +1,308 @@
+//go:build !noprobes
+
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// egress label of probes that follow the routing policy (mwan3, pbr, default route)
+const policyEgress = "policy"
+
+// dual wan failover probe collector
+type FailoverCollector struct {
+	sent       *prometheus.Desc
+	received   *prometheus.Desc
+	duplicates *prometheus.Desc
+	outOfOrder *prometheus.Desc
+	events     *prometheus.Desc
+	eventLost  *prometheus.Desc
+	egressInfo *prometheus.Desc
+	config     *FailoverConfig
+	mode       string
+
+	mu         sync.Mutex
+	probes     map[string]*failoverProbeStats
+	egress     string
+	eventCount float64
+	lostTotal  float64
+	// all counters start with the collector
+	created time.Time
+	window  *failoverWindow
+}
+
+// probe counters of one egress
+type failoverProbeStats struct {
+	sent       float64
+	received   float64
+	duplicates float64
+	outOfOrder float64
+	highestSeq int
+	seen       bool
+}
+
+// policy egress counters at the start of a failover event
+type failoverWindow struct {
+	end      time.Time
+	sent     float64
+	received float64
+}
+
+// create a new failover collector, pinging in the socket mode of the ping configuration
+func NewFailoverCollector(config *FailoverConfig, ping *PingConfig) *FailoverCollector {
+	labels := []string{"egress"}
+
+	c := &FailoverCollector{
+		sent: prometheus.NewDesc(
+			"openwrt_failover_probe_sent_total",
+			"total number of failover probes sent per egress",
+			labels, nil,
+		),
+		received: prometheus.NewDesc(
+			"openwrt_failover_probe_received_total",
+			"total number of failover probe replies received per egress",
+			labels, nil,
+		),
+		duplicates: prometheus.NewDesc(
+			"openwrt_failover_probe_duplicates_total",
+			"total number of duplicate failover probe replies per egress",
+			labels, nil,
+		),
+		outOfOrder: prometheus.NewDesc(
+			"openwrt_failover_probe_out_of_order_total",
+			"total number of failover probe replies received out of order per egress",
+			labels, nil,
+		),
+		events: prometheus.NewDesc(
+			"openwrt_failover_events_total",
+			"total number of times the policy egress interface towards the probe target changed",
+			nil, nil,
+		),
+		eventLost: prometheus.NewDesc(
+			"openwrt_failover_event_lost_packets_total",
+			"total number of policy egress probes lost within the window following failover events",
+			nil, nil,
+		),
+		egressInfo: prometheus.NewDesc(
+			"openwrt_failover_egress_info",
+			"interface currently used by the routing policy towards the probe target",
+			[]string{"interface"}, nil,
+		),
+		config:  config,
+		mode:    ping.Mode,
+		probes:  make(map[string]*failoverProbeStats),
+		created: time.Now(),
+	}
+
+	if c.config.Target != "" {
+		c.probes[policyEgress] = &failoverProbeStats{}
+		goBackground(func(ctx context.Context) { c.runProbe(ctx, policyEgress, "") })
+		for _, iface := range c.config.Interfaces {
+			c.probes[iface] = &failoverProbeStats{}
+			goBackground(func(ctx context.Context) { c.runProbe(ctx, iface, iface) })
+		}
+		goBackground(c.watchEgress)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *FailoverCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sent
+	ch <- c.received
+	ch <- c.duplicates
+	ch <- c.outOfOrder
+	ch <- c.events
+	ch <- c.eventLost
+	ch <- c.egressInfo
+}
+
+// collect implements prometheus.Collector
+func (c *FailoverCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.config.Target == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for egress, stats := range c.probes {
+		ch <- newCreatedCounter(c.sent, stats.sent, c.created, egress)
+		ch <- newCreatedCounter(c.received, stats.received, c.created, egress)
+		ch <- newCreatedCounter(c.duplicates, stats.duplicates, c.created, egress)
+		ch <- newCreatedCounter(c.outOfOrder, stats.outOfOrder, c.created, egress)
+	}
+
+	ch <- newCreatedCounter(c.events, c.eventCount, c.created)
+	ch <- newCreatedCounter(c.eventLost, c.lostTotal, c.created)
+
+	if c.egress != "" {
+		ch <- prometheus.MustNewConstMetric(c.egressInfo, prometheus.GaugeValue, 1, c.egress)
+	}
+}
+
+// continuously probe the target through an egress, restarting the pinger if it fails
+func (c *FailoverCollector) runProbe(ctx context.Context, egress string, iface string) {
+	for ctx.Err() == nil {
+		if err := c.probe(ctx, egress, iface); err != nil && ctx.Err() == nil {
+			slog.Error("error running failover probe", "egress", egress, "err", err)
+			sleepContext(ctx, 10*time.Second)
+		}
+	}
+}
+
+// send probes for an hour or until the pinger fails, tracking loss, duplicates and reordering
+func (c *FailoverCollector) probe(ctx context.Context, egress string, iface string) error {
+	pinger, err := probing.NewPinger(c.config.Target)
+	if err != nil {
+		return err
+	}
+
+	pinger.Interval = c.config.Interval
+	pinger.InterfaceName = iface
+	pinger.RecordRtts = false
+	pinger.RecordTTLs = false
+
+	// restart hourly, releasing the in-flight state the pinger keeps for lost probes
+	pinger.Timeout = time.Hour
+
+	// sequence numbers restart with every pinger
+	c.mu.Lock()
+	c.probes[egress].seen = false
+	c.mu.Unlock()
+
+	pinger.OnSend = func(*probing.Packet) {
+		c.mu.Lock()
+		c.probes[egress].sent++
+		c.mu.Unlock()
+	}
+	pinger.OnRecv = func(pkt *probing.Packet) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		stats := c.probes[egress]
+		stats.received++
+
+		// compare sequence numbers modulo 2^16 to survive wraparound
+		if stats.seen && int16(uint16(pkt.Seq)-uint16(stats.highestSeq)) < 0 {
+			stats.outOfOrder++
+			return
+		}
+		stats.highestSeq = pkt.Seq
+		stats.seen = true
+	}
+	pinger.OnDuplicateRecv = func(*probing.Packet) {
+		c.mu.Lock()
+		c.probes[egress].duplicates++
+		c.mu.Unlock()
+	}
+
+	return runPinger(ctx, pinger, c.mode)
+}
+
+// poll the egress interface chosen by the routing policy and track failover events
+func (c *FailoverCollector) watchEgress(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// a failing lookup is logged once until it succeeds again, not every second
+	failing := false
+	for running := true; running; running = nextTick(ctx, ticker) {
+		egress, err := getRouteEgress(ctx, c.config.Target)
+		switch {
+		case err == nil:
+			failing = false
+		case ctx.Err() != nil:
+		case failing || isExpectedError(err):
+			slog.Debug("failed to look up failover egress", "err", err)
+		default:
+			failing = true
+			slog.Warn("failed to look up failover egress", "err", err)
+		}
+
+		now := time.Now()
+		c.mu.Lock()
+		policy := c.probes[policyEgress]
+
+		// account the probes lost during a finished failover window
+		if c.window != nil && now.After(c.window.end) {
+			if lost := (policy.sent - c.window.sent) - (policy.received - c.window.received); lost > 0 {
+				c.lostTotal += lost
+			}
+			c.window = nil
+		}
+
+		if egress != "" && egress != c.egress {
+			if c.egress != "" {
+				c.eventCount++
+				if c.window == nil {
+					c.window = &failoverWindow{sent: policy.sent, received: policy.received}
+				}
+				c.window.end = now.Add(c.config.Window)
+			}
+			c.egress = egress
+		}
+		c.mu.Unlock()
+	}
+}
+
+// get the interface the kernel routes a destination through
+func getRouteEgress(ctx context.Context, destination string) (string, error) {
+	output, err := runCommand(ctx, "ip", "route", "get", destination)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(output))
+	for i, field := range fields {
+		if field == "dev" && i+1 < len(fields) {
+			return fields[i+1], nil
+		}
+	}
+	return "", nil
+}
+
+// load failover probe configuration from environment variables
+func loadFailoverConfig() *FailoverConfig {
+	config := &FailoverConfig{
+		Interval: 200 * time.Millisecond,
+		Window:   time.Minute,
+	}
+
+	// failover_probe_target: probe destination, enables failover probing
+	config.Target = os.Getenv("FAILOVER_PROBE_TARGET")
+
+	// failover_probe_interfaces: comma-separated list of wan devices probed directly
+	if interfacesEnv := os.Getenv("FAILOVER_PROBE_INTERFACES"); interfacesEnv != "" {
+		for _, iface := range strings.Split(interfacesEnv, ",") {
+			if iface = strings.TrimSpace(iface); iface != "" && iface != policyEgress {
+				config.Interfaces = append(config.Interfaces, iface)
+			}
+		}
+	}
+
+	// failover_probe_interval: interval between probes
+	if intervalEnv := os.Getenv("FAILOVER_PROBE_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.Interval = interval
+		}
+	}
+
+	// failover_window: time after a failover event during which lost probes are attributed to it
+	if windowEnv := os.Getenv("FAILOVER_WINDOW"); windowEnv != "" {
+		if window, err := time.ParseDuration(windowEnv); err == nil && window > 0 {
+			config.Window = window
+		}
+	}
+
+	return config
+}
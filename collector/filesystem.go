@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pseudo filesystems without meaningful usage
+var ignoredFilesystemTypes = map[string]bool{
+	"proc":        true,
+	"sysfs":       true,
+	"devpts":      true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"debugfs":     true,
+	"tracefs":     true,
+	"securityfs":  true,
+	"pstore":      true,
+	"bpf":         true,
+	"mqueue":      true,
+	"configfs":    true,
+	"fusectl":     true,
+	"hugetlbfs":   true,
+	"binfmt_misc": true,
+	"autofs":      true,
+	"nsfs":        true,
+}
+
+// filesystem usage collector
+type FilesystemCollector struct {
+	size      *prometheus.Desc
+	used      *prometheus.Desc
+	available *prometheus.Desc
+}
+
+// create a new filesystem collector
+func NewFilesystemCollector() *FilesystemCollector {
+	labels := []string{"device", "mountpoint", "fstype"}
+
+	return &FilesystemCollector{
+		size: prometheus.NewDesc(
+			"openwrt_filesystem_size_bytes",
+			"filesystem size in bytes",
+			labels, nil,
+		),
+		used: prometheus.NewDesc(
+			"openwrt_filesystem_used_bytes",
+			"filesystem used space in bytes",
+			labels, nil,
+		),
+		available: prometheus.NewDesc(
+			"openwrt_filesystem_available_bytes",
+			"filesystem space available to unprivileged users in bytes",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *FilesystemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.used
+	ch <- c.available
+}
+
+// collect implements prometheus.Collector
+func (c *FilesystemCollector) Collect(ch chan<- prometheus.Metric) {
+	filesystems, err := getFilesystems()
+	if err != nil {
+		logCollectError("filesystem", err)
+		return
+	}
+
+	for _, fs := range filesystems {
+		ch <- prometheus.MustNewConstMetric(
+			c.size,
+			prometheus.GaugeValue,
+			float64(fs.Size),
+			fs.Device, fs.MountPoint, fs.FSType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.used,
+			prometheus.GaugeValue,
+			float64(fs.Used),
+			fs.Device, fs.MountPoint, fs.FSType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.available,
+			prometheus.GaugeValue,
+			float64(fs.Available),
+			fs.Device, fs.MountPoint, fs.FSType,
+		)
+	}
+}
+
+// filesystem usage information
+type Filesystem struct {
+	Device     string
+	MountPoint string
+	FSType     string
+	Size       uint64
+	Used       uint64
+	Available  uint64
+}
+
+// get mounted filesystems from /proc/mounts and their usage via statfs
+func getFilesystems() ([]Filesystem, error) {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var filesystems []Filesystem
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		// format: <device> <mountpoint> <fstype> <options> <dump> <pass>
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+
+		device := fields[0]
+		mountPoint := unescapeMountPath(fields[1])
+		fsType := fields[2]
+
+		if ignoredFilesystemTypes[fsType] || seen[mountPoint] {
+			continue
+		}
+		seen[mountPoint] = true
+
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(mountPoint, &stat); err != nil {
+			slog.Error("error getting filesystem stats", "mount_point", mountPoint, "err", err)
+			continue
+		}
+
+		blockSize := uint64(stat.Bsize)
+		size := uint64(stat.Blocks) * blockSize
+
+		// skip empty filesystems such as /dev on some targets
+		if size == 0 {
+			continue
+		}
+
+		filesystems = append(filesystems, Filesystem{
+			Device:     device,
+			MountPoint: mountPoint,
+			FSType:     fsType,
+			Size:       size,
+			Used:       (uint64(stat.Blocks) - uint64(stat.Bfree)) * blockSize,
+			Available:  uint64(stat.Bavail) * blockSize,
+		})
+	}
+
+	return filesystems, scanner.Err()
+}
+
+// unescape octal sequences used in /proc/mounts (e.g. "\040" for space)
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) && isOctal(path[i+1]) && isOctal(path[i+2]) && isOctal(path[i+3]) {
+			b.WriteByte((path[i+1]-'0')<<6 | (path[i+2]-'0')<<3 | (path[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+
+	return b.String()
+}
+
+// check whether a byte is an octal digit
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// default path of the fingerprint file written by the dhcp hotplug script
+const defaultFingerprintFile = "/tmp/openwrt-exporter-fingerprints"
+
+// dhcp fingerprint of a client
+type DHCPFingerprint struct {
+	VendorClass      string
+	RequestedOptions string
+}
+
+// device type classification rule, matched against vendor class prefix or requested options
+type deviceTypeRule struct {
+	vendorPrefix string
+	options      string
+	deviceType   string
+}
+
+// coarse device type rules, evaluated in order
+var deviceTypeRules = []deviceTypeRule{
+	{vendorPrefix: "msft 5.0 xbox", deviceType: "console"},
+	{vendorPrefix: "nintendo", deviceType: "console"},
+	{vendorPrefix: "playstation", deviceType: "console"},
+	{vendorPrefix: "android-dhcp", deviceType: "phone"},
+	{vendorPrefix: "msft", deviceType: "pc"},
+	{vendorPrefix: "dhcpcd", deviceType: "pc"},
+	{vendorPrefix: "udhcp", deviceType: "iot"},
+	{vendorPrefix: "espressif", deviceType: "iot"},
+	{vendorPrefix: "esp32", deviceType: "iot"},
+	{vendorPrefix: "esp8266", deviceType: "iot"},
+	{vendorPrefix: "tizen", deviceType: "tv"},
+	{vendorPrefix: "webos", deviceType: "tv"},
+	// apple devices do not send a vendor class, so match on the parameter request list
+	{options: "1,121,3,6,15,119,252", deviceType: "phone"},
+	{options: "1,121,3,6,15,108,114,119,252,95,44,46", deviceType: "pc"},
+	{options: "1,121,3,6,15,114,119,252,95,44,46", deviceType: "pc"},
+	{options: "1,3,6,15,31,33,43,44,46,47,119,121,249,252", deviceType: "pc"},
+	{options: "1,3,6,12,15,28,42", deviceType: "iot"},
+	{options: "1,3,28,6", deviceType: "iot"},
+}
+
+// load dhcp fingerprints keyed by lowercase mac from the hotplug fingerprint file
+// format: <mac>\t<vendor_class>\t<requested_options>
+func loadDHCPFingerprints(path string) map[string]DHCPFingerprint {
+	fingerprints := make(map[string]DHCPFingerprint)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fingerprints
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		fingerprints[strings.ToLower(fields[0])] = DHCPFingerprint{
+			VendorClass:      fields[1],
+			RequestedOptions: fields[2],
+		}
+	}
+
+	return fingerprints
+}
+
+// classify a dhcp fingerprint into a coarse device type
+func classifyDevice(fingerprint DHCPFingerprint) string {
+	vendor := strings.ToLower(fingerprint.VendorClass)
+	options := strings.ReplaceAll(fingerprint.RequestedOptions, " ", "")
+
+	for _, rule := range deviceTypeRules {
+		if rule.vendorPrefix != "" && strings.HasPrefix(vendor, rule.vendorPrefix) {
+			return rule.deviceType
+		}
+		if rule.options != "" && options == rule.options {
+			return rule.deviceType
+		}
+	}
+
+	return "unknown"
+}
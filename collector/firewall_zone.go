@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// matches fw4 zone verdict chains, e.g. accept_to_wan, drop_from_lan
+var zoneVerdictChainRegexp = regexp.MustCompile(`^(accept|drop|reject)_(to|from)_(.+)$`)
+
+// firewall zone traffic collector
+type FirewallZoneCollector struct {
+	packets *prometheus.Desc
+	bytes   *prometheus.Desc
+}
+
+// create a new firewall zone collector
+func NewFirewallZoneCollector() *FirewallZoneCollector {
+	labels := []string{"zone", "direction", "verdict"}
+
+	return &FirewallZoneCollector{
+		packets: prometheus.NewDesc(
+			"openwrt_firewall_zone_packets_total",
+			"total number of packets handled by a firewall zone verdict (direction is to or from the zone)",
+			labels, nil,
+		),
+		bytes: prometheus.NewDesc(
+			"openwrt_firewall_zone_bytes_total",
+			"total number of bytes handled by a firewall zone verdict (direction is to or from the zone)",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *FirewallZoneCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.packets
+	ch <- c.bytes
+}
+
+// collect implements prometheus.Collector
+func (c *FirewallZoneCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *FirewallZoneCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	zones, err := getFirewallZoneTraffic(ctx)
+	if err != nil {
+		logCollectError("firewall zone", err)
+		return
+	}
+
+	for _, zone := range zones {
+		ch <- prometheus.MustNewConstMetric(
+			c.packets,
+			prometheus.CounterValue,
+			zone.Packets,
+			zone.Zone, zone.Direction, zone.Verdict,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.bytes,
+			prometheus.CounterValue,
+			zone.Bytes,
+			zone.Zone, zone.Direction, zone.Verdict,
+		)
+	}
+}
+
+// firewall zone traffic counters
+type FirewallZoneTraffic struct {
+	Zone      string
+	Direction string
+	Verdict   string
+	Packets   float64
+	Bytes     float64
+}
+
+// sum rule counters of fw4 zone verdict chains (accept/drop/reject_to/from_<zone>)
+func getFirewallZoneTraffic(ctx context.Context) ([]FirewallZoneTraffic, error) {
+	rules, err := getNftRules(ctx, "inet", "fw4")
+	if err != nil {
+		return nil, err
+	}
+
+	traffic := make(map[string]*FirewallZoneTraffic)
+	var order []string
+
+	for _, rule := range rules {
+		if rule.Counter == nil {
+			continue
+		}
+
+		match := zoneVerdictChainRegexp.FindStringSubmatch(rule.Chain)
+		if match == nil {
+			continue
+		}
+
+		zone, ok := traffic[rule.Chain]
+		if !ok {
+			zone = &FirewallZoneTraffic{
+				Zone:      match[3],
+				Direction: match[2],
+				Verdict:   match[1],
+			}
+			traffic[rule.Chain] = zone
+			order = append(order, rule.Chain)
+		}
+		zone.Packets += rule.Counter.Packets
+		zone.Bytes += rule.Counter.Bytes
+	}
+
+	result := make([]FirewallZoneTraffic, 0, len(order))
+	for _, chain := range order {
+		result = append(result, *traffic[chain])
+	}
+
+	return result, nil
+}
@@ -0,0 +1,236 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// mtd and ubi flash health collector
+type FlashCollector struct {
+	mtdSize             *prometheus.Desc
+	mtdEraseSize        *prometheus.Desc
+	mtdEraseBlocks      *prometheus.Desc
+	mtdBadBlocks        *prometheus.Desc
+	mtdECCFailures      *prometheus.Desc
+	mtdCorrectedBits    *prometheus.Desc
+	ubiEraseBlocks      *prometheus.Desc
+	ubiAvailEraseBlocks *prometheus.Desc
+	ubiBadEraseBlocks   *prometheus.Desc
+	ubiMaxEraseCount    *prometheus.Desc
+	ubiVolumeSize       *prometheus.Desc
+}
+
+// create a new flash collector
+func NewFlashCollector() *FlashCollector {
+	mtdLabels := []string{"device", "name"}
+	ubiLabels := []string{"device", "mtd"}
+
+	return &FlashCollector{
+		mtdSize: prometheus.NewDesc(
+			"openwrt_mtd_size_bytes",
+			"mtd partition size in bytes",
+			mtdLabels, nil,
+		),
+		mtdEraseSize: prometheus.NewDesc(
+			"openwrt_mtd_erase_block_size_bytes",
+			"mtd partition erase block size in bytes",
+			mtdLabels, nil,
+		),
+		mtdEraseBlocks: prometheus.NewDesc(
+			"openwrt_mtd_erase_blocks",
+			"number of erase blocks in the mtd partition",
+			mtdLabels, nil,
+		),
+		mtdBadBlocks: prometheus.NewDesc(
+			"openwrt_mtd_bad_blocks",
+			"number of bad blocks in the mtd partition",
+			mtdLabels, nil,
+		),
+		mtdECCFailures: prometheus.NewDesc(
+			"openwrt_mtd_ecc_failures_total",
+			"total number of uncorrectable ecc errors on the mtd partition",
+			mtdLabels, nil,
+		),
+		mtdCorrectedBits: prometheus.NewDesc(
+			"openwrt_mtd_corrected_bits_total",
+			"total number of bitflips corrected by ecc on the mtd partition",
+			mtdLabels, nil,
+		),
+		ubiEraseBlocks: prometheus.NewDesc(
+			"openwrt_ubi_erase_blocks",
+			"total number of physical erase blocks in the ubi device",
+			ubiLabels, nil,
+		),
+		ubiAvailEraseBlocks: prometheus.NewDesc(
+			"openwrt_ubi_available_erase_blocks",
+			"number of erase blocks available for new ubi volumes",
+			ubiLabels, nil,
+		),
+		ubiBadEraseBlocks: prometheus.NewDesc(
+			"openwrt_ubi_bad_erase_blocks",
+			"number of bad physical erase blocks in the ubi device",
+			ubiLabels, nil,
+		),
+		ubiMaxEraseCount: prometheus.NewDesc(
+			"openwrt_ubi_max_erase_count",
+			"maximum erase counter value of any physical erase block in the ubi device",
+			ubiLabels, nil,
+		),
+		ubiVolumeSize: prometheus.NewDesc(
+			"openwrt_ubi_volume_size_bytes",
+			"reserved size of the ubi volume in bytes",
+			[]string{"device", "volume", "name"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *FlashCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.mtdSize
+	ch <- c.mtdEraseSize
+	ch <- c.mtdEraseBlocks
+	ch <- c.mtdBadBlocks
+	ch <- c.mtdECCFailures
+	ch <- c.mtdCorrectedBits
+	ch <- c.ubiEraseBlocks
+	ch <- c.ubiAvailEraseBlocks
+	ch <- c.ubiBadEraseBlocks
+	ch <- c.ubiMaxEraseCount
+	ch <- c.ubiVolumeSize
+}
+
+// collect implements prometheus.Collector
+func (c *FlashCollector) Collect(ch chan<- prometheus.Metric) {
+	partitions, err := getMTDPartitions()
+	if err != nil {
+		logCollectError("mtd", err)
+	}
+
+	for _, p := range partitions {
+		ch <- prometheus.MustNewConstMetric(c.mtdSize, prometheus.GaugeValue, float64(p.Size), p.Device, p.Name)
+		ch <- prometheus.MustNewConstMetric(c.mtdEraseSize, prometheus.GaugeValue, float64(p.EraseSize), p.Device, p.Name)
+		if p.EraseSize > 0 {
+			ch <- prometheus.MustNewConstMetric(c.mtdEraseBlocks, prometheus.GaugeValue, float64(p.Size/p.EraseSize), p.Device, p.Name)
+		}
+
+		// bad block and ecc statistics are only available on nand flash
+		sysfs := filepath.Join("/sys/class/mtd", p.Device)
+		if value, ok := readSysfsFloat(filepath.Join(sysfs, "bad_blocks")); ok {
+			ch <- prometheus.MustNewConstMetric(c.mtdBadBlocks, prometheus.GaugeValue, value, p.Device, p.Name)
+		}
+		if value, ok := readSysfsFloat(filepath.Join(sysfs, "ecc_failures")); ok {
+			ch <- prometheus.MustNewConstMetric(c.mtdECCFailures, prometheus.CounterValue, value, p.Device, p.Name)
+		}
+		if value, ok := readSysfsFloat(filepath.Join(sysfs, "corrected_bits")); ok {
+			ch <- prometheus.MustNewConstMetric(c.mtdCorrectedBits, prometheus.CounterValue, value, p.Device, p.Name)
+		}
+	}
+
+	c.collectUBI(ch)
+}
+
+// collect ubi device and volume metrics from /sys/class/ubi
+func (c *FlashCollector) collectUBI(ch chan<- prometheus.Metric) {
+	devices, err := filepath.Glob("/sys/class/ubi/ubi[0-9]*")
+	if err != nil {
+		return
+	}
+
+	for _, path := range devices {
+		name := filepath.Base(path)
+
+		// volumes are named ubi<dev>_<vol>
+		if strings.Contains(name, "_") {
+			volumeName := readSysfsString(filepath.Join(path, "name"))
+			reserved, ok1 := readSysfsFloat(filepath.Join(path, "reserved_ebs"))
+			usable, ok2 := readSysfsFloat(filepath.Join(path, "usable_eb_size"))
+			if ok1 && ok2 {
+				device, _, _ := strings.Cut(name, "_")
+				ch <- prometheus.MustNewConstMetric(c.ubiVolumeSize, prometheus.GaugeValue, reserved*usable, device, name, volumeName)
+			}
+			continue
+		}
+
+		mtd := readSysfsString(filepath.Join(path, "mtd_num"))
+		if mtd != "" {
+			mtd = "mtd" + mtd
+		}
+
+		for _, m := range []struct {
+			desc *prometheus.Desc
+			file string
+		}{
+			{c.ubiEraseBlocks, "total_eraseblocks"},
+			{c.ubiAvailEraseBlocks, "avail_eraseblocks"},
+			{c.ubiBadEraseBlocks, "bad_peb_count"},
+			{c.ubiMaxEraseCount, "max_ec"},
+		} {
+			if value, ok := readSysfsFloat(filepath.Join(path, m.file)); ok {
+				ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, value, name, mtd)
+			}
+		}
+	}
+}
+
+// mtd partition information
+type MTDPartition struct {
+	Device    string
+	Name      string
+	Size      uint64
+	EraseSize uint64
+}
+
+// get mtd partitions from /proc/mtd
+func getMTDPartitions() ([]MTDPartition, error) {
+	file, err := os.Open("/proc/mtd")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var partitions []MTDPartition
+	scanner := bufio.NewScanner(file)
+
+	// skip header line
+	scanner.Scan()
+
+	for scanner.Scan() {
+		// format: mtd0: 00040000 00010000 "u-boot"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		size, err := strconv.ParseUint(fields[1], 16, 64)
+		if err != nil {
+			continue
+		}
+		eraseSize, err := strconv.ParseUint(fields[2], 16, 64)
+		if err != nil {
+			continue
+		}
+
+		partitions = append(partitions, MTDPartition{
+			Device:    strings.TrimSuffix(fields[0], ":"),
+			Name:      strings.Trim(strings.Join(fields[3:], " "), `"`),
+			Size:      size,
+			EraseSize: eraseSize,
+		})
+	}
+
+	return partitions, scanner.Err()
+}
+
+// read a numeric sysfs attribute
+func readSysfsFloat(path string) (float64, bool) {
+	value, err := strconv.ParseFloat(readSysfsString(path), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
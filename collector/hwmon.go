@@ -0,0 +1,160 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// matches hwmon sensor input files, e.g. temp1_input, fan2_input, in0_input, curr1_input
+var hwmonInputRegexp = regexp.MustCompile(`^(temp|fan|in|curr)(\d+)_input$`)
+
+// hwmon sensors collector
+type HwmonCollector struct {
+	temperature *prometheus.Desc
+	fan         *prometheus.Desc
+	voltage     *prometheus.Desc
+	current     *prometheus.Desc
+}
+
+// create a new hwmon collector
+func NewHwmonCollector() *HwmonCollector {
+	labels := []string{"chip", "device", "sensor", "label"}
+
+	return &HwmonCollector{
+		temperature: prometheus.NewDesc(
+			"openwrt_hwmon_temperature_celsius",
+			"hwmon temperature sensor reading in degrees celsius",
+			labels, nil,
+		),
+		fan: prometheus.NewDesc(
+			"openwrt_hwmon_fan_rpm",
+			"hwmon fan speed in revolutions per minute",
+			labels, nil,
+		),
+		voltage: prometheus.NewDesc(
+			"openwrt_hwmon_voltage_volts",
+			"hwmon voltage sensor reading in volts",
+			labels, nil,
+		),
+		current: prometheus.NewDesc(
+			"openwrt_hwmon_current_amperes",
+			"hwmon current sensor reading in amperes",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *HwmonCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.temperature
+	ch <- c.fan
+	ch <- c.voltage
+	ch <- c.current
+}
+
+// collect implements prometheus.Collector
+func (c *HwmonCollector) Collect(ch chan<- prometheus.Metric) {
+	sensors, err := getHwmonSensors()
+	if err != nil {
+		logCollectError("hwmon", err)
+		return
+	}
+
+	for _, sensor := range sensors {
+		var desc *prometheus.Desc
+		value := sensor.Value
+
+		// convert sysfs units (millidegrees, millivolts, milliamperes) to base units
+		switch sensor.Type {
+		case "temp":
+			desc = c.temperature
+			value /= 1000.0
+		case "fan":
+			desc = c.fan
+		case "in":
+			desc = c.voltage
+			value /= 1000.0
+		case "curr":
+			desc = c.current
+			value /= 1000.0
+		default:
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			value,
+			sensor.Chip,
+			sensor.Device,
+			sensor.Sensor,
+			sensor.Label,
+		)
+	}
+}
+
+// hwmon sensor reading in raw sysfs units
+type HwmonSensor struct {
+	Chip   string
+	Device string
+	Sensor string
+	Type   string
+	Label  string
+	Value  float64
+}
+
+// get hwmon sensor readings from /sys/class/hwmon
+func getHwmonSensors() ([]HwmonSensor, error) {
+	devices, err := filepath.Glob("/sys/class/hwmon/hwmon*")
+	if err != nil {
+		return nil, err
+	}
+
+	var sensors []HwmonSensor
+	for _, device := range devices {
+		chip := readSysfsString(filepath.Join(device, "name"))
+
+		entries, err := os.ReadDir(device)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			match := hwmonInputRegexp.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(readSysfsString(filepath.Join(device, entry.Name())), 64)
+			if err != nil {
+				continue
+			}
+
+			sensor := match[1] + match[2]
+			sensors = append(sensors, HwmonSensor{
+				Chip:   chip,
+				Device: filepath.Base(device),
+				Sensor: sensor,
+				Type:   match[1],
+				Label:  readSysfsString(filepath.Join(device, sensor+"_label")),
+				Value:  value,
+			})
+		}
+	}
+
+	return sensors, nil
+}
+
+// read a trimmed sysfs attribute, returning an empty string on error
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -1,7 +1,7 @@
 package collector
 
 import (
-	"log"
+	"log/slog"
 	"net"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,7 +32,7 @@ func (c *InterfaceIPCollector) Describe(ch chan<- *prometheus.Desc) {
 func (c *InterfaceIPCollector) Collect(ch chan<- prometheus.Metric) {
 	ipInfos, err := getInterfaceIPAddresses()
 	if err != nil {
-		log.Printf("error collecting interface ip metrics: %v", err)
+		logCollectError("interface ip", err)
 		return
 	}
 
@@ -80,7 +80,7 @@ func getInterfaceIPAddresses() ([]InterfaceIPInfo, error) {
 
 		addrs, err := iface.Addrs()
 		if err != nil {
-			log.Printf("error getting addresses for interface %s: %v", iface.Name, err)
+			slog.Error("error getting interface addresses", "interface", iface.Name, "err", err)
 			continue
 		}
 
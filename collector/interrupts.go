@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// per-cpu interrupt and softirq counter collector
+type InterruptsCollector struct {
+	interrupts *prometheus.Desc
+	softirqs   *prometheus.Desc
+}
+
+// create a new interrupts collector
+func NewInterruptsCollector() *InterruptsCollector {
+	return &InterruptsCollector{
+		interrupts: prometheus.NewDesc(
+			"openwrt_interrupts_total",
+			"total number of interrupts handled per cpu",
+			[]string{"irq", "cpu", "type", "devices"}, nil,
+		),
+		softirqs: prometheus.NewDesc(
+			"openwrt_softirqs_total",
+			"total number of softirqs handled per cpu",
+			[]string{"type", "cpu"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *InterruptsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.interrupts
+	ch <- c.softirqs
+}
+
+// collect implements prometheus.Collector
+func (c *InterruptsCollector) Collect(ch chan<- prometheus.Metric) {
+	// counters start at boot, a missing boot time only drops the created timestamps
+	bootTime, _ := getBootTime()
+
+	interrupts, err := getCPUCounters("/proc/interrupts")
+	if err != nil {
+		logCollectError("interrupt", err)
+	}
+	for _, counter := range interrupts {
+		for cpu, count := range counter.Counts {
+			ch <- newCreatedCounter(
+				c.interrupts,
+				count,
+				bootTime,
+				counter.Name, strconv.Itoa(cpu), counter.Type, counter.Devices,
+			)
+		}
+	}
+
+	softirqs, err := getCPUCounters("/proc/softirqs")
+	if err != nil {
+		logCollectError("softirq", err)
+	}
+	for _, counter := range softirqs {
+		for cpu, count := range counter.Counts {
+			ch <- newCreatedCounter(
+				c.softirqs,
+				count,
+				bootTime,
+				counter.Name, strconv.Itoa(cpu),
+			)
+		}
+	}
+}
+
+// per-cpu counts of an interrupt or softirq
+type CPUCounter struct {
+	Name    string
+	Type    string
+	Devices string
+	Counts  []float64
+}
+
+// get per-cpu counters from /proc/interrupts or /proc/softirqs
+// format: header line "CPU0 CPU1 ...", then "<name>: <count per cpu> [<type> <devices>]"
+func getCPUCounters(path string) ([]CPUCounter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	cpus := len(strings.Fields(scanner.Text()))
+
+	var counters []CPUCounter
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// global counters like ERR and MIS have a single value and are skipped on multicore systems
+		if len(fields) < cpus+1 {
+			continue
+		}
+
+		counter := CPUCounter{Name: strings.TrimSuffix(fields[0], ":")}
+		for _, field := range fields[1 : cpus+1] {
+			count, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				break
+			}
+			counter.Counts = append(counter.Counts, count)
+		}
+		if len(counter.Counts) != cpus {
+			continue
+		}
+
+		// numbered irqs describe the interrupt controller first, architecture-specific ones only have a description
+		rest := fields[cpus+1:]
+		if _, err := strconv.Atoi(counter.Name); err == nil && len(rest) > 0 {
+			counter.Type = rest[0]
+			rest = rest[1:]
+		}
+		counter.Devices = strings.Join(rest, " ")
+
+		counters = append(counters, counter)
+	}
+
+	return counters, scanner.Err()
+}
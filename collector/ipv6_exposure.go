@@ -0,0 +1,216 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ipv6 firewall exposure audit collector
+type IPv6ExposureCollector struct {
+	exposedPorts *prometheus.Desc
+	exposedInfo  *prometheus.Desc
+	policyAccept *prometheus.Desc
+}
+
+// create a new ipv6 exposure collector
+func NewIPv6ExposureCollector() *IPv6ExposureCollector {
+	return &IPv6ExposureCollector{
+		exposedPorts: prometheus.NewDesc(
+			"openwrt_ipv6_exposed_ports",
+			"number of ports reachable over ipv6 from the wan zone through firewall accept rules",
+			nil, nil,
+		),
+		exposedInfo: prometheus.NewDesc(
+			"openwrt_ipv6_exposed_port_info",
+			"information about ports reachable over ipv6 from the wan zone",
+			[]string{"zone", "rule", "chain", "proto", "port", "dest_ip"}, nil,
+		),
+		policyAccept: prometheus.NewDesc(
+			"openwrt_ipv6_wan_policy_accept",
+			"whether the wan zone default policy accepts all traffic (1 = fully exposed)",
+			[]string{"zone", "chain"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *IPv6ExposureCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.exposedPorts
+	ch <- c.exposedInfo
+	ch <- c.policyAccept
+}
+
+// collect implements prometheus.Collector
+func (c *IPv6ExposureCollector) Collect(ch chan<- prometheus.Metric) {
+	audit, err := getIPv6Exposure()
+	if err != nil {
+		logCollectError("ipv6 exposure", err)
+		return
+	}
+
+	for _, policy := range audit.Policies {
+		ch <- prometheus.MustNewConstMetric(
+			c.policyAccept,
+			prometheus.GaugeValue,
+			boolToFloat64(policy.Accept),
+			policy.Zone,
+			policy.Chain,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.exposedPorts,
+		prometheus.GaugeValue,
+		float64(len(audit.Ports)),
+	)
+
+	for _, port := range audit.Ports {
+		ch <- prometheus.MustNewConstMetric(
+			c.exposedInfo,
+			prometheus.GaugeValue,
+			1,
+			port.Zone,
+			port.Rule,
+			port.Chain,
+			port.Proto,
+			port.Port,
+			privateIP(port.DestIP),
+		)
+	}
+}
+
+// ipv6 exposure audit result
+type IPv6Exposure struct {
+	Policies []WANPolicy
+	Ports    []ExposedPort
+}
+
+// default policy of a wan zone chain
+type WANPolicy struct {
+	Zone   string
+	Chain  string
+	Accept bool
+}
+
+// port reachable over ipv6 from a wan zone
+type ExposedPort struct {
+	Zone   string
+	Rule   string
+	Chain  string
+	Proto  string
+	Port   string
+	DestIP string
+}
+
+// evaluate fw4 zone policies and accept rules for ipv6 exposure from wan zones
+func getIPv6Exposure() (*IPv6Exposure, error) {
+	sections, err := loadUCIConfig("firewall")
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &IPv6Exposure{}
+
+	// wan zones are the ones named wan or doing masquerading
+	wanZones := make(map[string]bool)
+	for _, section := range sections {
+		if section.Type != "zone" {
+			continue
+		}
+
+		name := section.Option("name")
+		if name != "wan" && section.Option("masq") != "1" {
+			continue
+		}
+		if section.Option("family") == "ipv4" {
+			continue
+		}
+		wanZones[name] = true
+
+		for _, chain := range []string{"input", "forward"} {
+			audit.Policies = append(audit.Policies, WANPolicy{
+				Zone:   name,
+				Chain:  chain,
+				Accept: strings.EqualFold(section.Option(chain), "ACCEPT"),
+			})
+		}
+	}
+
+	index := 0
+	for _, section := range sections {
+		if section.Type != "rule" {
+			continue
+		}
+
+		// fw4 names unnamed rules after their section index
+		name := section.Option("name")
+		if name == "" {
+			name = fmt.Sprintf("@rule[%d]", index)
+		}
+		index++
+
+		if section.Option("enabled") == "0" || !wanZones[section.Option("src")] {
+			continue
+		}
+		if !strings.EqualFold(section.Option("target"), "ACCEPT") || section.Option("family") == "ipv4" {
+			continue
+		}
+
+		// rules without a destination zone apply to the router itself
+		chain := "input"
+		if section.Option("dest") != "" {
+			chain = "forward"
+		}
+
+		protos := section.List("proto")
+		if len(protos) == 0 {
+			protos = []string{"tcp", "udp"}
+		}
+
+		ports := section.List("dest_port")
+		if len(ports) == 0 {
+			ports = []string{"any"}
+		}
+
+		// fw4 infers the family from addresses, so ipv4-only destinations are not exposed over ipv6
+		destIPs := section.List("dest_ip")
+		if len(destIPs) > 0 && !containsIPv6Address(destIPs) {
+			continue
+		}
+		destIP := strings.Join(destIPs, " ")
+		if destIP == "" {
+			destIP = "any"
+		}
+
+		for _, proto := range protos {
+			// icmp rules do not expose ports
+			if strings.HasPrefix(proto, "icmp") {
+				continue
+			}
+			for _, port := range ports {
+				audit.Ports = append(audit.Ports, ExposedPort{
+					Zone:   section.Option("src"),
+					Rule:   name,
+					Chain:  chain,
+					Proto:  proto,
+					Port:   port,
+					DestIP: destIP,
+				})
+			}
+		}
+	}
+
+	return audit, nil
+}
+
+// check whether any address in a list is an ipv6 address
+func containsIPv6Address(addresses []string) bool {
+	for _, address := range addresses {
+		if strings.Contains(address, ":") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// directory crash dumps of previous boots are kept in when ramoops/pstore is configured
+const pstoreDir = "/sys/fs/pstore"
+
+// kernel messages counted as crash events, by event type
+var kernelCrashPatterns = []struct {
+	event   string
+	crash   bool
+	pattern *regexp.Regexp
+}{
+	{"oops", true, regexp.MustCompile(`\bOops\b|^BUG: |Unable to handle kernel`)},
+	{"panic", true, regexp.MustCompile(`^Kernel panic - not syncing`)},
+	{"oom_kill", false, regexp.MustCompile(`invoked oom-killer`)},
+	{"firmware_crash", true, regexp.MustCompile(`(?i)firmware (has )?crash|fw crash|firmware halted`)},
+}
+
+// kernel oops, oom and firmware crash collector
+type KernelCrashCollector struct {
+	events        *prometheus.Desc
+	lastCrashTime *prometheus.Desc
+	pstoreRecords *prometheus.Desc
+
+	mu        sync.Mutex
+	lastSeq   int64
+	counts    map[string]float64
+	lastCrash time.Time
+}
+
+// create a new kernel crash collector
+func NewKernelCrashCollector() *KernelCrashCollector {
+	c := &KernelCrashCollector{
+		events: prometheus.NewDesc(
+			"openwrt_kernel_crash_events_total",
+			"total number of kernel oops, panic, oom-killer and firmware crash messages since boot",
+			[]string{"event"}, nil,
+		),
+		lastCrashTime: prometheus.NewDesc(
+			"openwrt_kernel_last_crash_timestamp_seconds",
+			"time of the last kernel oops, panic or firmware crash, including crash dumps of previous boots",
+			nil, nil,
+		),
+		pstoreRecords: prometheus.NewDesc(
+			"openwrt_kernel_pstore_crash_records",
+			"number of kernel crash dumps from previous boots kept in pstore",
+			nil, nil,
+		),
+		lastSeq: -1,
+		counts:  make(map[string]float64),
+	}
+
+	for _, p := range kernelCrashPatterns {
+		c.counts[p.event] = 0
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *KernelCrashCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.events
+	ch <- c.lastCrashTime
+	ch <- c.pstoreRecords
+}
+
+// collect implements prometheus.Collector
+func (c *KernelCrashCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.readKernelLog(); err != nil {
+		logCollectError("kernel crash", err)
+		return
+	}
+
+	// the ring buffer is read from boot, a missing boot time only drops the created timestamps
+	bootTime, _ := getBootTime()
+	for event, count := range c.counts {
+		ch <- newCreatedCounter(
+			c.events,
+			count,
+			bootTime,
+			event,
+		)
+	}
+
+	lastCrash := c.lastCrash
+	records, lastDump, err := getPstoreCrashRecords()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("failed to read pstore", "path", pstoreDir, "err", err)
+	}
+	if err == nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.pstoreRecords,
+			prometheus.GaugeValue,
+			float64(records),
+		)
+		if lastDump.After(lastCrash) {
+			lastCrash = lastDump
+		}
+	}
+
+	if !lastCrash.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.lastCrashTime,
+			prometheus.GaugeValue,
+			float64(lastCrash.Unix()),
+		)
+	}
+}
+
+// count crash messages in the kernel ring buffer that were not seen by a previous scrape
+func (c *KernelCrashCollector) readKernelLog() error {
+	fd, err := syscall.Open("/dev/kmsg", syscall.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	bootTime, err := getBootTime()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8192)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err == syscall.EPIPE {
+			// record was overwritten while reading, continue with the next one
+			continue
+		}
+		if err == syscall.EAGAIN {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// format: <priority>,<sequence>,<microseconds since boot>,<flags>[,...];<message>
+		header, message, ok := strings.Cut(string(buf[:n]), ";")
+		if !ok {
+			continue
+		}
+		fields := strings.Split(header, ",")
+		if len(fields) < 3 {
+			continue
+		}
+		seq, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || seq <= c.lastSeq {
+			continue
+		}
+		c.lastSeq = seq
+		micros, _ := strconv.ParseInt(fields[2], 10, 64)
+
+		message, _, _ = strings.Cut(message, "\n")
+		for _, p := range kernelCrashPatterns {
+			if !p.pattern.MatchString(message) {
+				continue
+			}
+			c.counts[p.event]++
+			if p.crash {
+				c.lastCrash = bootTime.Add(time.Duration(micros) * time.Microsecond)
+			}
+		}
+	}
+}
+
+// get the number of kernel log dumps in pstore and the time of the newest one
+func getPstoreCrashRecords() (int, time.Time, error) {
+	entries, err := os.ReadDir(pstoreDir)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var records int
+	var newest time.Time
+	for _, entry := range entries {
+		// dmesg-<backend>-<id>, console and ftrace records are not crash dumps
+		if !strings.HasPrefix(entry.Name(), "dmesg-") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(pstoreDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		records++
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+
+	return records, newest, nil
+}
@@ -0,0 +1,404 @@
+//go:build !noprobes
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// carrier-grade nat range, not reachable from the internet
+var cgnatNetwork = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// router-side latency breakdown collector (gateway, first public hop, internet anchor), the segment targets
+// are probed in the background and scrapes export the most recent sample
+type LatencySegmentCollector struct {
+	probeMs   *prometheus.Desc
+	probeLoss *prometheus.Desc
+	segmentMs *prometheus.Desc
+	config    *LatencySegmentConfig
+	mode      string
+
+	mu      sync.Mutex
+	samples []latencyProbeSample
+
+	// only used by the sampler
+	publicHop    string
+	discoveredAt time.Time
+}
+
+// result of pinging one segment probe target
+type latencyProbeSample struct {
+	name   string
+	target string
+	stats  *probing.Statistics
+}
+
+// create a new latency segment collector, pinging in the socket mode of the ping configuration
+func NewLatencySegmentCollector(config *LatencySegmentConfig, ping *PingConfig) *LatencySegmentCollector {
+	c := &LatencySegmentCollector{
+		probeMs: prometheus.NewDesc(
+			"openwrt_latency_probe_ms",
+			"average round trip time to a latency segment probe target in milliseconds",
+			[]string{"probe", "target"}, nil,
+		),
+		probeLoss: prometheus.NewDesc(
+			"openwrt_latency_probe_loss_percent",
+			"packet loss to a latency segment probe target",
+			[]string{"probe", "target"}, nil,
+		),
+		segmentMs: prometheus.NewDesc(
+			"openwrt_latency_segment_ms",
+			"latency added by a network segment in milliseconds (local = router to gateway, isp_edge = gateway to first public hop, internet = first public hop to anchor)",
+			[]string{"segment"}, nil,
+		),
+		config: config,
+		mode:   ping.Mode,
+	}
+
+	if c.config.Anchor != "" {
+		goBackground(c.sample)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *LatencySegmentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.probeMs
+	ch <- c.probeLoss
+	ch <- c.segmentMs
+}
+
+// collect implements prometheus.Collector
+func (c *LatencySegmentCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	samples := c.samples
+	c.mu.Unlock()
+
+	if len(samples) == 0 {
+		return
+	}
+
+	rtts := make([]float64, len(samples))
+	for i, sample := range samples {
+		stats := sample.stats
+		if stats == nil {
+			rtts[i] = -1
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.probeLoss,
+			prometheus.GaugeValue,
+			stats.PacketLoss,
+			sample.name, sample.target,
+		)
+
+		if stats.PacketsRecv == 0 {
+			rtts[i] = -1
+			continue
+		}
+		rtts[i] = float64(stats.AvgRtt.Microseconds()) / 1000.0
+
+		ch <- prometheus.MustNewConstMetric(
+			c.probeMs,
+			prometheus.GaugeValue,
+			rtts[i],
+			sample.name, sample.target,
+		)
+	}
+
+	// segment latency is the delta between consecutive probes
+	segments := []struct {
+		name      string
+		near, far int
+	}{
+		{"local", -1, 0},
+		{"isp_edge", 0, 1},
+		{"internet", 1, 2},
+	}
+	for _, segment := range segments {
+		near := 0.0
+		if segment.near >= 0 {
+			near = rtts[segment.near]
+		}
+		far := rtts[segment.far]
+		if near < 0 || far < 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.segmentMs,
+			prometheus.GaugeValue,
+			far-near,
+			segment.name,
+		)
+	}
+}
+
+// probe the segment targets on every interval until ctx is cancelled
+func (c *LatencySegmentCollector) sample(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for running := true; running; running = nextTick(ctx, ticker) {
+		samples, err := c.probeSegments(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logCollectError("latency segment", err)
+		}
+
+		// without a resolvable anchor the metrics are absent rather than stale
+		c.mu.Lock()
+		c.samples = samples
+		c.mu.Unlock()
+	}
+}
+
+// ping the gateway, the first public hop and the anchor concurrently
+func (c *LatencySegmentCollector) probeSegments(ctx context.Context) ([]latencyProbeSample, error) {
+	anchor, err := resolveIPv4(c.config.Anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	var gateway string
+	if gateways, err := getDefaultGateways(); err != nil {
+		slog.Warn("failed to read default gateways", "err", err)
+	} else if len(gateways) > 0 {
+		gateway = gateways[0]
+	}
+
+	samples := []latencyProbeSample{
+		{name: "gateway", target: gateway},
+		{name: "first_public_hop", target: c.getPublicHop(ctx, anchor)},
+		{name: "anchor", target: anchor},
+	}
+
+	var wg sync.WaitGroup
+	for i := range samples {
+		sample := &samples[i]
+		if sample.target == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, err := c.probe(ctx, sample.target)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("error probing latency segment", "segment", sample.name, "target", sample.target, "err", err)
+				}
+				return
+			}
+			sample.stats = stats
+		}()
+	}
+	wg.Wait()
+
+	return samples, nil
+}
+
+// get the first public hop towards the anchor, rediscovering it when stale
+func (c *LatencySegmentCollector) getPublicHop(ctx context.Context, anchor string) string {
+	if time.Since(c.discoveredAt) < c.config.DiscoveryInterval {
+		return c.publicHop
+	}
+
+	// time exceeded messages only arrive on a raw socket
+	if c.mode == pingModeUnprivileged {
+		return ""
+	}
+
+	hop, err := findFirstPublicHop(ctx, anchor, c.config.MaxHops, c.config.Timeout)
+	if ctx.Err() != nil {
+		return c.publicHop
+	}
+	switch {
+	case err != nil && errors.Is(err, os.ErrPermission) && c.mode == pingModeAuto:
+		slog.Debug("no permission for a raw icmp socket, skipping first public hop discovery", "err", err)
+	case err != nil:
+		slog.Warn("failed to discover first public hop", "err", err)
+	}
+	c.publicHop = hop
+	c.discoveredAt = time.Now()
+
+	return c.publicHop
+}
+
+// ping a target with the configured count and timeout
+func (c *LatencySegmentCollector) probe(ctx context.Context, target string) (*probing.Statistics, error) {
+	pinger, err := probing.NewPinger(target)
+	if err != nil {
+		return nil, err
+	}
+
+	pinger.Count = c.config.Count
+	pinger.Interval = 100 * time.Millisecond
+	pinger.Timeout = c.config.Timeout
+
+	if err := runPinger(ctx, pinger, c.mode); err != nil {
+		return nil, err
+	}
+	return pinger.Statistics(), nil
+}
+
+// resolve a host to its first ipv4 address
+func resolveIPv4(host string) (string, error) {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String(), nil
+		}
+	}
+	return "", &net.AddrError{Err: "no IPv4 address found", Addr: host}
+}
+
+// check whether an ipv4 address is publicly routable
+func isPublicIPv4(ip net.IP) bool {
+	return ip.To4() != nil && ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnatNetwork.Contains(ip)
+}
+
+// find the first publicly routable hop towards a destination by sending icmp echo requests with increasing ttl,
+// the trace stops early once ctx is cancelled
+func findFirstPublicHop(ctx context.Context, destination string, maxHops int, timeout time.Duration) (string, error) {
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = conn.Close() }()
+
+	// closing the socket unblocks a pending read
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	dst := &net.IPAddr{IP: net.ParseIP(destination)}
+	id := os.Getpid() & 0xffff
+	buf := make([]byte, 1500)
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := conn.IPv4PacketConn().SetTTL(ttl); err != nil {
+			return "", err
+		}
+
+		request := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Body: &icmp.Echo{ID: id, Seq: ttl, Data: []byte("openwrt-exporter")},
+		}
+		payload, err := request.Marshal(nil)
+		if err != nil {
+			return "", err
+		}
+		if _, err := conn.WriteTo(payload, dst); err != nil {
+			return "", err
+		}
+
+		// wait for the time exceeded (or echo reply) belonging to this probe
+		deadline := time.Now().Add(timeout)
+		for {
+			if err := conn.SetReadDeadline(deadline); err != nil {
+				return "", err
+			}
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				// no answer from this hop, try the next one
+				break
+			}
+
+			message, err := icmp.ParseMessage(1, buf[:n])
+			if err != nil {
+				continue
+			}
+			if !isOwnProbeReply(message, id) {
+				continue
+			}
+
+			ip := peer.(*net.IPAddr).IP
+			if isPublicIPv4(ip) {
+				return ip.String(), nil
+			}
+			if message.Type == ipv4.ICMPTypeEchoReply {
+				return "", fmt.Errorf("reached %s without passing a public hop", destination)
+			}
+			break
+		}
+	}
+
+	return "", fmt.Errorf("no public hop found within %d hops", maxHops)
+}
+
+// check whether an icmp message answers an echo request with the given id
+func isOwnProbeReply(message *icmp.Message, id int) bool {
+	switch body := message.Body.(type) {
+	case *icmp.Echo:
+		return message.Type == ipv4.ICMPTypeEchoReply && body.ID == id
+	case *icmp.TimeExceeded:
+		// the quoted datagram holds the original ip header followed by our icmp header
+		if len(body.Data) < ipv4.HeaderLen {
+			return false
+		}
+		headerLen := int(body.Data[0]&0x0f) * 4
+		if len(body.Data) < headerLen+8 {
+			return false
+		}
+		return int(body.Data[headerLen+4])<<8|int(body.Data[headerLen+5]) == id
+	}
+	return false
+}
+
+// load latency segment configuration from environment variables
+func loadLatencySegmentConfig() *LatencySegmentConfig {
+	config := &LatencySegmentConfig{
+		Count:             5,
+		Timeout:           2 * time.Second,
+		Interval:          30 * time.Second,
+		MaxHops:           8,
+		DiscoveryInterval: 10 * time.Minute,
+	}
+
+	// latency_anchor: internet anchor host, enables the latency breakdown
+	config.Anchor = os.Getenv("LATENCY_ANCHOR")
+
+	// latency_count: number of probes sent to each segment target
+	if countEnv := os.Getenv("LATENCY_COUNT"); countEnv != "" {
+		if count, err := strconv.Atoi(countEnv); err == nil && count > 0 {
+			config.Count = count
+		}
+	}
+
+	// latency_interval: time between two probes of the segment targets
+	if intervalEnv := os.Getenv("LATENCY_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.Interval = interval
+		}
+	}
+
+	// latency_max_hops: maximum ttl used to discover the first public hop
+	if hopsEnv := os.Getenv("LATENCY_MAX_HOPS"); hopsEnv != "" {
+		if hops, err := strconv.Atoi(hopsEnv); err == nil && hops > 0 {
+			config.MaxHops = hops
+		}
+	}
+
+	return config
+}
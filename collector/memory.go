@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// memory metrics collector
+type MemoryCollector struct {
+	memTotal     *prometheus.Desc
+	memFree      *prometheus.Desc
+	memAvailable *prometheus.Desc
+	buffers      *prometheus.Desc
+	cached       *prometheus.Desc
+	swapTotal    *prometheus.Desc
+	swapFree     *prometheus.Desc
+}
+
+// create a new memory collector
+func NewMemoryCollector() *MemoryCollector {
+	return &MemoryCollector{
+		memTotal: prometheus.NewDesc(
+			"openwrt_memory_total_bytes",
+			"total usable memory in bytes",
+			nil, nil,
+		),
+		memFree: prometheus.NewDesc(
+			"openwrt_memory_free_bytes",
+			"free memory in bytes",
+			nil, nil,
+		),
+		memAvailable: prometheus.NewDesc(
+			"openwrt_memory_available_bytes",
+			"memory available for new allocations without swapping in bytes",
+			nil, nil,
+		),
+		buffers: prometheus.NewDesc(
+			"openwrt_memory_buffers_bytes",
+			"memory used for block device buffers in bytes",
+			nil, nil,
+		),
+		cached: prometheus.NewDesc(
+			"openwrt_memory_cached_bytes",
+			"memory used for page cache in bytes",
+			nil, nil,
+		),
+		swapTotal: prometheus.NewDesc(
+			"openwrt_memory_swap_total_bytes",
+			"total swap space in bytes",
+			nil, nil,
+		),
+		swapFree: prometheus.NewDesc(
+			"openwrt_memory_swap_free_bytes",
+			"free swap space in bytes",
+			nil, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *MemoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.memTotal
+	ch <- c.memFree
+	ch <- c.memAvailable
+	ch <- c.buffers
+	ch <- c.cached
+	ch <- c.swapTotal
+	ch <- c.swapFree
+}
+
+// collect implements prometheus.Collector
+func (c *MemoryCollector) Collect(ch chan<- prometheus.Metric) {
+	meminfo, err := getMemInfo()
+	if err != nil {
+		logCollectError("memory", err)
+		return
+	}
+
+	metrics := []struct {
+		desc *prometheus.Desc
+		key  string
+	}{
+		{c.memTotal, "MemTotal"},
+		{c.memFree, "MemFree"},
+		{c.memAvailable, "MemAvailable"},
+		{c.buffers, "Buffers"},
+		{c.cached, "Cached"},
+		{c.swapTotal, "SwapTotal"},
+		{c.swapFree, "SwapFree"},
+	}
+
+	for _, m := range metrics {
+		value, ok := meminfo[m.key]
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			m.desc,
+			prometheus.GaugeValue,
+			value,
+		)
+	}
+}
+
+// get memory information in bytes from /proc/meminfo
+func getMemInfo() (map[string]float64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	meminfo := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		// format: <key>: <value> [kB]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		key := strings.TrimSuffix(fields[0], ":")
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+
+		meminfo[key] = value
+	}
+
+	return meminfo, scanner.Err()
+}
@@ -0,0 +1,245 @@
+//go:build !nomodem
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maximum size of a modem status page
+const maxModemPageSize = 1024 * 1024
+
+// bridged modem/ont status page collector
+type ModemCollector struct {
+	up     *prometheus.Desc
+	stat   *prometheus.Desc
+	config *ModemConfig
+
+	// whether the last fetch failed, an unreachable modem is only logged when it goes down or comes back
+	mu      sync.Mutex
+	failing bool
+}
+
+// create a new modem collector
+func NewModemCollector(config *ModemConfig) *ModemCollector {
+	return &ModemCollector{
+		up: prometheus.NewDesc(
+			"openwrt_modem_up",
+			"whether the modem status page could be fetched (1 = success)",
+			nil, nil,
+		),
+		stat: prometheus.NewDesc(
+			"openwrt_modem_stat",
+			"statistic extracted from the modem status page",
+			[]string{"name"}, nil,
+		),
+		config: config,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ModemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.stat
+}
+
+// collect implements prometheus.Collector
+func (c *ModemCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *ModemCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	if c.config.URL == "" {
+		return
+	}
+
+	page, err := c.fetchStatusPage(ctx)
+	c.logState(err)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+
+	// decode json lazily, only when a json rule is configured
+	var document any
+	decoded := false
+
+	for _, rule := range c.config.Rules {
+		var value float64
+		var ok bool
+
+		if rule.Regexp != nil {
+			value, ok = extractModemRegexp(page, rule.Regexp)
+		} else {
+			if !decoded {
+				if err := json.Unmarshal(page, &document); err != nil {
+					slog.Warn("modem status page is not valid json", "err", err)
+				}
+				decoded = true
+			}
+			value, ok = extractModemJSON(document, rule.JSONPath)
+		}
+
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.stat,
+			prometheus.GaugeValue,
+			value,
+			rule.Name,
+		)
+	}
+}
+
+// log the modem going down or coming back, openwrt_modem_up tracks it in between
+func (c *ModemCollector) logState(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil && !c.failing {
+		slog.Warn("modem status page unreachable", "err", err)
+	} else if err == nil && c.failing {
+		slog.Info("modem status page reachable again")
+	}
+	c.failing = err != nil
+}
+
+// fetch the modem status page
+func (c *ModemCollector) fetchStatusPage(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	client := &http.Client{Timeout: c.config.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxModemPageSize))
+}
+
+// extract the first capture group of a regex as a number
+func extractModemRegexp(page []byte, re *regexp.Regexp) (float64, bool) {
+	match := re.FindSubmatch(page)
+	if len(match) < 2 {
+		return 0, false
+	}
+	return parseModemValue(string(match[1]))
+}
+
+// walk a dotted json path (object keys or array indexes) and return the value as a number
+func extractModemJSON(document any, path []string) (float64, bool) {
+	current := document
+	for _, key := range path {
+		switch node := current.(type) {
+		case map[string]any:
+			current = node[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return 0, false
+			}
+			current = node[index]
+		default:
+			return 0, false
+		}
+	}
+
+	switch value := current.(type) {
+	case float64:
+		return value, true
+	case bool:
+		return boolToFloat64(value), true
+	case string:
+		return parseModemValue(value)
+	}
+	return 0, false
+}
+
+// parse a modem value, accepting numbers with trailing units (e.g. "38.5 dB")
+func parseModemValue(value string) (float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// load modem configuration from environment variables
+func loadModemConfig() *ModemConfig {
+	config := &ModemConfig{
+		URL:      os.Getenv("MODEM_URL"),
+		Username: os.Getenv("MODEM_USERNAME"),
+		Password: os.Getenv("MODEM_PASSWORD"),
+		Timeout:  5 * time.Second,
+	}
+
+	// modem_timeout: timeout for fetching the modem status page
+	if timeoutEnv := os.Getenv("MODEM_TIMEOUT"); timeoutEnv != "" {
+		if timeout, err := time.ParseDuration(timeoutEnv); err == nil && timeout > 0 {
+			config.Timeout = timeout
+		}
+	}
+
+	// modem_rules: semicolon-separated list of <name>=json:<path> or <name>=regex:<expression>
+	if rulesEnv := os.Getenv("MODEM_RULES"); rulesEnv != "" {
+		for _, entry := range strings.Split(rulesEnv, ";") {
+			name, rule, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok || name == "" {
+				slog.Warn("invalid MODEM_RULES entry", "entry", entry)
+				continue
+			}
+
+			if path, ok := strings.CutPrefix(rule, "json:"); ok {
+				config.Rules = append(config.Rules, ModemRule{
+					Name:     name,
+					JSONPath: strings.Split(path, "."),
+				})
+			} else if expression, ok := strings.CutPrefix(rule, "regex:"); ok {
+				re, err := regexp.Compile(expression)
+				if err != nil || re.NumSubexp() < 1 {
+					slog.Warn("invalid MODEM_RULES regex", "name", name, "regex", expression)
+					continue
+				}
+				config.Rules = append(config.Rules, ModemRule{
+					Name:   name,
+					Regexp: re,
+				})
+			} else {
+				slog.Warn("invalid MODEM_RULES entry", "entry", entry)
+			}
+		}
+	}
+
+	return config
+}
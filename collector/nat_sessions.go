@@ -0,0 +1,260 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// nat session per internal host collector
+type NATSessionCollector struct {
+	sessions *prometheus.Desc
+	limit    *prometheus.Desc
+	config   *NATSessionConfig
+}
+
+// nat session configuration
+type NATSessionConfig struct {
+	TopN int
+}
+
+// create a new nat session collector
+func NewNATSessionCollector(config *NATSessionConfig) *NATSessionCollector {
+	return &NATSessionCollector{
+		sessions: prometheus.NewDesc(
+			"openwrt_nat_host_sessions",
+			"number of translated conntrack sessions opened by an internal host",
+			[]string{"ip"}, nil,
+		),
+		limit: prometheus.NewDesc(
+			"openwrt_nat_host_session_limit",
+			"per-host connection limit configured with an nftables 'ct count' rule (host is an ip, a prefix or * for every host, handle tells apart rules of one chain)",
+			[]string{"host", "family", "table", "chain", "handle"}, nil,
+		),
+		config: config,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *NATSessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sessions
+	ch <- c.limit
+}
+
+// collect implements prometheus.Collector
+func (c *NATSessionCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *NATSessionCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	entries, err := getConntrackEntries()
+	if err != nil {
+		logCollectError("nat session", err)
+		return
+	}
+
+	for _, host := range topNATHosts(entries, c.config.TopN) {
+		ch <- prometheus.MustNewConstMetric(
+			c.sessions,
+			prometheus.GaugeValue,
+			host.Sessions,
+			privateIP(host.IP),
+		)
+	}
+
+	limits, err := getConnectionLimits(ctx)
+	if err != nil {
+		// fw3 routers have no nft, their limits are not exported
+		logCollectError("nat session limit", err)
+		return
+	}
+	for _, limit := range limits {
+		host := limit.Host
+		if host != "*" {
+			host = privateIP(host)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.limit,
+			prometheus.GaugeValue,
+			limit.Limit,
+			host, limit.Family, limit.Table, limit.Chain, strconv.Itoa(limit.Handle),
+		)
+	}
+}
+
+// translated session count of an internal host
+type NATHost struct {
+	IP       string
+	Sessions float64
+}
+
+// count translated sessions per private source address, returning the top n hosts (all if n is 0)
+func topNATHosts(entries []ConntrackEntry, n int) []NATHost {
+	counts := make(map[string]float64)
+	for _, entry := range entries {
+		// source nat rewrites the reply destination
+		if entry.ReplyDst == "" || entry.ReplyDst == entry.Src {
+			continue
+		}
+		if ip := net.ParseIP(entry.Src); ip == nil || !ip.IsPrivate() {
+			continue
+		}
+		counts[entry.Src]++
+	}
+
+	hosts := make([]NATHost, 0, len(counts))
+	for ip, count := range counts {
+		hosts = append(hosts, NATHost{IP: ip, Sessions: count})
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Sessions != hosts[j].Sessions {
+			return hosts[i].Sessions > hosts[j].Sessions
+		}
+		return hosts[i].IP < hosts[j].IP
+	})
+
+	if n > 0 && len(hosts) > n {
+		hosts = hosts[:n]
+	}
+	return hosts
+}
+
+// connection limit configured in an nftables rule
+type ConnectionLimit struct {
+	Host   string
+	Family string
+	Table  string
+	Chain  string
+	// handle of the rule, unique within its table, so rules without a source match
+	// (e.g. an ip saddr and an ip6 saddr meter) do not collide
+	Handle int
+	Limit  float64
+}
+
+// find 'ct count' connection limits in the nftables ruleset
+func getConnectionLimits(ctx context.Context) ([]ConnectionLimit, error) {
+	output, err := runCommand(ctx, "nft", "-j", "list", "ruleset")
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleset struct {
+		Nftables []struct {
+			Rule *struct {
+				Family string `json:"family"`
+				Table  string `json:"table"`
+				Chain  string `json:"chain"`
+				Handle int    `json:"handle"`
+				Expr   []any  `json:"expr"`
+			} `json:"rule"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &ruleset); err != nil {
+		return nil, err
+	}
+
+	var limits []ConnectionLimit
+	for _, item := range ruleset.Nftables {
+		if item.Rule == nil {
+			continue
+		}
+
+		limit, ok := findCtCount(item.Rule.Expr)
+		if !ok {
+			continue
+		}
+
+		// a rule matching a single source applies to that host, otherwise to every host
+		host := "*"
+		for _, expr := range item.Rule.Expr {
+			if source, ok := matchSourceAddress(expr); ok {
+				host = source
+			}
+		}
+
+		limits = append(limits, ConnectionLimit{
+			Host:   host,
+			Family: item.Rule.Family,
+			Table:  item.Rule.Table,
+			Chain:  item.Rule.Chain,
+			Handle: item.Rule.Handle,
+			Limit:  limit,
+		})
+	}
+
+	return limits, nil
+}
+
+// recursively search an expression tree for a 'ct count' statement
+func findCtCount(node any) (float64, bool) {
+	switch value := node.(type) {
+	case map[string]any:
+		if ct, ok := value["ct count"].(map[string]any); ok {
+			if limit, ok := ct["val"].(float64); ok {
+				return limit, true
+			}
+		}
+		for _, child := range value {
+			if limit, ok := findCtCount(child); ok {
+				return limit, true
+			}
+		}
+	case []any:
+		for _, child := range value {
+			if limit, ok := findCtCount(child); ok {
+				return limit, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// get the address or prefix of an 'ip saddr' / 'ip6 saddr' equality match
+func matchSourceAddress(expr any) (string, bool) {
+	node, _ := expr.(map[string]any)
+	match, _ := node["match"].(map[string]any)
+	if match == nil || (match["op"] != "==" && match["op"] != "in") {
+		return "", false
+	}
+
+	left, _ := match["left"].(map[string]any)
+	payload, _ := left["payload"].(map[string]any)
+	if payload == nil || payload["field"] != "saddr" {
+		return "", false
+	}
+
+	switch right := match["right"].(type) {
+	case string:
+		return right, true
+	case map[string]any:
+		if prefix, ok := right["prefix"].(map[string]any); ok {
+			addr, _ := prefix["addr"].(string)
+			length, _ := prefix["len"].(float64)
+			return fmt.Sprintf("%s/%d", addr, int(length)), true
+		}
+	}
+	return "", false
+}
+
+// load nat session configuration from environment variables
+func loadNATSessionConfig() *NATSessionConfig {
+	config := &NATSessionConfig{
+		TopN: 10,
+	}
+
+	// nat_top_n: number of hosts with the most sessions to export, 0 exports all hosts
+	if topEnv := os.Getenv("NAT_TOP_N"); topEnv != "" {
+		if top, err := strconv.Atoi(topEnv); err == nil && top >= 0 {
+			config.TopN = top
+		}
+	}
+
+	return config
+}
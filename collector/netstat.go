@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// protocol statistics exported from /proc/net/snmp and /proc/net/netstat
+var protocolStats = []struct {
+	protocol  string
+	field     string
+	name      string
+	help      string
+	valueType prometheus.ValueType
+}{
+	{"Ip", "ForwDatagrams", "openwrt_ip_forwarded_datagrams_total", "total number of forwarded ip datagrams", prometheus.CounterValue},
+	{"Ip", "InDiscards", "openwrt_ip_in_discards_total", "total number of received ip datagrams discarded for lack of buffer space", prometheus.CounterValue},
+	{"Ip", "OutNoRoutes", "openwrt_ip_out_no_routes_total", "total number of ip datagrams discarded because no route was found", prometheus.CounterValue},
+	{"Tcp", "ActiveOpens", "openwrt_tcp_active_opens_total", "total number of tcp connections opened by the router", prometheus.CounterValue},
+	{"Tcp", "PassiveOpens", "openwrt_tcp_passive_opens_total", "total number of tcp connections accepted by the router", prometheus.CounterValue},
+	{"Tcp", "AttemptFails", "openwrt_tcp_attempt_fails_total", "total number of failed tcp connection attempts", prometheus.CounterValue},
+	{"Tcp", "EstabResets", "openwrt_tcp_established_resets_total", "total number of established tcp connections that were reset", prometheus.CounterValue},
+	{"Tcp", "CurrEstab", "openwrt_tcp_current_established", "number of tcp connections in established or close-wait state", prometheus.GaugeValue},
+	{"Tcp", "InSegs", "openwrt_tcp_in_segments_total", "total number of received tcp segments", prometheus.CounterValue},
+	{"Tcp", "OutSegs", "openwrt_tcp_out_segments_total", "total number of sent tcp segments", prometheus.CounterValue},
+	{"Tcp", "RetransSegs", "openwrt_tcp_retransmitted_segments_total", "total number of retransmitted tcp segments", prometheus.CounterValue},
+	{"Tcp", "InErrs", "openwrt_tcp_in_errors_total", "total number of tcp segments received in error", prometheus.CounterValue},
+	{"Tcp", "OutRsts", "openwrt_tcp_out_resets_total", "total number of sent tcp segments with the rst flag", prometheus.CounterValue},
+	{"TcpExt", "ListenOverflows", "openwrt_tcp_listen_overflows_total", "total number of times the accept queue of a listening tcp socket overflowed", prometheus.CounterValue},
+	{"TcpExt", "ListenDrops", "openwrt_tcp_listen_drops_total", "total number of syn packets dropped by listening tcp sockets", prometheus.CounterValue},
+	{"TcpExt", "SyncookiesSent", "openwrt_tcp_syncookies_sent_total", "total number of syn cookies sent", prometheus.CounterValue},
+	{"TcpExt", "SyncookiesFailed", "openwrt_tcp_syncookies_failed_total", "total number of invalid syn cookies received", prometheus.CounterValue},
+	{"TcpExt", "TCPTimeouts", "openwrt_tcp_timeouts_total", "total number of tcp retransmission timeouts", prometheus.CounterValue},
+	{"Udp", "InDatagrams", "openwrt_udp_in_datagrams_total", "total number of udp datagrams delivered to sockets", prometheus.CounterValue},
+	{"Udp", "OutDatagrams", "openwrt_udp_out_datagrams_total", "total number of sent udp datagrams", prometheus.CounterValue},
+	{"Udp", "NoPorts", "openwrt_udp_no_ports_total", "total number of received udp datagrams for ports without a socket", prometheus.CounterValue},
+	{"Udp", "InErrors", "openwrt_udp_in_errors_total", "total number of received udp datagrams that could not be delivered", prometheus.CounterValue},
+	{"Udp", "RcvbufErrors", "openwrt_udp_receive_buffer_errors_total", "total number of udp datagrams dropped because the socket receive buffer was full", prometheus.CounterValue},
+	{"Udp", "SndbufErrors", "openwrt_udp_send_buffer_errors_total", "total number of udp datagrams dropped because the socket send buffer was full", prometheus.CounterValue},
+	{"Icmp", "InMsgs", "openwrt_icmp_in_messages_total", "total number of received icmp messages", prometheus.CounterValue},
+	{"Icmp", "OutMsgs", "openwrt_icmp_out_messages_total", "total number of sent icmp messages", prometheus.CounterValue},
+	{"Icmp", "InErrors", "openwrt_icmp_in_errors_total", "total number of received icmp messages with errors", prometheus.CounterValue},
+	{"Icmp", "InDestUnreachs", "openwrt_icmp_in_destination_unreachable_total", "total number of received icmp destination unreachable messages", prometheus.CounterValue},
+	{"Icmp", "OutDestUnreachs", "openwrt_icmp_out_destination_unreachable_total", "total number of sent icmp destination unreachable messages", prometheus.CounterValue},
+}
+
+// tcp, udp and icmp protocol statistics collector
+type NetstatCollector struct {
+	descs []*prometheus.Desc
+}
+
+// create a new netstat collector
+func NewNetstatCollector() *NetstatCollector {
+	c := &NetstatCollector{}
+	for _, stat := range protocolStats {
+		c.descs = append(c.descs, prometheus.NewDesc(stat.name, stat.help, nil, nil))
+	}
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *NetstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+// collect implements prometheus.Collector
+func (c *NetstatCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := getProtocolStats("/proc/net/snmp")
+	if err != nil {
+		logCollectError("netstat", err)
+		return
+	}
+
+	// tcp extensions, missing on kernels without procfs netstat support
+	extStats, err := getProtocolStats("/proc/net/netstat")
+	if err != nil {
+		logCollectError("netstat", err, "path", "/proc/net/netstat")
+	}
+	for protocol, values := range extStats {
+		stats[protocol] = values
+	}
+
+	// counters start at boot, a missing boot time only drops the created timestamps
+	bootTime, _ := getBootTime()
+	for i, stat := range protocolStats {
+		value, ok := stats[stat.protocol][stat.field]
+		if !ok {
+			continue
+		}
+		if stat.valueType == prometheus.CounterValue {
+			ch <- newCreatedCounter(c.descs[i], value, bootTime)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.descs[i],
+			stat.valueType,
+			value,
+		)
+	}
+}
+
+// get protocol statistics keyed by protocol and field name
+// format: pairs of "<protocol>: <field> <field> ..." and "<protocol>: <value> <value> ..." lines
+func getProtocolStats(path string) (map[string]map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := make(map[string]map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		header := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		values := strings.Fields(scanner.Text())
+		if len(header) == 0 || len(header) != len(values) || header[0] != values[0] {
+			return nil, fmt.Errorf("unexpected format of %s near %q", path, scanner.Text())
+		}
+
+		protocol := strings.TrimSuffix(header[0], ":")
+		stats[protocol] = make(map[string]float64)
+		for i := 1; i < len(header); i++ {
+			if value, err := strconv.ParseFloat(values[i], 64); err == nil {
+				stats[protocol][header[i]] = value
+			}
+		}
+	}
+
+	return stats, scanner.Err()
+}
@@ -2,10 +2,16 @@ package collector
 
 import (
 	"bufio"
-	"log"
+	"context"
+	"encoding/binary"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -17,6 +23,22 @@ type NetworkCollector struct {
 	uptime    *prometheus.Desc
 	rxPackets *prometheus.Desc
 	txPackets *prometheus.Desc
+
+	rxErrors     *prometheus.Desc
+	rxDropped    *prometheus.Desc
+	rxFifo       *prometheus.Desc
+	rxFrame      *prometheus.Desc
+	rxMulticast  *prometheus.Desc
+	txErrors     *prometheus.Desc
+	txDropped    *prometheus.Desc
+	txFifo       *prometheus.Desc
+	txCollisions *prometheus.Desc
+	txCarrier    *prometheus.Desc
+
+	up             *prometheus.Desc
+	carrierChanges *prometheus.Desc
+	speed          *prometheus.Desc
+	fullDuplex     *prometheus.Desc
 }
 
 // create a new network collector
@@ -42,9 +64,79 @@ func NewNetworkCollector() *NetworkCollector {
 			"total number of packets transmitted on network interface",
 			[]string{"interface"}, nil,
 		),
+		rxErrors: prometheus.NewDesc(
+			"openwrt_network_receive_errors_total",
+			"total number of receive errors on network interface",
+			[]string{"interface"}, nil,
+		),
+		rxDropped: prometheus.NewDesc(
+			"openwrt_network_receive_drop_total",
+			"total number of received packets dropped on network interface",
+			[]string{"interface"}, nil,
+		),
+		rxFifo: prometheus.NewDesc(
+			"openwrt_network_receive_fifo_total",
+			"total number of receive fifo overruns on network interface",
+			[]string{"interface"}, nil,
+		),
+		rxFrame: prometheus.NewDesc(
+			"openwrt_network_receive_frame_total",
+			"total number of received frames with alignment or crc errors on network interface",
+			[]string{"interface"}, nil,
+		),
+		rxMulticast: prometheus.NewDesc(
+			"openwrt_network_receive_multicast_total",
+			"total number of multicast packets received on network interface",
+			[]string{"interface"}, nil,
+		),
+		txErrors: prometheus.NewDesc(
+			"openwrt_network_transmit_errors_total",
+			"total number of transmit errors on network interface",
+			[]string{"interface"}, nil,
+		),
+		txDropped: prometheus.NewDesc(
+			"openwrt_network_transmit_drop_total",
+			"total number of packets dropped before transmission on network interface",
+			[]string{"interface"}, nil,
+		),
+		txFifo: prometheus.NewDesc(
+			"openwrt_network_transmit_fifo_total",
+			"total number of transmit fifo underruns on network interface",
+			[]string{"interface"}, nil,
+		),
+		txCollisions: prometheus.NewDesc(
+			"openwrt_network_transmit_colls_total",
+			"total number of collisions while transmitting on network interface",
+			[]string{"interface"}, nil,
+		),
+		txCarrier: prometheus.NewDesc(
+			"openwrt_network_transmit_carrier_total",
+			"total number of carrier losses while transmitting on network interface",
+			[]string{"interface"}, nil,
+		),
+		up: prometheus.NewDesc(
+			"openwrt_network_up",
+			"whether the network interface is operationally up (1 = up)",
+			[]string{"interface", "operstate"}, nil,
+		),
+		carrierChanges: prometheus.NewDesc(
+			"openwrt_network_carrier_changes_total",
+			"total number of link up and down transitions of network interface",
+			[]string{"interface"}, nil,
+		),
+		speed: prometheus.NewDesc(
+			"openwrt_network_speed_mbps",
+			"negotiated link speed of network interface in megabits per second",
+			[]string{"interface"}, nil,
+		),
+		fullDuplex: prometheus.NewDesc(
+			"openwrt_network_full_duplex",
+			"whether the network interface negotiated full duplex (1 = full, 0 = half)",
+			[]string{"interface"}, nil,
+		),
 		uptime: prometheus.NewDesc(
 			"openwrt_network_uptime_seconds",
-			"network interface uptime in seconds",
+			"time since the logical interface using this device came up in seconds",
 			[]string{"interface"}, nil,
 		),
 	}
@@ -56,17 +148,42 @@ func (c *NetworkCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.txBytes
 	ch <- c.rxPackets
 	ch <- c.txPackets
+	ch <- c.rxErrors
+	ch <- c.rxDropped
+	ch <- c.rxFifo
+	ch <- c.rxFrame
+	ch <- c.rxMulticast
+	ch <- c.txErrors
+	ch <- c.txDropped
+	ch <- c.txFifo
+	ch <- c.txCollisions
+	ch <- c.txCarrier
+	ch <- c.up
+	ch <- c.carrierChanges
+	ch <- c.speed
+	ch <- c.fullDuplex
 	ch <- c.uptime
 }
 
 // collect implements prometheus.Collector
 func (c *NetworkCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *NetworkCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	interfaces, err := getNetworkInterfaces()
 	if err != nil {
-		log.Printf("error collecting network metrics: %v", err)
+		logCollectError("network", err)
 		return
 	}
 
+	// only devices of logical interfaces managed by netifd have an uptime
+	uptimes, err := getDeviceUptimes(ctx)
+	if err != nil {
+		logCollectError("interface uptime", err)
+	}
+
 	for _, iface := range interfaces {
 		ch <- prometheus.MustNewConstMetric(
 			c.rxBytes,
@@ -93,28 +210,232 @@ func (c *NetworkCollector) Collect(ch chan<- prometheus.Metric) {
 			iface.Name,
 		)
 
-		// get interface uptime from /sys/class/net/<interface>/statistics/uptime or use system uptime
-		uptime := getInterfaceUptime(iface.Name)
+		errorCounters := []struct {
+			desc  *prometheus.Desc
+			value uint64
+		}{
+			{c.rxErrors, iface.RxErrors},
+			{c.rxDropped, iface.RxDropped},
+			{c.rxFifo, iface.RxFifo},
+			{c.rxFrame, iface.RxFrame},
+			{c.rxMulticast, iface.RxMulticast},
+			{c.txErrors, iface.TxErrors},
+			{c.txDropped, iface.TxDropped},
+			{c.txFifo, iface.TxFifo},
+			{c.txCollisions, iface.TxCollisions},
+			{c.txCarrier, iface.TxCarrier},
+		}
+		for _, counter := range errorCounters {
+			ch <- prometheus.MustNewConstMetric(
+				counter.desc,
+				prometheus.CounterValue,
+				float64(counter.value),
+				iface.Name,
+			)
+		}
+
+		if uptime, ok := uptimes[iface.Name]; ok {
+			ch <- prometheus.MustNewConstMetric(
+				c.uptime,
+				prometheus.GaugeValue,
+				uptime,
+				iface.Name,
+			)
+		}
+
+		c.collectLinkState(ch, iface.Name)
+	}
+}
+
+// collect operational state, carrier changes, speed and duplex from /sys/class/net/<interface>
+func (c *NetworkCollector) collectLinkState(ch chan<- prometheus.Metric, name string) {
+	dir := filepath.Join("/sys/class/net", name)
+
+	// tunnels and ppp devices report "unknown" but have a carrier while usable
+	operstate := readSysfsString(filepath.Join(dir, "operstate"))
+	if operstate != "" {
+		up := operstate == "up" || (operstate == "unknown" && readSysfsString(filepath.Join(dir, "carrier")) == "1")
+		ch <- prometheus.MustNewConstMetric(
+			c.up,
+			prometheus.GaugeValue,
+			boolToFloat64(up),
+			name, operstate,
+		)
+	}
+
+	if changes, err := strconv.ParseFloat(readSysfsString(filepath.Join(dir, "carrier_changes")), 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.carrierChanges,
+			prometheus.CounterValue,
+			changes,
+			name,
+		)
+	}
+
+	// speed and duplex are only known for physical ports with a link (-1 and "unknown" otherwise)
+	if speed, err := strconv.ParseFloat(readSysfsString(filepath.Join(dir, "speed")), 64); err == nil && speed > 0 {
 		ch <- prometheus.MustNewConstMetric(
-			c.uptime,
+			c.speed,
 			prometheus.GaugeValue,
-			uptime,
-			iface.Name,
+			speed,
+			name,
 		)
 	}
+	switch readSysfsString(filepath.Join(dir, "duplex")) {
+	case "full":
+		ch <- prometheus.MustNewConstMetric(c.fullDuplex, prometheus.GaugeValue, 1, name)
+	case "half":
+		ch <- prometheus.MustNewConstMetric(c.fullDuplex, prometheus.GaugeValue, 0, name)
+	}
+}
+
+// network interface statistics configuration shared by the network, network role and wan utilization collectors
+type NetworkConfig struct {
+	// read statistics from /proc/net/dev instead of rtnetlink
+	ProcStats bool
+}
+
+var (
+	networkConfig     *NetworkConfig
+	networkConfigOnce sync.Once
+)
+
+// get the network configuration, loading it from environment variables on first use
+func getNetworkConfig() *NetworkConfig {
+	networkConfigOnce.Do(func() {
+		networkConfig = loadNetworkConfig()
+	})
+	return networkConfig
+}
+
+// replace the network configuration used by all collectors (nil keeps the current one)
+func setNetworkConfig(config *NetworkConfig) {
+	if config == nil {
+		return
+	}
+	networkConfigOnce.Do(func() {})
+	networkConfig = config
+}
+
+// load network configuration from environment variables
+func loadNetworkConfig() *NetworkConfig {
+	config := &NetworkConfig{}
+
+	// network_proc_stats: read interface statistics from /proc/net/dev instead of rtnetlink
+	if procEnv := os.Getenv("NETWORK_PROC_STATS"); procEnv != "" {
+		if procStats, err := strconv.ParseBool(procEnv); err == nil {
+			config.ProcStats = procStats
+		}
+	}
+
+	return config
 }
 
 // networkinterface represents a network interface
 type NetworkInterface struct {
 	Name      string
+	Flags     uint32
 	RxBytes   uint64
 	TxBytes   uint64
 	RxPackets uint64
 	TxPackets uint64
+
+	RxErrors     uint64
+	RxDropped    uint64
+	RxFifo       uint64
+	RxFrame      uint64
+	RxMulticast  uint64
+	TxErrors     uint64
+	TxDropped    uint64
+	TxFifo       uint64
+	TxCollisions uint64
+	TxCarrier    uint64
 }
 
-// get network interfaces from /proc/net/dev
+// get network interfaces and their statistics, skipping the loopback interface
 func getNetworkInterfaces() ([]NetworkInterface, error) {
+	if getNetworkConfig().ProcStats {
+		return getProcNetworkInterfaces()
+	}
+	return getNetlinkNetworkInterfaces()
+}
+
+// rtnetlink link attributes (linux/if_link.h)
+const (
+	iflaIfname  = 3
+	iflaStats64 = 23
+)
+
+// number of 64-bit counters in struct rtnl_link_stats64 up to and including tx_compressed
+const linkStats64Counters = 23
+
+// get network interfaces with 64-bit statistics from an RTM_GETLINK dump
+func getNetlinkNetworkInterfaces() ([]NetworkInterface, error) {
+	data, err := syscall.NetlinkRIB(syscall.RTM_GETLINK, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("rtnetlink link dump: %w", err)
+	}
+	messages, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("rtnetlink link dump: %w", err)
+	}
+
+	var interfaces []NetworkInterface
+	for _, message := range messages {
+		if message.Header.Type != syscall.RTM_NEWLINK || len(message.Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+		info := (*syscall.IfInfomsg)(unsafe.Pointer(&message.Data[0]))
+		if info.Flags&syscall.IFF_LOOPBACK != 0 {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&message)
+		if err != nil {
+			continue
+		}
+
+		iface := NetworkInterface{Flags: info.Flags}
+		var stats []byte
+		for _, attr := range attrs {
+			switch attr.Attr.Type {
+			case iflaIfname:
+				iface.Name = strings.TrimRight(string(attr.Value), "\x00")
+			case iflaStats64:
+				stats = attr.Value
+			}
+		}
+		if iface.Name == "" || len(stats) < linkStats64Counters*8 {
+			continue
+		}
+
+		// struct rtnl_link_stats64, combined into the /proc/net/dev columns the same way the kernel does
+		var counters [linkStats64Counters]uint64
+		for i := range counters {
+			counters[i] = binary.NativeEndian.Uint64(stats[i*8:])
+		}
+		iface.RxPackets = counters[0]
+		iface.TxPackets = counters[1]
+		iface.RxBytes = counters[2]
+		iface.TxBytes = counters[3]
+		iface.RxErrors = counters[4]
+		iface.TxErrors = counters[5]
+		iface.RxDropped = counters[6] + counters[15]
+		iface.TxDropped = counters[7]
+		iface.RxMulticast = counters[8]
+		iface.TxCollisions = counters[9]
+		iface.RxFrame = counters[10] + counters[11] + counters[12] + counters[13]
+		iface.RxFifo = counters[14]
+		iface.TxCarrier = counters[16] + counters[17] + counters[19] + counters[20]
+		iface.TxFifo = counters[18]
+
+		interfaces = append(interfaces, iface)
+	}
+
+	return interfaces, nil
+}
+
+// get network interfaces from /proc/net/dev
+func getProcNetworkInterfaces() ([]NetworkInterface, error) {
 	file, err := os.Open("/proc/net/dev")
 	if err != nil {
 		return nil, err
@@ -143,40 +464,31 @@ func getNetworkInterfaces() ([]NetworkInterface, error) {
 			continue
 		}
 
-		rxBytes, _ := strconv.ParseUint(fields[1], 10, 64)
-		rxPackets, _ := strconv.ParseUint(fields[2], 10, 64)
-		txBytes, _ := strconv.ParseUint(fields[9], 10, 64)
-		txPackets, _ := strconv.ParseUint(fields[10], 10, 64)
+		// receive: bytes packets errs drop fifo frame compressed multicast
+		// transmit: bytes packets errs drop fifo colls carrier compressed
+		var counters [16]uint64
+		for i := range counters {
+			counters[i], _ = strconv.ParseUint(fields[i+1], 10, 64)
+		}
 
 		interfaces = append(interfaces, NetworkInterface{
-			Name:      name,
-			RxBytes:   rxBytes,
-			TxBytes:   txBytes,
-			RxPackets: rxPackets,
-			TxPackets: txPackets,
+			Name:         name,
+			RxBytes:      counters[0],
+			RxPackets:    counters[1],
+			RxErrors:     counters[2],
+			RxDropped:    counters[3],
+			RxFifo:       counters[4],
+			RxFrame:      counters[5],
+			RxMulticast:  counters[7],
+			TxBytes:      counters[8],
+			TxPackets:    counters[9],
+			TxErrors:     counters[10],
+			TxDropped:    counters[11],
+			TxFifo:       counters[12],
+			TxCollisions: counters[13],
+			TxCarrier:    counters[14],
 		})
 	}
 
 	return interfaces, scanner.Err()
 }
-
-// get interface uptime, fallback to system uptime
-func getInterfaceUptime(_ string) float64 {
-	// try to read system uptime as a proxy
-	data, err := os.ReadFile("/proc/uptime")
-	if err != nil {
-		return 0
-	}
-
-	fields := strings.Fields(string(data))
-	if len(fields) < 1 {
-		return 0
-	}
-
-	uptime, err := strconv.ParseFloat(fields[0], 64)
-	if err != nil {
-		return 0
-	}
-
-	return uptime
-}
@@ -0,0 +1,309 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// neighbor states in which a gateway is known not to answer
+var unreachableNeighborStates = map[string]bool{
+	"FAILED":     true,
+	"INCOMPLETE": true,
+}
+
+// neighbor states confirmed reachable, stale entries have not been confirmed recently
+var reachableNeighborStates = map[string]bool{
+	"REACHABLE": true,
+	"PERMANENT": true,
+	"NOARP":     true,
+}
+
+// logical (netifd) network interface collector
+type NetworkInterfaceCollector struct {
+	up               *prometheus.Desc
+	uptime           *prometheus.Desc
+	info             *prometheus.Desc
+	gatewayReachable *prometheus.Desc
+	dnsServer        *prometheus.Desc
+	prefixLength     *prometheus.Desc
+	prefixValid      *prometheus.Desc
+	prefixPreferred  *prometheus.Desc
+	raReceived       *prometheus.Desc
+}
+
+// create a new network interface collector
+func NewNetworkInterfaceCollector() *NetworkInterfaceCollector {
+	return &NetworkInterfaceCollector{
+		up: prometheus.NewDesc(
+			"openwrt_interface_up",
+			"whether the logical network interface is up (1 = up)",
+			[]string{"interface"}, nil,
+		),
+		uptime: prometheus.NewDesc(
+			"openwrt_interface_uptime_seconds",
+			"time since the logical network interface came up in seconds",
+			[]string{"interface"}, nil,
+		),
+		info: prometheus.NewDesc(
+			"openwrt_interface_info",
+			"protocol and devices of a logical network interface",
+			[]string{"interface", "proto", "device", "l3_device"}, nil,
+		),
+		gatewayReachable: prometheus.NewDesc(
+			"openwrt_interface_gateway_reachable",
+			"whether the default gateway of the interface answers neighbor discovery (1 = reachable)",
+			[]string{"interface", "gateway"}, nil,
+		),
+		dnsServer: prometheus.NewDesc(
+			"openwrt_interface_dns_server_info",
+			"dns server configured on or received by a logical network interface",
+			[]string{"interface", "server"}, nil,
+		),
+		prefixLength: prometheus.NewDesc(
+			"openwrt_interface_ipv6_prefix_length",
+			"length of an ipv6 prefix delegated to a logical network interface",
+			[]string{"interface", "prefix"}, nil,
+		),
+		prefixValid: prometheus.NewDesc(
+			"openwrt_interface_ipv6_prefix_valid_seconds",
+			"remaining valid lifetime of a delegated ipv6 prefix in seconds",
+			[]string{"interface", "prefix"}, nil,
+		),
+		prefixPreferred: prometheus.NewDesc(
+			"openwrt_interface_ipv6_prefix_preferred_seconds",
+			"remaining preferred lifetime of a delegated ipv6 prefix in seconds",
+			[]string{"interface", "prefix"}, nil,
+		),
+		raReceived: prometheus.NewDesc(
+			"openwrt_interface_ipv6_ra_received",
+			"whether router advertisements are received on a dhcpv6 interface (1 = default route present)",
+			[]string{"interface"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *NetworkInterfaceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.uptime
+	ch <- c.info
+	ch <- c.gatewayReachable
+	ch <- c.dnsServer
+	ch <- c.prefixLength
+	ch <- c.prefixValid
+	ch <- c.prefixPreferred
+	ch <- c.raReceived
+}
+
+// collect implements prometheus.Collector
+func (c *NetworkInterfaceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *NetworkInterfaceCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	interfaces, err := getUbusNetworkInterfaces(ctx)
+	if err != nil {
+		logCollectError("network interface", err)
+		return
+	}
+
+	neighbors, err := getNeighborStatesByIP(ctx)
+	if err != nil {
+		logCollectError("network interface", err, "source", "neighbor table")
+	}
+
+	for _, iface := range interfaces {
+		ch <- prometheus.MustNewConstMetric(
+			c.up,
+			prometheus.GaugeValue,
+			boolToFloat64(iface.Up),
+			iface.Interface,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.info,
+			prometheus.GaugeValue,
+			1,
+			iface.Interface, iface.Proto, iface.Device, iface.L3Device,
+		)
+		if !iface.Up {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.uptime,
+			prometheus.GaugeValue,
+			float64(iface.Uptime),
+			iface.Interface,
+		)
+		for _, gateway := range iface.gateways() {
+			ch <- prometheus.MustNewConstMetric(
+				c.gatewayReachable,
+				prometheus.GaugeValue,
+				boolToFloat64(isGatewayReachable(iface.L3Device, gateway, neighbors)),
+				iface.Interface, gateway,
+			)
+		}
+		for _, server := range iface.DNSServers {
+			ch <- prometheus.MustNewConstMetric(
+				c.dnsServer,
+				prometheus.GaugeValue,
+				1,
+				iface.Interface, server,
+			)
+		}
+
+		for _, prefix := range iface.IPv6Prefixes {
+			name := fmt.Sprintf("%s/%d", prefix.Address, prefix.Mask)
+			ch <- prometheus.MustNewConstMetric(
+				c.prefixLength,
+				prometheus.GaugeValue,
+				float64(prefix.Mask),
+				iface.Interface, name,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.prefixValid,
+				prometheus.GaugeValue,
+				float64(prefix.Valid),
+				iface.Interface, name,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.prefixPreferred,
+				prometheus.GaugeValue,
+				float64(prefix.Preferred),
+				iface.Interface, name,
+			)
+		}
+
+		// ipv6 default routes only come from router advertisements, which odhcp6c passes to netifd
+		if iface.Proto == "dhcpv6" {
+			received := false
+			for _, route := range iface.Routes {
+				if route.Target == "::" && route.Mask == 0 {
+					received = true
+				}
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.raReceived,
+				prometheus.GaugeValue,
+				boolToFloat64(received),
+				iface.Interface,
+			)
+		}
+	}
+}
+
+// logical interface from 'ubus call network.interface dump'
+type UbusNetworkInterface struct {
+	Interface     string   `json:"interface"`
+	Up            bool     `json:"up"`
+	Uptime        int64    `json:"uptime"`
+	Proto         string   `json:"proto"`
+	Device        string   `json:"device"`
+	L3Device      string   `json:"l3_device"`
+	DNSServers    []string `json:"dns-server"`
+	IPv4Addresses []struct {
+		Address string `json:"address"`
+		Mask    int    `json:"mask"`
+	} `json:"ipv4-address"`
+	Routes []struct {
+		Target  string `json:"target"`
+		Mask    int    `json:"mask"`
+		Nexthop string `json:"nexthop"`
+	} `json:"route"`
+	IPv6Prefixes []struct {
+		Address   string `json:"address"`
+		Mask      int    `json:"mask"`
+		Preferred int64  `json:"preferred"`
+		Valid     int64  `json:"valid"`
+	} `json:"ipv6-prefix"`
+}
+
+// get the logical interfaces as reported by netifd
+func (c *NetworkInterfaceCollector) Interfaces(ctx context.Context) ([]UbusNetworkInterface, error) {
+	return getUbusNetworkInterfaces(ctx)
+}
+
+// get default route nexthops of the interface
+func (i *UbusNetworkInterface) gateways() []string {
+	var gateways []string
+	for _, route := range i.Routes {
+		if route.Mask != 0 || (route.Target != "0.0.0.0" && route.Target != "::") {
+			continue
+		}
+		if route.Nexthop == "" || route.Nexthop == "0.0.0.0" || route.Nexthop == "::" {
+			continue
+		}
+		gateways = append(gateways, route.Nexthop)
+	}
+	return gateways
+}
+
+// get logical interfaces from netifd
+func getUbusNetworkInterfaces(ctx context.Context) ([]UbusNetworkInterface, error) {
+	var dump struct {
+		Interface []UbusNetworkInterface `json:"interface"`
+	}
+	if err := ubusCall(ctx, "network.interface", "dump", nil, &dump); err != nil {
+		return nil, err
+	}
+	return dump.Interface, nil
+}
+
+// get neighbor table states keyed by ip address from 'ip neigh show'
+func getNeighborStatesByIP(ctx context.Context) (map[string]string, error) {
+	output, err := runCommand(ctx, "ip", "neigh", "show")
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		states[fields[0]] = fields[len(fields)-1]
+	}
+
+	return states, scanner.Err()
+}
+
+// check whether a gateway answers neighbor discovery, point-to-point links (pppoe, wireguard) have no neighbor entries
+func isGatewayReachable(device, gateway string, neighbors map[string]string) bool {
+	if link, err := net.InterfaceByName(device); err == nil && link.Flags&net.FlagPointToPoint != 0 {
+		return link.Flags&net.FlagUp != 0
+	}
+
+	state, ok := neighbors[gateway]
+	return ok && !unreachableNeighborStates[state]
+}
+
+// map layer 3 devices of up logical interfaces to the interface uptime in seconds
+func getDeviceUptimes(ctx context.Context) (map[string]float64, error) {
+	interfaces, err := getUbusNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uptimes := make(map[string]float64)
+	for _, iface := range interfaces {
+		if !iface.Up || iface.L3Device == "" {
+			continue
+		}
+
+		// a device shared by several interfaces (e.g. wan and wan6) is up since the longest running one
+		uptime := float64(iface.Uptime)
+		if current, ok := uptimes[iface.L3Device]; !ok || uptime > current {
+			uptimes[iface.L3Device] = uptime
+		}
+	}
+
+	return uptimes, nil
+}
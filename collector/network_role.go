@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// network role (firewall zone) traffic rollup collector
+type NetworkRoleCollector struct {
+	rxBytes   *prometheus.Desc
+	txBytes   *prometheus.Desc
+	rxPackets *prometheus.Desc
+	txPackets *prometheus.Desc
+}
+
+// create a new network role collector
+func NewNetworkRoleCollector() *NetworkRoleCollector {
+	return &NetworkRoleCollector{
+		rxBytes: prometheus.NewDesc(
+			"openwrt_network_role_receive_bytes_total",
+			"total number of bytes received on all interfaces of a firewall zone role",
+			[]string{"role"}, nil,
+		),
+		txBytes: prometheus.NewDesc(
+			"openwrt_network_role_transmit_bytes_total",
+			"total number of bytes transmitted on all interfaces of a firewall zone role",
+			[]string{"role"}, nil,
+		),
+		rxPackets: prometheus.NewDesc(
+			"openwrt_network_role_receive_packets_total",
+			"total number of packets received on all interfaces of a firewall zone role",
+			[]string{"role"}, nil,
+		),
+		txPackets: prometheus.NewDesc(
+			"openwrt_network_role_transmit_packets_total",
+			"total number of packets transmitted on all interfaces of a firewall zone role",
+			[]string{"role"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *NetworkRoleCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rxBytes
+	ch <- c.txBytes
+	ch <- c.rxPackets
+	ch <- c.txPackets
+}
+
+// collect implements prometheus.Collector
+func (c *NetworkRoleCollector) Collect(ch chan<- prometheus.Metric) {
+	roles, err := getDeviceRoles()
+	if err != nil {
+		logCollectError("network role", err)
+		return
+	}
+
+	interfaces, err := getNetworkInterfaces()
+	if err != nil {
+		logCollectError("network role", err)
+		return
+	}
+
+	// aggregate interface counters per role
+	totals := make(map[string]*NetworkInterface)
+	for _, iface := range interfaces {
+		role, ok := roles[iface.Name]
+		if !ok {
+			continue
+		}
+
+		total, ok := totals[role]
+		if !ok {
+			total = &NetworkInterface{Name: role}
+			totals[role] = total
+		}
+		total.RxBytes += iface.RxBytes
+		total.TxBytes += iface.TxBytes
+		total.RxPackets += iface.RxPackets
+		total.TxPackets += iface.TxPackets
+	}
+
+	for role, total := range totals {
+		ch <- prometheus.MustNewConstMetric(
+			c.rxBytes,
+			prometheus.CounterValue,
+			float64(total.RxBytes),
+			role,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.txBytes,
+			prometheus.CounterValue,
+			float64(total.TxBytes),
+			role,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.rxPackets,
+			prometheus.CounterValue,
+			float64(total.RxPackets),
+			role,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.txPackets,
+			prometheus.CounterValue,
+			float64(total.TxPackets),
+			role,
+		)
+	}
+}
+
+// map linux device names to firewall zone names using uci network and firewall config
+func getDeviceRoles() (map[string]string, error) {
+	firewall, err := loadUCIConfig("firewall")
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := loadUCIConfig("network")
+	if err != nil {
+		return nil, err
+	}
+
+	// map logical network names to linux devices
+	networkDevices := make(map[string]string)
+	for _, section := range network {
+		if section.Type != "interface" || section.Name == "" {
+			continue
+		}
+		if device := uciInterfaceDevice(&section); device != "" {
+			networkDevices[section.Name] = device
+		}
+	}
+
+	roles := make(map[string]string)
+	for _, section := range firewall {
+		if section.Type != "zone" {
+			continue
+		}
+
+		zone := section.Option("name")
+		if zone == "" {
+			continue
+		}
+
+		// explicit devices listed in the zone
+		for _, device := range section.List("device") {
+			roles[device] = zone
+		}
+
+		for _, name := range section.List("network") {
+			if device, ok := networkDevices[name]; ok {
+				roles[device] = zone
+			}
+		}
+	}
+
+	return roles, nil
+}
+
+// determine the layer 3 linux device for a uci network interface section
+func uciInterfaceDevice(section *UCISection) string {
+	switch section.Option("proto") {
+	case "pppoe", "pppoa", "pptp", "l2tp", "3g", "ppp", "6in4", "6to4", "6rd", "dslite", "map":
+		return section.Option("proto") + "-" + section.Name
+	case "wireguard":
+		return section.Name
+	}
+
+	// openwrt 21.02+ uses "device", older releases use "ifname"
+	device := section.Option("device")
+	if device == "" {
+		device = section.Option("ifname")
+	}
+
+	// legacy bridges without a device section
+	if section.Option("type") == "bridge" {
+		return "br-" + section.Name
+	}
+
+	// aliases (e.g. "@wan") share the device of another interface
+	if strings.HasPrefix(device, "@") {
+		return ""
+	}
+
+	return device
+}
@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// nftables named counter collector
+type NftablesCollector struct {
+	packets *prometheus.Desc
+	bytes   *prometheus.Desc
+}
+
+// create a new nftables collector
+func NewNftablesCollector() *NftablesCollector {
+	labels := []string{"family", "table", "name"}
+
+	return &NftablesCollector{
+		packets: prometheus.NewDesc(
+			"openwrt_nft_counter_packets_total",
+			"total number of packets counted by a named nftables counter",
+			labels, nil,
+		),
+		bytes: prometheus.NewDesc(
+			"openwrt_nft_counter_bytes_total",
+			"total number of bytes counted by a named nftables counter",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *NftablesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.packets
+	ch <- c.bytes
+}
+
+// collect implements prometheus.Collector
+func (c *NftablesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *NftablesCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	counters, err := getNftCounters(ctx)
+	if err != nil {
+		logCollectError("nftables", err)
+		return
+	}
+
+	for _, counter := range counters {
+		ch <- prometheus.MustNewConstMetric(
+			c.packets,
+			prometheus.CounterValue,
+			counter.Packets,
+			counter.Family, counter.Table, counter.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.bytes,
+			prometheus.CounterValue,
+			counter.Bytes,
+			counter.Family, counter.Table, counter.Name,
+		)
+	}
+}
+
+// named nftables counter
+type NftNamedCounter struct {
+	Family  string  `json:"family"`
+	Table   string  `json:"table"`
+	Name    string  `json:"name"`
+	Packets float64 `json:"packets"`
+	Bytes   float64 `json:"bytes"`
+}
+
+// list named counters of all tables via 'nft -j list counters'
+func getNftCounters(ctx context.Context) ([]NftNamedCounter, error) {
+	output, err := runCommand(ctx, "nft", "-j", "list", "counters")
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleset struct {
+		Nftables []struct {
+			Counter *NftNamedCounter `json:"counter"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &ruleset); err != nil {
+		return nil, err
+	}
+
+	var counters []NftNamedCounter
+	for _, item := range ruleset.Nftables {
+		if item.Counter != nil {
+			counters = append(counters, *item.Counter)
+		}
+	}
+
+	return counters, nil
+}
+
+// nftables rule with its counter, referenced named sets and verdict
+type NftRule struct {
+	Family  string
+	Table   string
+	Chain   string
+	Comment string
+	Counter *NftCounter
+	Sets    []string
+	Verdict string
+}
+
+// nftables packet and byte counter
+type NftCounter struct {
+	Packets float64 `json:"packets"`
+	Bytes   float64 `json:"bytes"`
+}
+
+// list rules of an nftables table via 'nft -j list table'
+func getNftRules(ctx context.Context, family string, table string) ([]NftRule, error) {
+	output, err := runCommand(ctx, "nft", "-j", "list", "table", family, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleset struct {
+		Nftables []struct {
+			Rule *struct {
+				Family  string                       `json:"family"`
+				Table   string                       `json:"table"`
+				Chain   string                       `json:"chain"`
+				Comment string                       `json:"comment"`
+				Expr    []map[string]json.RawMessage `json:"expr"`
+			} `json:"rule"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &ruleset); err != nil {
+		return nil, err
+	}
+
+	var rules []NftRule
+	for _, item := range ruleset.Nftables {
+		if item.Rule == nil {
+			continue
+		}
+
+		rule := NftRule{
+			Family:  item.Rule.Family,
+			Table:   item.Rule.Table,
+			Chain:   item.Rule.Chain,
+			Comment: item.Rule.Comment,
+		}
+
+		for _, expr := range item.Rule.Expr {
+			if raw, ok := expr["counter"]; ok {
+				var counter NftCounter
+				if err := json.Unmarshal(raw, &counter); err == nil {
+					rule.Counter = &counter
+				}
+			}
+
+			// set lookups look like {"match": {"right": "@setname", ...}}
+			if raw, ok := expr["match"]; ok {
+				var match struct {
+					Right any `json:"right"`
+				}
+				if err := json.Unmarshal(raw, &match); err == nil {
+					if set, ok := match.Right.(string); ok && strings.HasPrefix(set, "@") {
+						rule.Sets = append(rule.Sets, strings.TrimPrefix(set, "@"))
+					}
+				}
+			}
+
+			for _, verdict := range []string{"accept", "drop", "reject"} {
+				if _, ok := expr[verdict]; ok {
+					rule.Verdict = verdict
+				}
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// nlbwmon per-device bandwidth accounting collector
+type NlbwmonCollector struct {
+	rxBytes     *prometheus.Desc
+	rxPackets   *prometheus.Desc
+	txBytes     *prometheus.Desc
+	txPackets   *prometheus.Desc
+	connections *prometheus.Desc
+}
+
+// create a new nlbwmon collector
+func NewNlbwmonCollector() *NlbwmonCollector {
+	labels := []string{"mac"}
+
+	return &NlbwmonCollector{
+		rxBytes: prometheus.NewDesc(
+			"openwrt_nlbwmon_receive_bytes_total",
+			"total bytes received by a device in the current nlbwmon accounting period",
+			labels, nil,
+		),
+		rxPackets: prometheus.NewDesc(
+			"openwrt_nlbwmon_receive_packets_total",
+			"total packets received by a device in the current nlbwmon accounting period",
+			labels, nil,
+		),
+		txBytes: prometheus.NewDesc(
+			"openwrt_nlbwmon_transmit_bytes_total",
+			"total bytes transmitted by a device in the current nlbwmon accounting period",
+			labels, nil,
+		),
+		txPackets: prometheus.NewDesc(
+			"openwrt_nlbwmon_transmit_packets_total",
+			"total packets transmitted by a device in the current nlbwmon accounting period",
+			labels, nil,
+		),
+		connections: prometheus.NewDesc(
+			"openwrt_nlbwmon_connections_total",
+			"total connections opened by a device in the current nlbwmon accounting period",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *NlbwmonCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rxBytes
+	ch <- c.rxPackets
+	ch <- c.txBytes
+	ch <- c.txPackets
+	ch <- c.connections
+}
+
+// collect implements prometheus.Collector
+func (c *NlbwmonCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *NlbwmonCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	usages, err := getNlbwmonUsage(ctx)
+	if err != nil {
+		logCollectError("nlbwmon", err)
+		return
+	}
+
+	for _, usage := range usages {
+		mac := privateMAC(usage.MAC)
+		ch <- prometheus.MustNewConstMetric(c.rxBytes, prometheus.CounterValue, usage.RxBytes, mac)
+		ch <- prometheus.MustNewConstMetric(c.rxPackets, prometheus.CounterValue, usage.RxPackets, mac)
+		ch <- prometheus.MustNewConstMetric(c.txBytes, prometheus.CounterValue, usage.TxBytes, mac)
+		ch <- prometheus.MustNewConstMetric(c.txPackets, prometheus.CounterValue, usage.TxPackets, mac)
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.CounterValue, usage.Connections, mac)
+	}
+}
+
+// per-device usage reported by nlbwmon
+type NlbwmonUsage struct {
+	MAC         string
+	Connections float64
+	RxBytes     float64
+	RxPackets   float64
+	TxBytes     float64
+	TxPackets   float64
+}
+
+// get per-device usage from 'nlbw -c json -g mac'
+func getNlbwmonUsage(ctx context.Context) ([]NlbwmonUsage, error) {
+	output, err := runCommand(ctx, "nlbw", "-c", "json", "-g", "mac")
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, err
+	}
+
+	// locate columns by name, their order depends on the nlbw version
+	index := make(map[string]int)
+	for i, column := range response.Columns {
+		index[column] = i
+	}
+	macIndex, ok := index["mac"]
+	if !ok {
+		return nil, fmt.Errorf("nlbw output has no mac column")
+	}
+
+	number := func(row []any, column string) float64 {
+		i, ok := index[column]
+		if !ok || i >= len(row) {
+			return 0
+		}
+		value, _ := row[i].(float64)
+		return value
+	}
+
+	var usages []NlbwmonUsage
+	for _, row := range response.Data {
+		if macIndex >= len(row) {
+			continue
+		}
+		mac, _ := row[macIndex].(string)
+		if mac == "" {
+			continue
+		}
+
+		usages = append(usages, NlbwmonUsage{
+			MAC:         strings.ToLower(mac),
+			Connections: number(row, "conns"),
+			RxBytes:     number(row, "rx_bytes"),
+			RxPackets:   number(row, "rx_pkts"),
+			TxBytes:     number(row, "tx_bytes"),
+			TxPackets:   number(row, "tx_pkts"),
+		})
+	}
+
+	return usages, nil
+}
@@ -0,0 +1,268 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// default path of the state file written by the ntp hotplug script
+const defaultNTPStateFile = "/tmp/openwrt-exporter-ntp"
+
+// seconds between the ntp epoch (1900) and the unix epoch (1970)
+const ntpEpochOffset = 2208988800
+
+// stratum reported by ntp servers and daemons that are not synchronized
+const ntpUnsyncedStratum = 16
+
+// ntp synchronization collector
+type NTPCollector struct {
+	synchronized *prometheus.Desc
+	stratum      *prometheus.Desc
+	offset       *prometheus.Desc
+	lastSync     *prometheus.Desc
+	info         *prometheus.Desc
+	config       *NTPConfig
+}
+
+// ntp collector configuration
+type NTPConfig struct {
+	StateFile string
+}
+
+// create a new ntp collector
+func NewNTPCollector(config *NTPConfig) *NTPCollector {
+	return &NTPCollector{
+		synchronized: prometheus.NewDesc(
+			"openwrt_ntp_synchronized",
+			"whether the system clock is synchronized to an ntp server (1 = synchronized)",
+			nil, nil,
+		),
+		stratum: prometheus.NewDesc(
+			"openwrt_ntp_stratum",
+			"ntp stratum of the system clock (16 = unsynchronized)",
+			nil, nil,
+		),
+		offset: prometheus.NewDesc(
+			"openwrt_ntp_offset_seconds",
+			"estimated offset of the system clock from ntp time in seconds",
+			nil, nil,
+		),
+		lastSync: prometheus.NewDesc(
+			"openwrt_ntp_last_sync_timestamp_seconds",
+			"time of the last ntp update of the system clock",
+			nil, nil,
+		),
+		info: prometheus.NewDesc(
+			"openwrt_ntp_info",
+			"ntp daemon the sync state was read from and its reference server",
+			[]string{"daemon", "server"}, nil,
+		),
+		config: config,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *NTPCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.synchronized
+	ch <- c.stratum
+	ch <- c.offset
+	ch <- c.lastSync
+	ch <- c.info
+}
+
+// collect implements prometheus.Collector
+func (c *NTPCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *NTPCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	status, err := getNTPStatus(ctx, c.config.StateFile)
+	if err != nil {
+		logCollectError("ntp", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.synchronized,
+		prometheus.GaugeValue,
+		boolToFloat64(status.Synchronized),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.stratum,
+		prometheus.GaugeValue,
+		status.Stratum,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.offset,
+		prometheus.GaugeValue,
+		status.Offset,
+	)
+	if !status.LastSync.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.lastSync,
+			prometheus.GaugeValue,
+			float64(status.LastSync.Unix()),
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.info,
+		prometheus.GaugeValue,
+		1,
+		status.Daemon, status.Server,
+	)
+}
+
+// ntp synchronization state
+type NTPStatus struct {
+	Daemon       string
+	Server       string
+	Synchronized bool
+	Stratum      float64
+	Offset       float64
+	LastSync     time.Time
+}
+
+// get ntp state from chrony, ntpd or the busybox ntpd hotplug state file, whichever is available
+func getNTPStatus(ctx context.Context, stateFile string) (*NTPStatus, error) {
+	status, err := getChronyStatus(ctx)
+	if !isMissingCommand(err) {
+		return status, err
+	}
+
+	status, err = getNtpqStatus(ctx)
+	if !isMissingCommand(err) {
+		return status, err
+	}
+
+	return getSysntpdStatus(stateFile)
+}
+
+// check whether a command failed because it is not installed or not allowlisted
+func isMissingCommand(err error) bool {
+	return errors.Is(err, exec.ErrNotFound) || errors.Is(err, errCommandNotAllowed)
+}
+
+// get chrony tracking state from 'chronyc -c tracking'
+func getChronyStatus(ctx context.Context) (*NTPStatus, error) {
+	output, err := runCommand(ctx, "chronyc", "-c", "tracking")
+	if err != nil {
+		return nil, err
+	}
+
+	// fields: ref id, ref name, stratum, ref time, system time offset, last offset, ..., leap status
+	fields := strings.Split(strings.TrimSpace(string(output)), ",")
+	if len(fields) < 14 {
+		return nil, fmt.Errorf("unexpected chronyc tracking output: %q", output)
+	}
+
+	stratum, _ := strconv.ParseFloat(fields[2], 64)
+	refTime, _ := strconv.ParseFloat(fields[3], 64)
+	offset, _ := strconv.ParseFloat(fields[4], 64)
+
+	status := &NTPStatus{
+		Daemon:       "chrony",
+		Server:       fields[1],
+		Stratum:      stratum,
+		Offset:       offset,
+		Synchronized: fields[13] != "Not synchronised" && stratum > 0 && stratum < ntpUnsyncedStratum,
+	}
+	if refTime > 0 {
+		status.LastSync = time.Unix(int64(refTime), 0)
+	}
+	return status, nil
+}
+
+// get ntpd system variables from 'ntpq -c rv'
+func getNtpqStatus(ctx context.Context) (*NTPStatus, error) {
+	output, err := runCommand(ctx, "ntpq", "-c", "rv 0 leap,stratum,offset,reftime,refid")
+	if err != nil {
+		return nil, err
+	}
+
+	// output: leap=00, stratum=2, offset=-0.123, reftime=e9b1b9a1.12345678  Mon, ..., refid=192.0.2.1
+	vars := make(map[string]string)
+	for _, entry := range strings.Split(strings.ReplaceAll(string(output), "\n", ","), ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(entry), "="); ok {
+			vars[key] = strings.Trim(value, `"`)
+		}
+	}
+
+	stratum, _ := strconv.ParseFloat(vars["stratum"], 64)
+	offset, _ := strconv.ParseFloat(vars["offset"], 64)
+
+	status := &NTPStatus{
+		Daemon:       "ntpd",
+		Server:       vars["refid"],
+		Stratum:      stratum,
+		Offset:       offset / 1000,
+		Synchronized: vars["leap"] != "11" && stratum > 0 && stratum < ntpUnsyncedStratum,
+	}
+
+	// reftime is a hex ntp timestamp followed by a readable date
+	if seconds, _, ok := strings.Cut(strings.Fields(vars["reftime"] + " ")[0], "."); ok {
+		if ntpSeconds, err := strconv.ParseUint(seconds, 16, 64); err == nil && ntpSeconds > ntpEpochOffset {
+			status.LastSync = time.Unix(int64(ntpSeconds-ntpEpochOffset), 0)
+		}
+	}
+	return status, nil
+}
+
+// get busybox ntpd state from the file written by the ntp hotplug script
+// format: <unix_time>\t<action>\t<stratum>\t<offset>\t<poll_interval>
+func getSysntpdStatus(stateFile string) (*NTPStatus, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(strings.TrimRight(string(data), "\n"), "\t")
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("unexpected ntp state file format: %q", data)
+	}
+
+	updated := parseUnixTime(fields[0])
+	stratum, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		stratum = ntpUnsyncedStratum
+	}
+	offset, _ := strconv.ParseFloat(fields[3], 64)
+
+	// busybox ntpd reports every poll, so a state older than two polls means ntpd stopped
+	maxAge := time.Hour
+	if poll, err := strconv.Atoi(fields[4]); err == nil && poll > 0 {
+		maxAge = 2 * time.Duration(poll) * time.Second
+	}
+
+	status := &NTPStatus{
+		Daemon:   "sysntpd",
+		Stratum:  stratum,
+		Offset:   offset,
+		LastSync: updated,
+		Synchronized: fields[1] != "unsync" && stratum < ntpUnsyncedStratum &&
+			!updated.IsZero() && time.Since(updated) <= maxAge,
+	}
+	return status, nil
+}
+
+// load ntp configuration from environment variables
+func loadNTPConfig() *NTPConfig {
+	config := &NTPConfig{
+		StateFile: defaultNTPStateFile,
+	}
+
+	// ntp_state_file: state file written by the ntp hotplug script
+	if path := os.Getenv("NTP_STATE_FILE"); path != "" {
+		config.StateFile = path
+	}
+
+	return config
+}
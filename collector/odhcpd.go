@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// odhcpd hosts file with dhcpv6 leases
+const odhcpdLeaseFile = "/tmp/hosts/odhcpd"
+
+// dhcpv6 lease handed out by odhcpd
+type DHCPv6Lease struct {
+	Interface   string
+	DUID        string
+	IAID        string
+	Hostname    string
+	MAC         string
+	Addresses   []string
+	Prefixes    []string
+	LeaseRemain float64
+}
+
+// get dhcpv6 leases from ubus, falling back to the odhcpd hosts file
+func getDHCPv6Leases(ctx context.Context) ([]DHCPv6Lease, error) {
+	leases, err := getUbusDHCPv6Leases(ctx)
+	if err == nil {
+		return leases, nil
+	}
+	return parseOdhcpdLeaseFile(odhcpdLeaseFile)
+}
+
+// ubus 'dhcp ipv6leases' response
+type ubusIPv6Leases struct {
+	Device map[string]struct {
+		Leases []struct {
+			DUID     string `json:"duid"`
+			IAID     int64  `json:"iaid"`
+			Hostname string `json:"hostname"`
+			Valid    int64  `json:"valid"`
+			Address  []struct {
+				Address string `json:"address"`
+			} `json:"ipv6-addr"`
+			Prefix []struct {
+				Address      string `json:"address"`
+				PrefixLength int    `json:"prefix-length"`
+			} `json:"ipv6-prefix"`
+		} `json:"leases"`
+	} `json:"device"`
+}
+
+// get dhcpv6 leases from 'ubus call dhcp ipv6leases'
+func getUbusDHCPv6Leases(ctx context.Context) ([]DHCPv6Lease, error) {
+	var response ubusIPv6Leases
+	if err := ubusCall(ctx, "dhcp", "ipv6leases", nil, &response); err != nil {
+		return nil, err
+	}
+
+	var leases []DHCPv6Lease
+	for iface, device := range response.Device {
+		for _, raw := range device.Leases {
+			lease := DHCPv6Lease{
+				Interface: iface,
+				DUID:      raw.DUID,
+				IAID:      strconv.FormatInt(raw.IAID, 16),
+				Hostname:  raw.Hostname,
+				MAC:       duidMAC(raw.DUID),
+			}
+			if raw.Valid > 0 {
+				lease.LeaseRemain = float64(raw.Valid)
+			}
+			for _, address := range raw.Address {
+				lease.Addresses = append(lease.Addresses, address.Address)
+			}
+			for _, prefix := range raw.Prefix {
+				lease.Prefixes = append(lease.Prefixes, prefix.Address+"/"+strconv.Itoa(prefix.PrefixLength))
+			}
+			leases = append(leases, lease)
+		}
+	}
+
+	return leases, nil
+}
+
+// parse the odhcpd hosts file
+// lease format: # <interface> <duid> <iaid> <hostname> <valid_until> <assigned> <length> <address/length>...
+func parseOdhcpdLeaseFile(path string) ([]DHCPv6Lease, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var leases []DHCPv6Lease
+	scanner := bufio.NewScanner(file)
+	now := time.Now().Unix()
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// skip dhcpv4 leases written by newer odhcpd versions
+		if len(fields) < 8 || fields[0] != "#" || fields[3] == "ipv4" {
+			continue
+		}
+
+		hostname := fields[4]
+		if hostname == "-" {
+			hostname = ""
+		}
+
+		lease := DHCPv6Lease{
+			Interface: fields[1],
+			DUID:      fields[2],
+			IAID:      fields[3],
+			Hostname:  hostname,
+			MAC:       duidMAC(fields[2]),
+		}
+
+		validUntil, _ := strconv.ParseInt(fields[5], 10, 64)
+		if validUntil > now {
+			lease.LeaseRemain = float64(validUntil - now)
+		}
+
+		// addresses have a /128 length, delegated prefixes are shorter
+		for _, field := range fields[8:] {
+			ip, network, err := net.ParseCIDR(field)
+			if err != nil {
+				continue
+			}
+			if ones, _ := network.Mask.Size(); ones == 128 {
+				lease.Addresses = append(lease.Addresses, ip.String())
+			} else {
+				lease.Prefixes = append(lease.Prefixes, network.String())
+			}
+		}
+
+		leases = append(leases, lease)
+	}
+
+	return leases, scanner.Err()
+}
+
+// extract the ethernet mac from a duid-llt or duid-ll, if present
+func duidMAC(duid string) string {
+	raw, err := hex.DecodeString(duid)
+	if err != nil || len(raw) < 4 {
+		return ""
+	}
+
+	// duid type (2 bytes) followed by hardware type (2 bytes), 1 = ethernet
+	var mac []byte
+	switch {
+	case raw[0] == 0 && raw[1] == 1 && len(raw) == 14:
+		mac = raw[8:]
+	case raw[0] == 0 && raw[1] == 3 && len(raw) == 10:
+		mac = raw[4:]
+	default:
+		return ""
+	}
+	if raw[2] != 0 || raw[3] != 1 {
+		return ""
+	}
+
+	return net.HardwareAddr(mac).String()
+}
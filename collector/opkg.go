@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// opkg package inventory collector
+type OpkgCollector struct {
+	installed      *prometheus.Desc
+	upgradable     *prometheus.Desc
+	upgradableInfo *prometheus.Desc
+	lastCheck      *prometheus.Desc
+	config         *OpkgConfig
+
+	mu        sync.Mutex
+	inventory *OpkgInventory
+}
+
+// opkg collector configuration
+type OpkgConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// installed and upgradable packages at the time of a check
+type OpkgInventory struct {
+	Installed  int
+	Upgradable []OpkgUpgrade
+	Checked    time.Time
+}
+
+// package with a newer version in the package lists
+type OpkgUpgrade struct {
+	Package    string
+	Version    string
+	NewVersion string
+}
+
+// create a new opkg collector
+func NewOpkgCollector(config *OpkgConfig) *OpkgCollector {
+	c := &OpkgCollector{
+		installed: prometheus.NewDesc(
+			"openwrt_opkg_installed_packages",
+			"number of installed opkg packages",
+			nil, nil,
+		),
+		upgradable: prometheus.NewDesc(
+			"openwrt_opkg_upgradable_packages",
+			"number of installed opkg packages with a newer version in the package lists",
+			nil, nil,
+		),
+		upgradableInfo: prometheus.NewDesc(
+			"openwrt_opkg_upgradable_package_info",
+			"installed opkg package with a newer version in the package lists",
+			[]string{"package", "version", "new_version"}, nil,
+		),
+		lastCheck: prometheus.NewDesc(
+			"openwrt_opkg_last_check_timestamp_seconds",
+			"time of the last successful package inventory check",
+			nil, nil,
+		),
+		config: config,
+	}
+
+	if c.config.Enabled {
+		goBackground(c.check)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *OpkgCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.installed
+	ch <- c.upgradable
+	ch <- c.upgradableInfo
+	ch <- c.lastCheck
+}
+
+// collect implements prometheus.Collector
+func (c *OpkgCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	inventory := c.inventory
+	c.mu.Unlock()
+
+	if inventory == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.installed,
+		prometheus.GaugeValue,
+		float64(inventory.Installed),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.upgradable,
+		prometheus.GaugeValue,
+		float64(len(inventory.Upgradable)),
+	)
+	for _, upgrade := range inventory.Upgradable {
+		ch <- prometheus.MustNewConstMetric(
+			c.upgradableInfo,
+			prometheus.GaugeValue,
+			1,
+			upgrade.Package, upgrade.Version, upgrade.NewVersion,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.lastCheck,
+		prometheus.GaugeValue,
+		float64(inventory.Checked.Unix()),
+	)
+}
+
+// periodically refresh the package inventory, opkg is too slow to run on every scrape
+func (c *OpkgCollector) check(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for running := true; running; running = nextTick(ctx, ticker) {
+		inventory, err := getOpkgInventory(ctx)
+		if err != nil {
+			slog.Error("error checking opkg packages", "err", err)
+			continue
+		}
+
+		c.mu.Lock()
+		c.inventory = inventory
+		c.mu.Unlock()
+	}
+}
+
+// get installed and upgradable packages from opkg
+func getOpkgInventory(ctx context.Context) (*OpkgInventory, error) {
+	installed, err := runCommand(ctx, "opkg", "list-installed")
+	if err != nil {
+		return nil, err
+	}
+	upgradable, err := runCommand(ctx, "opkg", "list-upgradable")
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &OpkgInventory{Checked: time.Now()}
+
+	// format: <package> - <version>
+	scanner := bufio.NewScanner(strings.NewReader(string(installed)))
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), " - ") {
+			inventory.Installed++
+		}
+	}
+
+	// format: <package> - <installed version> - <new version>
+	scanner = bufio.NewScanner(strings.NewReader(string(upgradable)))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), " - ")
+		if len(fields) != 3 {
+			continue
+		}
+		inventory.Upgradable = append(inventory.Upgradable, OpkgUpgrade{
+			Package:    strings.TrimSpace(fields[0]),
+			Version:    strings.TrimSpace(fields[1]),
+			NewVersion: strings.TrimSpace(fields[2]),
+		})
+	}
+
+	return inventory, scanner.Err()
+}
+
+// load opkg configuration from environment variables
+func loadOpkgConfig() *OpkgConfig {
+	config := &OpkgConfig{
+		Interval: 12 * time.Hour,
+	}
+
+	// opkg_check_enabled: periodically list installed and upgradable packages
+	if enabledEnv := os.Getenv("OPKG_CHECK_ENABLED"); enabledEnv != "" {
+		if enabled, err := strconv.ParseBool(enabledEnv); err == nil {
+			config.Enabled = enabled
+		}
+	}
+
+	// opkg_check_interval: interval between package inventory checks
+	if intervalEnv := os.Getenv("OPKG_CHECK_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.Interval = interval
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// constructors of collectors that can be compiled out with build tags (nowifi, nomodem,
+// noupnp, noprobes), registered by the init functions of the group files
+var optionalCollectors = make(map[string]func(cfg *Config) prometheus.Collector)
+
+// names of the collectors of all optional groups, known in every build so scrape views naming
+// a compiled-out collector can be told apart from a misspelled one
+var optionalCollectorNames = []string{
+	"modem", "cellular",
+	"ping", "latency_segments", "failover",
+	"upnp",
+	"wireless", "wireless_survey", "roaming",
+}
+
+// configuration loaders and checks of the compiled optional groups, registered with their collectors
+var (
+	optionalDefaults   []func(cfg *Config)
+	optionalValidators []func(cfg *Config) []error
+)
+
+// create an optional collector, nil when its group was compiled out
+func newOptionalCollector(name string, cfg *Config) prometheus.Collector {
+	newCollector, ok := optionalCollectors[name]
+	if !ok {
+		return nil
+	}
+	return newCollector(cfg)
+}
+
+// check whether a collector belongs to an optional group that was compiled out of this build
+func CompiledOut(name string) bool {
+	_, compiled := optionalCollectors[name]
+	return !compiled && slices.Contains(optionalCollectorNames, name)
+}
+
+// the configuration types of the optional groups are defined in every build, so Config
+// and programs embedding the collectors do not depend on the build tags
+
+// modem scraping configuration
+type ModemConfig struct {
+	URL      string
+	Username string
+	Password string
+	Timeout  time.Duration
+	Rules    []ModemRule
+}
+
+// extraction rule for a single modem statistic, using either a json path or a regex
+type ModemRule struct {
+	Name     string
+	JSONPath []string
+	Regexp   *regexp.Regexp
+}
+
+// cellular modem configuration
+type CellularConfig struct {
+	Backend string
+	Device  string
+}
+
+// ping socket modes, privileged sends icmp over a raw socket (needs root or CAP_NET_RAW),
+// unprivileged uses an icmp datagram socket (needs the gid in net.ipv4.ping_group_range)
+const (
+	pingModeAuto         = "auto"
+	pingModePrivileged   = "privileged"
+	pingModeUnprivileged = "unprivileged"
+)
+
+// ping configuration
+type PingConfig struct {
+	Targets []PingTarget
+	// number of most recent probes the statistics are computed over
+	Count    int
+	Interval time.Duration
+	// probes without a reply within the timeout count as lost
+	Timeout time.Duration
+	// "auto" tries privileged mode and falls back to unprivileged on permission errors
+	Mode       string
+	SLOs       []PingSLO
+	SLOWindows []time.Duration
+}
+
+type IPType string
+
+const (
+	IPTypeIPv4 IPType = "IPv4"
+	IPTypeIPv6 IPType = "IPv6"
+)
+
+// ping target with IP version
+type PingTarget struct {
+	Host   string
+	IPType IPType
+	// interface (device or logical name) or source address the pings are bound to, empty to follow the routing table
+	Source string
+}
+
+// ping latency slo definition
+type PingSLO struct {
+	Target    string
+	Threshold time.Duration
+	Objective float64
+}
+
+// latency segment configuration
+type LatencySegmentConfig struct {
+	Anchor            string
+	Count             int
+	Timeout           time.Duration
+	Interval          time.Duration
+	MaxHops           int
+	DiscoveryInterval time.Duration
+}
+
+// failover probe configuration
+type FailoverConfig struct {
+	Target     string
+	Interfaces []string
+	Interval   time.Duration
+	Window     time.Duration
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// oui databases shipped by common packages, in lookup order
+var defaultOUIFiles = []string{
+	"/usr/share/nmap/nmap-mac-prefixes",
+	"/usr/share/ieee-data/oui.txt",
+	"/usr/share/wireshark/manuf",
+}
+
+// load mac vendor prefixes keyed by the uppercase 24-bit oui (e.g. "A4B1C1")
+// accepts the nmap-mac-prefixes ("A4B1C1 Vendor"), ieee oui.txt ("A4-B1-C1   (hex)  Vendor")
+// and wireshark manuf ("A4:B1:C1\tShort\tLong vendor") formats, an empty path tries the default files
+func loadOUIDatabase(path string) (map[string]string, error) {
+	paths := defaultOUIFiles
+	if path != "" {
+		paths = []string{path}
+	}
+
+	var file *os.File
+	var err error
+	for _, candidate := range paths {
+		file, err = os.Open(candidate)
+		if err == nil {
+			break
+		}
+	}
+	if file == nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	vendors := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// manuf also lists 28 and 36 bit blocks (00:1B:C5:00:00:00/36), which are skipped
+		prefix := strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(fields[0]))
+		if len(prefix) != 6 || !isHex(prefix) {
+			continue
+		}
+
+		var vendor string
+		switch {
+		case strings.Contains(line, "(hex)"):
+			_, vendor, _ = strings.Cut(line, "(hex)")
+		case strings.Contains(line, "\t"):
+			// manuf: prefix, short name, optional long name
+			vendor = strings.Split(line, "\t")[1]
+		default:
+			vendor = strings.TrimPrefix(line, fields[0])
+		}
+		vendor = strings.TrimSpace(vendor)
+		if vendor != "" {
+			vendors[prefix] = vendor
+		}
+	}
+
+	return vendors, scanner.Err()
+}
+
+// get the vendor of a mac address, "private" for locally administered (randomized) addresses
+func lookupVendor(mac string, vendors map[string]string) string {
+	prefix := strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(mac))
+	if len(prefix) < 6 || !isHex(prefix[:6]) {
+		return ""
+	}
+
+	// second least significant bit of the first octet marks locally administered addresses
+	if strings.ContainsRune("2367ABEF", rune(prefix[1])) {
+		return "private"
+	}
+	return vendors[prefix[:6]]
+}
+
+// check whether a string only contains hexadecimal digits
+func isHex(value string) bool {
+	for _, r := range value {
+		if !strings.ContainsRune("0123456789ABCDEFabcdef", r) {
+			return false
+		}
+	}
+	return true
+}
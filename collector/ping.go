@@ -1,9 +1,16 @@
+//go:build !noprobes
+
 package collector
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,45 +20,63 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// ping collector
+// how long a failed pinger waits before resolving and starting again
+const pingRetryDelay = 10 * time.Second
+
+// ping target hostname could not be resolved
+var errPingResolve = errors.New("resolving target")
+
+// ping collector, each target is pinged continuously in the background
+// and scrapes export statistics over the most recent probes
 type PingCollector struct {
 	latencyMs    *prometheus.Desc
 	packetLoss   *prometheus.Desc
 	minLatencyMs *prometheus.Desc
 	maxLatencyMs *prometheus.Desc
 	avgLatencyMs *prometheus.Desc
+	stddevMs     *prometheus.Desc
+	jitterMs     *prometheus.Desc
+	sent         *prometheus.Desc
+	received     *prometheus.Desc
+	modeInfo     *prometheus.Desc
+	resolveMs    *prometheus.Desc
+	resolveFails *prometheus.Desc
 	config       *PingConfig
-}
+	slo          *pingSLOTracker
 
-// ping configuration
-type PingConfig struct {
-	Targets     []PingTarget
-	Count       int
-	Interval    time.Duration
-	Timeout     time.Duration
-	Concurrency int
+	mu      sync.Mutex
+	targets map[PingTarget]*pingTargetState
 }
 
-type IPType string
-
-const (
-	IPTypeIPv4 IPType = "IPv4"
-	IPTypeIPv6 IPType = "IPv6"
-)
+// outcome of a single probe
+type pingProbe struct {
+	received bool
+	rtt      time.Duration
+}
 
-// ping target with IP version
-type PingTarget struct {
-	Host   string
-	IPType IPType
+// rolling state of a ping target
+type pingTargetState struct {
+	ip       string
+	probes   []pingProbe
+	inflight map[int]time.Time
+	// probes sent and answered within the timeout since the target resolved to ip
+	sent     float64
+	received float64
+	since    time.Time
+	// privileged or unprivileged
+	mode string
+	// duration of the last dns lookup and failed lookups, only tracked for hostnames
+	resolveMs       float64
+	resolveFailures float64
+	resolved        bool
 }
 
-// create a new ping collector
-func NewPingCollector() *PingCollector {
-	config := loadPingConfig()
+// create a new ping collector and start pinging the targets
+func NewPingCollector(config *PingConfig) *PingCollector {
 
-	labels := []string{"target", "ip", "ip_type"}
+	labels := []string{"target", "ip", "ip_type", "source"}
 
-	return &PingCollector{
+	c := &PingCollector{
 		latencyMs: prometheus.NewDesc(
 			"openwrt_ping_latency_ms",
 			"ping latency in milliseconds",
@@ -77,8 +102,56 @@ func NewPingCollector() *PingCollector {
 			"average ping latency in milliseconds",
 			labels, nil,
 		),
-		config: config,
+		stddevMs: prometheus.NewDesc(
+			"openwrt_ping_stddev_latency_ms",
+			"standard deviation of ping latency in milliseconds",
+			labels, nil,
+		),
+		jitterMs: prometheus.NewDesc(
+			"openwrt_ping_jitter_ms",
+			"mean absolute latency difference between consecutive ping replies in milliseconds",
+			labels, nil,
+		),
+		sent: prometheus.NewDesc(
+			"openwrt_ping_packets_sent_total",
+			"total number of ping packets sent",
+			labels, nil,
+		),
+		received: prometheus.NewDesc(
+			"openwrt_ping_packets_received_total",
+			"total number of ping packets received",
+			labels, nil,
+		),
+		modeInfo: prometheus.NewDesc(
+			"openwrt_ping_mode_info",
+			"socket mode used to ping a target (privileged = raw icmp socket, unprivileged = icmp datagram socket)",
+			append(labels, "mode"), nil,
+		),
+		resolveMs: prometheus.NewDesc(
+			"openwrt_ping_resolve_duration_ms",
+			"duration of the last dns resolution of a ping target hostname in milliseconds",
+			[]string{"target", "ip_type", "source"}, nil,
+		),
+		resolveFails: prometheus.NewDesc(
+			"openwrt_ping_resolve_failures_total",
+			"total number of failed dns resolutions of a ping target hostname",
+			[]string{"target", "ip_type", "source"}, nil,
+		),
+		config:  config,
+		slo:     newPingSLOTracker(config.SLOs, config.SLOWindows),
+		targets: make(map[PingTarget]*pingTargetState),
+	}
+
+	mode := pingModePrivileged
+	if config.Mode == pingModeUnprivileged {
+		mode = pingModeUnprivileged
+	}
+	for _, target := range config.Targets {
+		c.targets[target] = &pingTargetState{inflight: make(map[int]time.Time), mode: mode}
+		goBackground(func(ctx context.Context) { c.runTarget(ctx, target) })
 	}
+
+	return c
 }
 
 // describe implements prometheus.Collector
@@ -88,97 +161,301 @@ func (c *PingCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.minLatencyMs
 	ch <- c.maxLatencyMs
 	ch <- c.avgLatencyMs
+	ch <- c.stddevMs
+	ch <- c.jitterMs
+	ch <- c.sent
+	ch <- c.received
+	ch <- c.modeInfo
+	ch <- c.resolveMs
+	ch <- c.resolveFails
+	c.slo.describe(ch)
 }
 
 // collect implements prometheus.Collector
 func (c *PingCollector) Collect(ch chan<- prometheus.Metric) {
-	if len(c.config.Targets) == 0 {
-		return
-	}
-
-	// result struct for collecting ping results
-	type pingResultWithTarget struct {
-		target PingTarget
-		result *PingResult
-		err    error
-	}
-
-	// task channel for workers
-	tasksCh := make(chan PingTarget, len(c.config.Targets))
-
-	// results channel
-	resultsCh := make(chan pingResultWithTarget, len(c.config.Targets))
-
-	// determine worker count
-	workerCount := c.config.Concurrency
-	if workerCount > len(c.config.Targets) {
-		workerCount = len(c.config.Targets)
-	}
-
-	// start fixed number of workers
-	var wg sync.WaitGroup
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for target := range tasksCh {
-				result, err := pingTarget(target, c.config)
-				resultsCh <- pingResultWithTarget{
-					target: target,
-					result: result,
-					err:    err,
-				}
+	c.mu.Lock()
+	for target, state := range c.targets {
+		// resolution metrics stay available while the target cannot be resolved
+		if net.ParseIP(target.Host) == nil {
+			ch <- prometheus.MustNewConstMetric(
+				c.resolveFails,
+				prometheus.CounterValue,
+				state.resolveFailures,
+				target.Host, string(target.IPType), target.Source,
+			)
+			if state.resolved {
+				ch <- prometheus.MustNewConstMetric(
+					c.resolveMs,
+					prometheus.GaugeValue,
+					state.resolveMs,
+					target.Host, string(target.IPType), target.Source,
+				)
 			}
-		}()
-	}
+		}
 
-	// send tasks to workers
-	for _, target := range c.config.Targets {
-		tasksCh <- target
-	}
-	close(tasksCh)
+		if state.ip == "" {
+			continue
+		}
 
-	// close results channel when all workers are done
-	go func() {
-		wg.Wait()
-		close(resultsCh)
-	}()
+		labels := []string{target.Host, state.ip, string(target.IPType), target.Source}
 
-	// collect results and send metrics
-	for r := range resultsCh {
-		if r.err != nil {
-			log.Printf("error pinging target %s: %v", r.target.Host, r.err)
+		ch <- newCreatedCounter(c.sent, state.sent, state.since, labels...)
+		ch <- newCreatedCounter(c.received, state.received, state.since, labels...)
+		ch <- prometheus.MustNewConstMetric(c.modeInfo, prometheus.GaugeValue, 1, append(labels, state.mode)...)
+
+		result := state.result()
+		if result == nil {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.packetLoss,
+			prometheus.GaugeValue,
+			result.PacketLoss,
+			labels...,
+		)
+
+		// latency is unknown while every probe in the window is lost
+		if len(result.Rtts) == 0 {
 			continue
 		}
 
 		ch <- prometheus.MustNewConstMetric(
 			c.avgLatencyMs,
 			prometheus.GaugeValue,
-			r.result.AvgLatencyMs,
-			r.target.Host, r.result.IP, r.result.IPType,
+			result.AvgLatencyMs,
+			labels...,
 		)
 
 		ch <- prometheus.MustNewConstMetric(
 			c.minLatencyMs,
 			prometheus.GaugeValue,
-			r.result.MinLatencyMs,
-			r.target.Host, r.result.IP, r.result.IPType,
+			result.MinLatencyMs,
+			labels...,
 		)
 
 		ch <- prometheus.MustNewConstMetric(
 			c.maxLatencyMs,
 			prometheus.GaugeValue,
-			r.result.MaxLatencyMs,
-			r.target.Host, r.result.IP, r.result.IPType,
+			result.MaxLatencyMs,
+			labels...,
 		)
 
 		ch <- prometheus.MustNewConstMetric(
-			c.packetLoss,
+			c.stddevMs,
 			prometheus.GaugeValue,
-			r.result.PacketLoss,
-			r.target.Host, r.result.IP, r.result.IPType,
+			result.StdDevLatencyMs,
+			labels...,
 		)
+
+		// jitter needs two replies
+		if len(result.Rtts) > 1 {
+			ch <- prometheus.MustNewConstMetric(
+				c.jitterMs,
+				prometheus.GaugeValue,
+				result.JitterMs,
+				labels...,
+			)
+		}
+	}
+	c.mu.Unlock()
+
+	c.slo.collect(ch)
+}
+
+// continuously ping a target, resolving it again whenever the pinger stops
+func (c *PingCollector) runTarget(ctx context.Context, target PingTarget) {
+	for ctx.Err() == nil {
+		if err := c.ping(ctx, target); err != nil && ctx.Err() == nil {
+			slog.Error("error pinging target", "target", target, "err", err)
+
+			// unresolvable or unreachable targets lose the whole window
+			c.mu.Lock()
+			state := c.targets[target]
+			state.ip = ""
+			state.probes = nil
+			c.mu.Unlock()
+
+			// dns failures are exported separately and do not count as packet loss,
+			// otherwise the probes that would have been sent until the retry are lost
+			if !errors.Is(err, errPingResolve) {
+				c.slo.record(target, &PingResult{PacketsSent: max(1, int(pingRetryDelay/c.config.Interval))})
+			}
+
+			sleepContext(ctx, pingRetryDelay)
+		}
+	}
+}
+
+// ping a target for an hour or until the pinger fails, settling each probe as it is answered or times out
+func (c *PingCollector) ping(ctx context.Context, target PingTarget) error {
+	start := time.Now()
+	ip, err := resolvePingTarget(target)
+	duration := time.Since(start)
+
+	c.mu.Lock()
+	state := c.targets[target]
+	state.resolveMs = float64(duration.Microseconds()) / 1000.0
+	state.resolved = true
+	if err != nil {
+		state.resolveFailures++
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPingResolve, err)
+	}
+
+	pinger, err := probing.NewPinger(ip.String())
+	if err != nil {
+		return err
 	}
+
+	pinger.Interval = c.config.Interval
+	if err := bindPinger(ctx, pinger, target.Source); err != nil {
+		return err
+	}
+	pinger.RecordRtts = false
+	pinger.RecordTTLs = false
+
+	// restart hourly, picking up dns changes and releasing the in-flight state the pinger keeps for lost probes
+	pinger.Timeout = time.Hour
+
+	c.mu.Lock()
+	if state.ip != ip.String() {
+		state.probes = nil
+		state.sent, state.received = 0, 0
+		state.since = time.Now()
+	}
+	state.ip = ip.String()
+	state.inflight = make(map[int]time.Time)
+	mode := state.mode
+	c.mu.Unlock()
+
+	pinger.SetPrivileged(mode == pingModePrivileged)
+
+	pinger.OnSend = func(pkt *probing.Packet) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		state.inflight[pkt.Seq] = time.Now()
+		state.sent++
+	}
+	// unsent probes (wan down, no route, bound device gone) are lost right away, the pinger keeps going
+	pinger.OnSendError = func(*probing.Packet, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		state.sent++
+		c.settle(target, state, pingProbe{})
+	}
+	pinger.OnRecv = func(pkt *probing.Packet) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// replies arriving after the timeout were already counted as lost
+		if _, ok := state.inflight[pkt.Seq]; !ok || pkt.Rtt >= c.config.Timeout {
+			return
+		}
+		delete(state.inflight, pkt.Seq)
+		state.received++
+		c.settle(target, state, pingProbe{received: true, rtt: pkt.Rtt})
+	}
+
+	// probes time out on their own clock, so the window keeps moving while no probe is sent or answered
+	expiring, stopExpiring := context.WithCancel(ctx)
+	defer stopExpiring()
+	go c.expire(expiring, target, state)
+
+	err = pinger.RunWithContext(ctx)
+
+	// without CAP_NET_RAW opening the raw socket fails, retry right away with a datagram socket
+	if err != nil && errors.Is(err, os.ErrPermission) && c.config.Mode == pingModeAuto && mode == pingModePrivileged {
+		slog.Warn("no permission for privileged ping, falling back to unprivileged mode", "target", target, "err", err)
+		c.mu.Lock()
+		state.mode = pingModeUnprivileged
+		c.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// run a configured pinger in the socket mode selected by PING_MODE, for pingers that do not track the mode they ended up in;
+// privileged unless configured unprivileged, and in auto mode a missing permission for the raw socket retries with a datagram socket
+func runPinger(ctx context.Context, pinger *probing.Pinger, mode string) error {
+	pinger.SetPrivileged(mode != pingModeUnprivileged)
+	err := pinger.RunWithContext(ctx)
+
+	// the socket is opened before anything is sent, so the pinger can simply run again
+	if err != nil && errors.Is(err, os.ErrPermission) && mode == pingModeAuto {
+		slog.Debug("no permission for privileged ping, falling back to unprivileged mode", "target", pinger.Addr(), "err", err)
+		pinger.SetPrivileged(false)
+		err = pinger.RunWithContext(ctx)
+	}
+	return err
+}
+
+// settle in-flight probes of a target as lost once they are older than the timeout, until ctx is done
+func (c *PingCollector) expire(ctx context.Context, target PingTarget, state *pingTargetState) {
+	ticker := time.NewTicker(min(c.config.Interval, c.config.Timeout))
+	defer ticker.Stop()
+
+	for nextTick(ctx, ticker) {
+		now := time.Now()
+		c.mu.Lock()
+		for seq, sent := range state.inflight {
+			if now.Sub(sent) >= c.config.Timeout {
+				delete(state.inflight, seq)
+				c.settle(target, state, pingProbe{})
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// add a finished probe to the rolling window of a target, the caller must hold c.mu
+func (c *PingCollector) settle(target PingTarget, state *pingTargetState, probe pingProbe) {
+	state.probes = append(state.probes, probe)
+	if len(state.probes) > c.config.Count {
+		state.probes = state.probes[len(state.probes)-c.config.Count:]
+	}
+
+	result := &PingResult{PacketsSent: 1}
+	if probe.received {
+		result.Rtts = []time.Duration{probe.rtt}
+	}
+	c.slo.record(target, result)
+}
+
+// statistics over the rolling window of a target, nil before the first probe finished
+func (s *pingTargetState) result() *PingResult {
+	if len(s.probes) == 0 {
+		return nil
+	}
+
+	result := &PingResult{PacketsSent: len(s.probes), IP: s.ip}
+	var minRtt, maxRtt, total time.Duration
+	for _, probe := range s.probes {
+		if !probe.received {
+			continue
+		}
+		if len(result.Rtts) == 0 || probe.rtt < minRtt {
+			minRtt = probe.rtt
+		}
+		maxRtt = max(maxRtt, probe.rtt)
+		total += probe.rtt
+		result.Rtts = append(result.Rtts, probe.rtt)
+	}
+
+	result.PacketLoss = float64(len(s.probes)-len(result.Rtts)) / float64(len(s.probes)) * 100
+	if len(result.Rtts) > 0 {
+		result.MinLatencyMs = float64(minRtt.Microseconds()) / 1000.0
+		result.MaxLatencyMs = float64(maxRtt.Microseconds()) / 1000.0
+		result.AvgLatencyMs = float64((total / time.Duration(len(result.Rtts))).Microseconds()) / 1000.0
+		result.StdDevLatencyMs, result.JitterMs = rttVariation(result.Rtts)
+	}
+
+	return result
 }
 
 // ping result
@@ -186,45 +463,31 @@ type PingResult struct {
 	MinLatencyMs float64
 	MaxLatencyMs float64
 	AvgLatencyMs float64
-	PacketLoss   float64
-	IP           string
-	IPType       string
+	// population standard deviation and mean consecutive difference (rfc 3550 style jitter, unsmoothed)
+	StdDevLatencyMs float64
+	JitterMs        float64
+	PacketLoss      float64
+	PacketsSent     int
+	Rtts            []time.Duration
+	IP              string
 }
 
 // load ping configuration from environment variables
 func loadPingConfig() *PingConfig {
 	config := &PingConfig{
-		Count:       10,
-		Interval:    10 * time.Millisecond,
-		Timeout:     3 * time.Second,
-		Concurrency: 10,
-	}
-
-	// ping_targets: comma-separated list of IPv4 targets
-	targetsEnv := os.Getenv("PING_TARGETS")
-	if targetsEnv != "" {
-		targets := strings.Split(targetsEnv, ",")
-		for _, target := range targets {
-			target = strings.TrimSpace(target)
-			if target != "" {
-				config.Targets = append(config.Targets, PingTarget{Host: target, IPType: IPTypeIPv4})
-			}
-		}
+		Count:    60,
+		Interval: time.Second,
+		Timeout:  3 * time.Second,
+		Mode:     pingModeAuto,
 	}
 
-	// ping_targets_v6: comma-separated list of IPv6 targets
-	targetsV6Env := os.Getenv("PING_TARGETS_V6")
-	if targetsV6Env != "" {
-		targets := strings.Split(targetsV6Env, ",")
-		for _, target := range targets {
-			target = strings.TrimSpace(target)
-			if target != "" {
-				config.Targets = append(config.Targets, PingTarget{Host: target, IPType: IPTypeIPv6})
-			}
-		}
-	}
+	// ping_targets: comma-separated list of IPv4 targets, each optionally bound to a source (<host>@<interface or address>)
+	config.Targets = append(config.Targets, parsePingTargets(os.Getenv("PING_TARGETS"), IPTypeIPv4)...)
+
+	// ping_targets_v6: comma-separated list of IPv6 targets, with the same syntax
+	config.Targets = append(config.Targets, parsePingTargets(os.Getenv("PING_TARGETS_V6"), IPTypeIPv6)...)
 
-	// ping_count: number of ping packets to send
+	// ping_count: number of most recent probes the statistics are computed over
 	if countEnv := os.Getenv("PING_COUNT"); countEnv != "" {
 		if count, err := strconv.Atoi(countEnv); err == nil && count > 0 {
 			config.Count = count
@@ -238,28 +501,119 @@ func loadPingConfig() *PingConfig {
 		}
 	}
 
-	// ping_timeout: ping timeout in seconds
+	// ping_timeout: time after which an unanswered probe counts as lost
 	if timeoutEnv := os.Getenv("PING_TIMEOUT"); timeoutEnv != "" {
 		if timeout, err := time.ParseDuration(timeoutEnv); err == nil && timeout > 0 {
 			config.Timeout = timeout
 		}
 	}
 
-	// ping_concurrency: number of concurrent ping workers
-	if concurrencyEnv := os.Getenv("PING_CONCURRENCY"); concurrencyEnv != "" {
-		if concurrency, err := strconv.Atoi(concurrencyEnv); err == nil && concurrency > 0 {
-			config.Concurrency = concurrency
+	// ping_mode: auto, privileged or unprivileged
+	if modeEnv := os.Getenv("PING_MODE"); modeEnv != "" {
+		switch modeEnv {
+		case pingModeAuto, pingModePrivileged, pingModeUnprivileged:
+			config.Mode = modeEnv
+		default:
+			slog.Warn("invalid PING_MODE", "value", modeEnv, "using", pingModeAuto)
 		}
 	}
 
+	// ping_slos and ping_slo_windows: latency slo definitions
+	config.SLOs, config.SLOWindows = loadPingSLOs()
+
 	return config
 }
 
-// ping a target and return the result
-func pingTarget(target PingTarget, config *PingConfig) (*PingResult, error) {
+// standard deviation and jitter of round trip times in milliseconds
+func rttVariation(rtts []time.Duration) (float64, float64) {
+	if len(rtts) == 0 {
+		return 0, 0
+	}
 
-	// resolve IP address first to determine IP type
-	var resolvedIP net.IP
+	ms := make([]float64, len(rtts))
+	var mean float64
+	for i, rtt := range rtts {
+		ms[i] = float64(rtt.Microseconds()) / 1000.0
+		mean += ms[i]
+	}
+	mean /= float64(len(ms))
+
+	var variance, jitter float64
+	for i, value := range ms {
+		variance += (value - mean) * (value - mean)
+		if i > 0 {
+			jitter += math.Abs(value - ms[i-1])
+		}
+	}
+	variance /= float64(len(ms))
+	if len(ms) > 1 {
+		jitter /= float64(len(ms) - 1)
+	}
+
+	return math.Sqrt(variance), jitter
+}
+
+// string form of a target for log messages (e.g. "1.1.1.1@wan")
+func (t PingTarget) String() string {
+	if t.Source == "" {
+		return t.Host
+	}
+	return t.Host + "@" + t.Source
+}
+
+// parse a comma-separated list of <host>[@<source>] ping targets
+func parsePingTargets(list string, ipType IPType) []PingTarget {
+	var targets []PingTarget
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, source, _ := strings.Cut(entry, "@")
+		targets = append(targets, PingTarget{
+			Host:   strings.TrimSpace(host),
+			IPType: ipType,
+			Source: strings.TrimSpace(source),
+		})
+	}
+	return targets
+}
+
+// bind a pinger to a source address, a network device, or the layer 3 device of a logical interface
+func bindPinger(ctx context.Context, pinger *probing.Pinger, source string) error {
+	if source == "" {
+		return nil
+	}
+
+	if net.ParseIP(source) != nil {
+		pinger.Source = source
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join("/sys/class/net", source)); err == nil {
+		pinger.InterfaceName = source
+		return nil
+	}
+
+	// logical interfaces such as wan map to a device that may change (e.g. pppoe-wan after reconnecting)
+	interfaces, err := getUbusNetworkInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("source %s is neither an address nor a device and interfaces cannot be listed: %w", source, err)
+	}
+	for _, iface := range interfaces {
+		if iface.Interface == source {
+			if !iface.Up || iface.L3Device == "" {
+				return fmt.Errorf("interface %s is down", source)
+			}
+			pinger.InterfaceName = iface.L3Device
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown source %s", source)
+}
+
+// resolve a target to an address of its ip type
+func resolvePingTarget(target PingTarget) (net.IP, error) {
 
 	// lookup IPs
 	ips, err := net.LookupIP(target.Host)
@@ -267,6 +621,7 @@ func pingTarget(target PingTarget, config *PingConfig) (*PingResult, error) {
 		return nil, err
 	}
 
+	var resolvedIP net.IP
 	switch target.IPType {
 	case IPTypeIPv4:
 		for _, ip := range ips {
@@ -286,40 +641,8 @@ func pingTarget(target PingTarget, config *PingConfig) (*PingResult, error) {
 		return nil, &net.AddrError{Err: "unknown IP type", Addr: target.Host}
 	}
 	if resolvedIP == nil {
-		return nil, &net.AddrError{Err: "no IPv6 address found", Addr: target.Host}
-	}
-
-	// create pinger with resolved IP
-	pinger, err := probing.NewPinger(resolvedIP.String())
-	if err != nil {
-		return nil, err
-	}
-
-	// set privileged mode to true to use icmp (requires root)
-	pinger.SetPrivileged(true)
-
-	// configure ping parameters
-	pinger.Count = config.Count
-	pinger.Interval = config.Interval
-	pinger.Timeout = config.Timeout
-
-	// run ping
-	err = pinger.Run()
-	if err != nil {
-		return nil, err
-	}
-
-	// get statistics
-	stats := pinger.Statistics()
-
-	result := &PingResult{
-		PacketLoss:   stats.PacketLoss,
-		MinLatencyMs: float64(stats.MinRtt.Microseconds()) / 1000.0,
-		MaxLatencyMs: float64(stats.MaxRtt.Microseconds()) / 1000.0,
-		AvgLatencyMs: float64(stats.AvgRtt.Microseconds()) / 1000.0,
-		IP:           resolvedIP.String(),
-		IPType:       string(target.IPType),
+		return nil, &net.AddrError{Err: "no " + string(target.IPType) + " address found", Addr: target.Host}
 	}
 
-	return result, nil
+	return resolvedIP, nil
 }
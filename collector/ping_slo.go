@@ -0,0 +1,203 @@
+//go:build !noprobes
+
+package collector
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// good and total probe counts of a single ping run
+type sloSample struct {
+	time  time.Time
+	good  int
+	total int
+}
+
+// rolling slo compliance tracker for ping targets
+type pingSLOTracker struct {
+	objective  *prometheus.Desc
+	compliance *prometheus.Desc
+	burnRate   *prometheus.Desc
+	slos       map[string]PingSLO
+	windows    []time.Duration
+
+	mu      sync.Mutex
+	samples map[PingTarget][]sloSample
+}
+
+// create a new slo tracker for the configured slos and windows
+func newPingSLOTracker(slos []PingSLO, windows []time.Duration) *pingSLOTracker {
+	labels := []string{"target", "ip_type", "source"}
+
+	t := &pingSLOTracker{
+		objective: prometheus.NewDesc(
+			"openwrt_ping_slo_objective_ratio",
+			"configured ratio of ping probes that must stay below the latency threshold",
+			[]string{"target", "ip_type", "source", "threshold_ms"}, nil,
+		),
+		compliance: prometheus.NewDesc(
+			"openwrt_ping_slo_compliance_ratio",
+			"ratio of ping probes answered below the latency threshold over the window",
+			append(labels, "window"), nil,
+		),
+		burnRate: prometheus.NewDesc(
+			"openwrt_ping_slo_burn_rate",
+			"rate at which the slo error budget is consumed over the window (1 = exactly on budget)",
+			append(labels, "window"), nil,
+		),
+		slos:    make(map[string]PingSLO),
+		windows: windows,
+		samples: make(map[PingTarget][]sloSample),
+	}
+
+	for _, slo := range slos {
+		t.slos[slo.Target] = slo
+	}
+
+	return t
+}
+
+// describe slo metrics
+func (t *pingSLOTracker) describe(ch chan<- *prometheus.Desc) {
+	ch <- t.objective
+	ch <- t.compliance
+	ch <- t.burnRate
+}
+
+// record the result of a ping run for a target with an slo
+func (t *pingSLOTracker) record(target PingTarget, result *PingResult) {
+	slo, ok := t.slos[target.Host]
+	if !ok {
+		return
+	}
+
+	// lost packets count against the slo
+	sample := sloSample{time: time.Now(), total: result.PacketsSent}
+	for _, rtt := range result.Rtts {
+		if rtt < slo.Threshold {
+			sample.good++
+		}
+	}
+	if sample.total == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// drop samples older than the longest window
+	var longest time.Duration
+	for _, window := range t.windows {
+		longest = max(longest, window)
+	}
+	samples := append(t.samples[target], sample)
+	for len(samples) > 0 && sample.time.Sub(samples[0].time) > longest {
+		samples = samples[1:]
+	}
+	t.samples[target] = samples
+}
+
+// export slo compliance and burn rate for all tracked targets
+func (t *pingSLOTracker) collect(ch chan<- prometheus.Metric) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for target, samples := range t.samples {
+		slo := t.slos[target.Host]
+		ipType := string(target.IPType)
+
+		ch <- prometheus.MustNewConstMetric(
+			t.objective,
+			prometheus.GaugeValue,
+			slo.Objective,
+			target.Host, ipType, target.Source, strconv.FormatFloat(float64(slo.Threshold.Microseconds())/1000.0, 'f', -1, 64),
+		)
+
+		for _, window := range t.windows {
+			good, total := 0, 0
+			for _, sample := range samples {
+				if now.Sub(sample.time) <= window {
+					good += sample.good
+					total += sample.total
+				}
+			}
+			if total == 0 {
+				continue
+			}
+
+			compliance := float64(good) / float64(total)
+			windowLabel := formatWindow(window)
+
+			ch <- prometheus.MustNewConstMetric(
+				t.compliance,
+				prometheus.GaugeValue,
+				compliance,
+				target.Host, ipType, target.Source, windowLabel,
+			)
+
+			if slo.Objective < 1 {
+				ch <- prometheus.MustNewConstMetric(
+					t.burnRate,
+					prometheus.GaugeValue,
+					(1-compliance)/(1-slo.Objective),
+					target.Host, ipType, target.Source, windowLabel,
+				)
+			}
+		}
+	}
+}
+
+// load ping slo definitions and windows from environment variables
+func loadPingSLOs() ([]PingSLO, []time.Duration) {
+	var slos []PingSLO
+
+	// ping_slos: comma-separated list of <target>=<latency>@<objective_percent>
+	if slosEnv := os.Getenv("PING_SLOS"); slosEnv != "" {
+		for _, entry := range strings.Split(slosEnv, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			target, rest, ok1 := strings.Cut(entry, "=")
+			latency, objective, ok2 := strings.Cut(rest, "@")
+			threshold, err1 := time.ParseDuration(latency)
+			percent, err2 := strconv.ParseFloat(objective, 64)
+			if !ok1 || !ok2 || err1 != nil || err2 != nil || percent <= 0 || percent > 100 {
+				slog.Warn("invalid PING_SLOS entry", "entry", entry)
+				continue
+			}
+
+			slos = append(slos, PingSLO{
+				Target:    strings.TrimSpace(target),
+				Threshold: threshold,
+				Objective: percent / 100,
+			})
+		}
+	}
+
+	windows := []time.Duration{5 * time.Minute, time.Hour}
+
+	// ping_slo_windows: comma-separated list of rolling windows
+	if windowsEnv := os.Getenv("PING_SLO_WINDOWS"); windowsEnv != "" {
+		var parsed []time.Duration
+		for _, entry := range strings.Split(windowsEnv, ",") {
+			if window, err := time.ParseDuration(strings.TrimSpace(entry)); err == nil && window > 0 {
+				parsed = append(parsed, window)
+			}
+		}
+		if len(parsed) > 0 {
+			windows = parsed
+		}
+	}
+
+	return slos, windows
+}
@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// power over ethernet port collector
+type PoECollector struct {
+	budget      *prometheus.Desc
+	consumption *prometheus.Desc
+	enabled     *prometheus.Desc
+	power       *prometheus.Desc
+	class       *prometheus.Desc
+	info        *prometheus.Desc
+}
+
+// create a new poe collector
+func NewPoECollector() *PoECollector {
+	return &PoECollector{
+		budget: prometheus.NewDesc(
+			"openwrt_poe_budget_watts",
+			"total power budget of the poe controller in watts",
+			nil, nil,
+		),
+		consumption: prometheus.NewDesc(
+			"openwrt_poe_power_watts",
+			"total power delivered by the poe controller in watts",
+			nil, nil,
+		),
+		enabled: prometheus.NewDesc(
+			"openwrt_poe_port_enabled",
+			"whether poe is enabled on the port (1 = enabled)",
+			[]string{"port"}, nil,
+		),
+		power: prometheus.NewDesc(
+			"openwrt_poe_port_power_watts",
+			"power delivered on the poe port in watts",
+			[]string{"port"}, nil,
+		),
+		class: prometheus.NewDesc(
+			"openwrt_poe_port_class",
+			"poe class negotiated with the powered device",
+			[]string{"port"}, nil,
+		),
+		info: prometheus.NewDesc(
+			"openwrt_poe_port_info",
+			"poe mode and status of the port",
+			[]string{"port", "mode", "status"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *PoECollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.budget
+	ch <- c.consumption
+	ch <- c.enabled
+	ch <- c.power
+	ch <- c.class
+	ch <- c.info
+}
+
+// collect implements prometheus.Collector
+func (c *PoECollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *PoECollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	var status ubusPoEInfo
+	if err := ubusCall(ctx, "poe", "info", nil, &status); err != nil {
+		// most routers have no poe controller
+		logCollectError("poe", err)
+		return
+	}
+
+	if budget := jsonNumber(status.Budget); budget != nil {
+		ch <- prometheus.MustNewConstMetric(c.budget, prometheus.GaugeValue, *budget)
+	}
+	if consumption := jsonNumber(status.Consumption); consumption != nil {
+		ch <- prometheus.MustNewConstMetric(c.consumption, prometheus.GaugeValue, *consumption)
+	}
+
+	for name, port := range status.Ports {
+		ch <- prometheus.MustNewConstMetric(
+			c.enabled,
+			prometheus.GaugeValue,
+			boolToFloat64(port.Status != "Disabled"),
+			name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.info,
+			prometheus.GaugeValue,
+			1,
+			name, port.Mode, port.Status,
+		)
+
+		// consumption is only reported while power is delivered
+		power := 0.0
+		if consumption := jsonNumber(port.Consumption); consumption != nil {
+			power = *consumption
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.power,
+			prometheus.GaugeValue,
+			power,
+			name,
+		)
+		if class := jsonNumber(port.Class); class != nil {
+			ch <- prometheus.MustNewConstMetric(
+				c.class,
+				prometheus.GaugeValue,
+				*class,
+				name,
+			)
+		}
+	}
+}
+
+// ubus poe info reply (realtek-poe)
+type ubusPoEInfo struct {
+	Budget      any `json:"budget"`
+	Consumption any `json:"consumption"`
+	Ports       map[string]struct {
+		Mode        string `json:"mode"`
+		Status      string `json:"status"`
+		Consumption any    `json:"consumption"`
+		Class       any    `json:"class"`
+	} `json:"ports"`
+}
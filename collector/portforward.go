@@ -0,0 +1,200 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// port forward (fw4 dnat redirect) metrics collector
+type PortForwardCollector struct {
+	info    *prometheus.Desc
+	packets *prometheus.Desc
+	bytes   *prometheus.Desc
+}
+
+// create a new port forward collector
+func NewPortForwardCollector() *PortForwardCollector {
+	return &PortForwardCollector{
+		info: prometheus.NewDesc(
+			"openwrt_port_forward_info",
+			"configured port forward rule with its protocols, external port and internal destination",
+			[]string{"section", "name", "proto", "external_port", "destination_ip", "destination_port"}, nil,
+		),
+		packets: prometheus.NewDesc(
+			"openwrt_port_forward_packets_total",
+			"total number of packets matched by a port forward rule",
+			[]string{"section", "name"}, nil,
+		),
+		bytes: prometheus.NewDesc(
+			"openwrt_port_forward_bytes_total",
+			"total number of bytes matched by a port forward rule",
+			[]string{"section", "name"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *PortForwardCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.packets
+	ch <- c.bytes
+}
+
+// collect implements prometheus.Collector
+func (c *PortForwardCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *PortForwardCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	forwards, err := getPortForwards(ctx)
+	if err != nil {
+		logCollectError("port forward", err)
+		return
+	}
+
+	for _, forward := range forwards {
+		ch <- prometheus.MustNewConstMetric(
+			c.info,
+			prometheus.GaugeValue,
+			1,
+			forward.Section, forward.Name, forward.Proto, forward.ExternalPort, privateIP(forward.DestIP), forward.DestPort,
+		)
+		if !forward.Counted {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.packets,
+			prometheus.CounterValue,
+			forward.Packets,
+			forward.Section, forward.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.bytes,
+			prometheus.CounterValue,
+			forward.Bytes,
+			forward.Section, forward.Name,
+		)
+	}
+}
+
+// port forward information
+type PortForward struct {
+	// uci section index as @redirect[N], unique unlike the name
+	Section      string
+	Name         string
+	Proto        string
+	ExternalPort string
+	DestIP       string
+	DestPort     string
+	Packets      float64
+	Bytes        float64
+	// whether the counters belong to this redirect, see getPortForwards
+	Counted bool
+}
+
+// get configured dnat redirects and their nft counters
+func getPortForwards(ctx context.Context) ([]PortForward, error) {
+	sections, err := loadUCIConfig("firewall")
+	if err != nil {
+		return nil, err
+	}
+
+	var forwards []PortForward
+	index := 0
+	for _, section := range sections {
+		if section.Type != "redirect" {
+			continue
+		}
+
+		// fw4 names unnamed redirects after their section index
+		ref := fmt.Sprintf("@redirect[%d]", index)
+		name := section.Option("name")
+		if name == "" {
+			name = ref
+		}
+		index++
+
+		if section.Option("enabled") == "0" {
+			continue
+		}
+		if target := section.Option("target"); target != "" && target != "DNAT" {
+			continue
+		}
+
+		// fw4 defaults: both tcp and udp, internal port equal to the external port
+		protos := section.List("proto")
+		if len(protos) == 0 {
+			protos = []string{"tcp", "udp"}
+		}
+		destPort := section.Option("dest_port")
+		if destPort == "" {
+			destPort = section.Option("src_dport")
+		}
+
+		forwards = append(forwards, PortForward{
+			Section:      ref,
+			Name:         name,
+			Proto:        strings.Join(protos, ","),
+			ExternalPort: section.Option("src_dport"),
+			DestIP:       section.Option("dest_ip"),
+			DestPort:     destPort,
+		})
+	}
+
+	if len(forwards) == 0 {
+		return nil, nil
+	}
+
+	rules, err := getNftRules(ctx, "inet", "fw4")
+	if err != nil {
+		return nil, err
+	}
+
+	// sum counters of all dstnat rules generated for each redirect
+	type counter struct {
+		packets, bytes float64
+		claimed        bool
+	}
+	counters := make(map[string]*counter)
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.Chain, "dstnat") || rule.Counter == nil {
+			continue
+		}
+
+		name, ok := strings.CutPrefix(rule.Comment, "!fw4: ")
+		if !ok {
+			continue
+		}
+
+		total, ok := counters[name]
+		if !ok {
+			total = &counter{}
+			counters[name] = total
+		}
+		total.packets += rule.Counter.Packets
+		total.bytes += rule.Counter.Bytes
+	}
+
+	// rules are matched by the name in their comment, so redirects sharing a name share one counter;
+	// it is reported for the first of them only to not count the traffic twice
+	for i := range forwards {
+		total, ok := counters[forwards[i].Name]
+		if !ok {
+			forwards[i].Counted = true
+			continue
+		}
+		if total.claimed {
+			continue
+		}
+		total.claimed = true
+		forwards[i].Packets = total.packets
+		forwards[i].Bytes = total.bytes
+		forwards[i].Counted = true
+	}
+
+	return forwards, nil
+}
@@ -0,0 +1,337 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// neighbor states that prove a device answered recently
+var presentNeighborStates = map[string]bool{
+	"REACHABLE": true,
+	"DELAY":     true,
+	"PROBE":     true,
+	"PERMANENT": true,
+}
+
+// device presence collector with arrival and departure hysteresis
+type PresenceCollector struct {
+	present    *prometheus.Desc
+	arrivals   *prometheus.Desc
+	departures *prometheus.Desc
+	config     *PresenceConfig
+
+	mu      sync.Mutex
+	devices map[string]*presenceState
+	expiry  map[string]int64
+}
+
+// presence configuration
+type PresenceConfig struct {
+	Enabled     bool
+	Interval    time.Duration
+	ArriveGrace time.Duration
+	AwayGrace   time.Duration
+}
+
+// presence state of a single mac address
+type presenceState struct {
+	present     bool
+	streakStart time.Time
+	lastSeen    time.Time
+	arrivals    float64
+	departures  float64
+}
+
+// create a new presence collector
+func NewPresenceCollector(config *PresenceConfig) *PresenceCollector {
+	c := &PresenceCollector{
+		present: prometheus.NewDesc(
+			"openwrt_device_present",
+			"whether a device is considered present after applying grace periods (1 = present)",
+			[]string{"mac"}, nil,
+		),
+		arrivals: prometheus.NewDesc(
+			"openwrt_device_arrivals_total",
+			"total number of times a device became present",
+			[]string{"mac"}, nil,
+		),
+		departures: prometheus.NewDesc(
+			"openwrt_device_departures_total",
+			"total number of times a device became absent",
+			[]string{"mac"}, nil,
+		),
+		config:  config,
+		devices: make(map[string]*presenceState),
+		expiry:  make(map[string]int64),
+	}
+
+	if c.config.Enabled {
+		goBackground(c.poll)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *PresenceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.present
+	ch <- c.arrivals
+	ch <- c.departures
+}
+
+// collect implements prometheus.Collector
+func (c *PresenceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for mac, state := range c.devices {
+		mac := privateMAC(mac)
+		ch <- prometheus.MustNewConstMetric(
+			c.present,
+			prometheus.GaugeValue,
+			boolToFloat64(state.present),
+			mac,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.arrivals,
+			prometheus.CounterValue,
+			state.arrivals,
+			mac,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.departures,
+			prometheus.CounterValue,
+			state.departures,
+			mac,
+		)
+	}
+}
+
+// periodically gather presence signals and advance the per-device state machine
+func (c *PresenceCollector) poll(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for running := true; running; running = nextTick(ctx, ticker) {
+		seen := c.getSeenMACs(ctx)
+		now := time.Now()
+
+		c.mu.Lock()
+		for mac := range seen {
+			state, ok := c.devices[mac]
+			if !ok {
+				state = &presenceState{}
+				c.devices[mac] = state
+			}
+
+			// a gap longer than the poll interval starts a new streak
+			if now.Sub(state.lastSeen) > 2*c.config.Interval {
+				state.streakStart = now
+			}
+			state.lastSeen = now
+
+			if !state.present && now.Sub(state.streakStart) >= c.config.ArriveGrace {
+				state.present = true
+				state.arrivals++
+			}
+		}
+
+		for mac, state := range c.devices {
+			if seen[mac] {
+				continue
+			}
+			away := now.Sub(state.lastSeen)
+			if state.present && away >= c.config.AwayGrace {
+				state.present = false
+				state.departures++
+			}
+
+			// forget devices that have been gone for a day
+			if !state.present && away >= 24*time.Hour {
+				delete(c.devices, mac)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// get macs seen by any presence signal since the previous poll
+func (c *PresenceCollector) getSeenMACs(ctx context.Context) map[string]bool {
+	seen := make(map[string]bool)
+
+	// dhcp: only a lease renewal proves the device is around, not the lease itself
+	leases, err := parseDHCPLeases()
+	if err != nil {
+		logCollectError("presence", err, "source", "dhcp leases")
+	}
+	now := time.Now().Unix()
+	expiry := make(map[string]int64)
+	for _, lease := range leases {
+		mac := strings.ToLower(lease.MAC)
+		expiry[mac] = now + int64(lease.LeaseRemain)
+		if last, ok := c.expiry[mac]; !ok || expiry[mac] > last+60 {
+			seen[mac] = true
+		}
+	}
+	// the first poll has no renewal history, so do not treat every lease as seen
+	if len(c.expiry) == 0 {
+		seen = make(map[string]bool)
+	}
+	c.expiry = expiry
+
+	// arp/ndp: only confirmed neighbor entries, stale entries linger for minutes
+	neighbors, err := getNeighborStates(ctx)
+	if err != nil {
+		logCollectError("presence", err, "source", "neighbor table")
+	}
+	for mac, state := range neighbors {
+		if presentNeighborStates[state] {
+			seen[mac] = true
+		}
+	}
+
+	// wi-fi association
+	stations, err := getWirelessStations(ctx)
+	if err != nil {
+		logCollectError("presence", err, "source", "wireless stations")
+	}
+	for _, station := range stations {
+		seen[station.MAC] = true
+	}
+
+	return seen
+}
+
+// get the most recent neighbor state per lowercase mac from 'ip neigh show'
+func getNeighborStates(ctx context.Context) (map[string]string, error) {
+	output, err := runCommand(ctx, "ip", "neigh", "show")
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// format: <ip> dev <interface> lladdr <mac> [router] <state>
+		mac := ""
+		for i, field := range fields {
+			if field == "lladdr" && i+1 < len(fields) {
+				mac = strings.ToLower(fields[i+1])
+				break
+			}
+		}
+		if mac == "" {
+			continue
+		}
+
+		// a mac with several addresses is present if any of them is
+		state := fields[len(fields)-1]
+		if !presentNeighborStates[states[mac]] {
+			states[mac] = state
+		}
+	}
+
+	return states, scanner.Err()
+}
+
+// wireless station associated with an access point interface
+type WirelessStation struct {
+	Interface string
+	MAC       string
+	// signal strength in dBm, 0 if not reported
+	Signal int
+	// seconds since the station associated
+	ConnectedTime float64
+}
+
+// get associated stations of all wireless interfaces from 'iw dev <if> station dump'
+func getWirelessStations(ctx context.Context) ([]WirelessStation, error) {
+	interfaces, err := getWirelessInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var stations []WirelessStation
+	for _, iface := range interfaces {
+		output, err := runCommand(ctx, "iw", "dev", iface, "station", "dump")
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(string(output)))
+		for scanner.Scan() {
+			// format: Station <mac> (on <interface>)
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "Station" {
+				stations = append(stations, WirelessStation{
+					Interface: iface,
+					MAC:       strings.ToLower(fields[1]),
+				})
+				continue
+			}
+
+			// format: signal: -52 [-55, -54] dBm (combined signal, then per chain)
+			if len(fields) >= 2 && fields[0] == "signal:" && len(stations) > 0 {
+				if signal, err := strconv.Atoi(fields[1]); err == nil {
+					stations[len(stations)-1].Signal = signal
+				}
+			}
+
+			// format: connected time: 3600 seconds
+			if len(fields) >= 3 && fields[0] == "connected" && fields[1] == "time:" && len(stations) > 0 {
+				if seconds, err := strconv.ParseFloat(fields[2], 64); err == nil {
+					stations[len(stations)-1].ConnectedTime = seconds
+				}
+			}
+		}
+	}
+
+	return stations, nil
+}
+
+// load presence configuration from environment variables
+func loadPresenceConfig() *PresenceConfig {
+	config := &PresenceConfig{
+		Interval:  10 * time.Second,
+		AwayGrace: 5 * time.Minute,
+	}
+
+	// presence_enabled: track device presence in the background
+	if enabledEnv := os.Getenv("PRESENCE_ENABLED"); enabledEnv != "" {
+		if enabled, err := strconv.ParseBool(enabledEnv); err == nil {
+			config.Enabled = enabled
+		}
+	}
+
+	// presence_interval: interval between presence polls
+	if intervalEnv := os.Getenv("PRESENCE_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.Interval = interval
+		}
+	}
+
+	// presence_arrive_grace: how long a device must be seen continuously before it is present
+	if graceEnv := os.Getenv("PRESENCE_ARRIVE_GRACE"); graceEnv != "" {
+		if grace, err := time.ParseDuration(graceEnv); err == nil && grace >= 0 {
+			config.ArriveGrace = grace
+		}
+	}
+
+	// presence_away_grace: how long a device must be unseen before it is absent
+	if graceEnv := os.Getenv("PRESENCE_AWAY_GRACE"); graceEnv != "" {
+		if grace, err := time.ParseDuration(graceEnv); err == nil && grace >= 0 {
+			config.AwayGrace = grace
+		}
+	}
+
+	return config
+}
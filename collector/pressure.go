@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// resources reported by pressure stall information (psi)
+var pressureResources = []string{"cpu", "io", "memory"}
+
+// averaging windows of the psi stall percentages
+var pressureWindows = map[string]string{
+	"avg10":  "10s",
+	"avg60":  "60s",
+	"avg300": "300s",
+}
+
+// pressure stall information collector
+type PressureCollector struct {
+	stallPercent *prometheus.Desc
+	stallTime    *prometheus.Desc
+}
+
+// create a new pressure collector
+func NewPressureCollector() *PressureCollector {
+	return &PressureCollector{
+		stallPercent: prometheus.NewDesc(
+			"openwrt_pressure_stall_percent",
+			"share of wall time in which some or all tasks were stalled on a resource, averaged over a window",
+			[]string{"resource", "kind", "window"}, nil,
+		),
+		stallTime: prometheus.NewDesc(
+			"openwrt_pressure_stalled_seconds_total",
+			"total time in which some or all tasks were stalled on a resource in seconds",
+			[]string{"resource", "kind"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *PressureCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.stallPercent
+	ch <- c.stallTime
+}
+
+// collect implements prometheus.Collector
+func (c *PressureCollector) Collect(ch chan<- prometheus.Metric) {
+	// stall totals start at boot, a missing boot time only drops the created timestamps
+	bootTime, _ := getBootTime()
+
+	for _, resource := range pressureResources {
+		stalls, err := getPressureStalls(resource)
+		if err != nil {
+			// kernels without CONFIG_PSI have no /proc/pressure
+			logCollectError("pressure", err)
+			continue
+		}
+
+		for _, stall := range stalls {
+			for window, percent := range stall.Percent {
+				ch <- prometheus.MustNewConstMetric(
+					c.stallPercent,
+					prometheus.GaugeValue,
+					percent,
+					resource, stall.Kind, window,
+				)
+			}
+			ch <- newCreatedCounter(
+				c.stallTime,
+				stall.Total,
+				bootTime,
+				resource, stall.Kind,
+			)
+		}
+	}
+}
+
+// stall state of a resource for either some or all (full) tasks
+type PressureStall struct {
+	Kind    string
+	Percent map[string]float64
+	Total   float64
+}
+
+// get the stall states of a resource from /proc/pressure/<resource>
+// format: some avg10=0.00 avg60=0.00 avg300=0.00 total=<microseconds>
+func getPressureStalls(resource string) ([]PressureStall, error) {
+	file, err := os.Open(filepath.Join("/proc/pressure", resource))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var stalls []PressureStall
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		stall := PressureStall{Kind: fields[0], Percent: make(map[string]float64)}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			if key == "total" {
+				stall.Total = number / 1e6
+			} else if window, ok := pressureWindows[key]; ok {
+				stall.Percent[window] = number
+			}
+		}
+		stalls = append(stalls, stall)
+	}
+
+	return stalls, scanner.Err()
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// privacy configuration for client mac and ip labels
+type PrivacyConfig struct {
+	// "" exports labels as-is, "hash" replaces macs and ips with salted hashes,
+	// "truncate" keeps the vendor (oui) part of macs and hashes the rest
+	Mode string
+	// salt mixed into hashes, so they cannot be reversed by hashing all addresses
+	Salt string
+}
+
+var (
+	privacyConfig     *PrivacyConfig
+	privacyConfigOnce sync.Once
+
+	// salt used when none is configured, hashes then change on every restart
+	randomSalt     string
+	randomSaltOnce sync.Once
+)
+
+// get the privacy configuration, loading it from environment variables on first use
+func getPrivacyConfig() *PrivacyConfig {
+	privacyConfigOnce.Do(func() {
+		privacyConfig = loadPrivacyConfig()
+	})
+	return privacyConfig
+}
+
+// replace the privacy configuration used by all collectors (nil keeps the current one)
+func setPrivacyConfig(config *PrivacyConfig) {
+	if config == nil {
+		return
+	}
+	privacyConfigOnce.Do(func() {})
+	privacyConfig = config
+}
+
+// check whether a privacy mode is supported
+func ValidPrivacyMode(mode string) bool {
+	return mode == "" || mode == "hash" || mode == "truncate"
+}
+
+// salted sha-256 digest of a label value
+func privacyDigest(config *PrivacyConfig, value string) []byte {
+	salt := config.Salt
+	if salt == "" {
+		randomSaltOnce.Do(func() {
+			slog.Warn("PRIVACY_SALT is not set, anonymized labels change on every restart")
+			buf := make([]byte, 16)
+			_, _ = rand.Read(buf)
+			randomSalt = hex.EncodeToString(buf)
+		})
+		salt = randomSalt
+	}
+
+	digest := sha256.Sum256([]byte(salt + "|" + strings.ToLower(value)))
+	return digest[:]
+}
+
+// mac address label according to the privacy mode, hashed macs keep the mac format
+// and are marked locally administered so they never resolve to a real vendor
+func privateMAC(mac string) string {
+	config := getPrivacyConfig()
+	if config.Mode == "" || mac == "" {
+		return mac
+	}
+
+	digest := privacyDigest(config, mac)
+	octets := make([]byte, 6)
+	copy(octets, digest)
+	octets[0] = octets[0]&^0x01 | 0x02
+
+	// truncate keeps the first three octets identifying the vendor
+	if config.Mode == "truncate" {
+		if hw, err := parseMAC(mac); err == nil {
+			copy(octets, hw[:3])
+		}
+	}
+
+	parts := make([]string, len(octets))
+	for i, octet := range octets {
+		parts[i] = fmt.Sprintf("%02x", octet)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ip address (or other client identifier) label according to the privacy mode
+func privateIP(ip string) string {
+	config := getPrivacyConfig()
+	if config.Mode == "" || ip == "" {
+		return ip
+	}
+	return hex.EncodeToString(privacyDigest(config, normalizeIP(ip))[:8])
+}
+
+// parse a colon or dash separated 48-bit mac address
+func parseMAC(mac string) ([]byte, error) {
+	hw, err := hex.DecodeString(strings.NewReplacer(":", "", "-", "").Replace(mac))
+	if err != nil {
+		return nil, err
+	}
+	if len(hw) != 6 {
+		return nil, fmt.Errorf("invalid mac address %q", mac)
+	}
+	return hw, nil
+}
+
+// load privacy configuration from environment variables
+func loadPrivacyConfig() *PrivacyConfig {
+	config := &PrivacyConfig{
+		Mode: os.Getenv("PRIVACY_MODE"),
+		// privacy_salt: secret mixed into label hashes
+		Salt: os.Getenv("PRIVACY_SALT"),
+	}
+
+	// privacy_mode: "hash" or "truncate" to anonymize client mac and ip labels
+	if !ValidPrivacyMode(config.Mode) {
+		slog.Warn("invalid PRIVACY_MODE, exporting labels as-is", "value", config.Mode)
+		config.Mode = ""
+	}
+
+	return config
+}
@@ -0,0 +1,188 @@
+//go:build !noprobes
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// number of echo requests sent by a ping probe
+const probePingCount = 3
+
+// probe modules available on the /probe endpoint
+var ProbeModules = []string{"ping", "tcp", "http"}
+
+// ad-hoc probe of a single target, run on every collect (blackbox exporter semantics)
+type ProbeCollector struct {
+	success        *prometheus.Desc
+	duration       *prometheus.Desc
+	pingRttMs      *prometheus.Desc
+	pingLoss       *prometheus.Desc
+	httpStatusCode *prometheus.Desc
+	module         string
+	target         string
+	timeout        time.Duration
+	pingMode       string
+}
+
+// create a new probe collector for a module and target
+func NewProbeCollector(module, target string, timeout time.Duration, ping *PingConfig) (*ProbeCollector, error) {
+	if !slices.Contains(ProbeModules, module) {
+		return nil, fmt.Errorf("unknown module %q", module)
+	}
+	if target == "" {
+		return nil, errors.New("target is required")
+	}
+
+	return &ProbeCollector{
+		success: prometheus.NewDesc(
+			"openwrt_probe_success",
+			"whether the probe succeeded (1 = success)",
+			nil, nil,
+		),
+		duration: prometheus.NewDesc(
+			"openwrt_probe_duration_seconds",
+			"time the probe took in seconds",
+			nil, nil,
+		),
+		pingRttMs: prometheus.NewDesc(
+			"openwrt_probe_ping_rtt_ms",
+			"average round trip time of the ping probe in milliseconds",
+			nil, nil,
+		),
+		pingLoss: prometheus.NewDesc(
+			"openwrt_probe_ping_packet_loss_percent",
+			"packet loss of the ping probe",
+			nil, nil,
+		),
+		httpStatusCode: prometheus.NewDesc(
+			"openwrt_probe_http_status_code",
+			"status code of the http probe response",
+			nil, nil,
+		),
+		module:   module,
+		target:   target,
+		timeout:  timeout,
+		pingMode: ping.Mode,
+	}, nil
+}
+
+// describe implements prometheus.Collector
+func (c *ProbeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.success
+	ch <- c.duration
+	ch <- c.pingRttMs
+	ch <- c.pingLoss
+	ch <- c.httpStatusCode
+}
+
+// collect implements prometheus.Collector
+func (c *ProbeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector, the probe is aborted once ctx is done,
+// e.g. when the client of the probe request goes away
+func (c *ProbeCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	start := time.Now()
+
+	var err error
+	switch c.module {
+	case "ping":
+		err = c.probePing(ctx, ch)
+	case "tcp":
+		err = c.probeTCP(ctx)
+	case "http":
+		err = c.probeHTTP(ctx, ch)
+	}
+	if err != nil {
+		slog.Debug("probe failed", "module", c.module, "target", c.target, "err", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, time.Since(start).Seconds())
+	ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, boolToFloat64(err == nil))
+}
+
+// ping the target, succeeding if at least one reply arrives
+func (c *ProbeCollector) probePing(ctx context.Context, ch chan<- prometheus.Metric) error {
+	stats, err := c.ping(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.pingLoss, prometheus.GaugeValue, stats.PacketLoss)
+	if stats.PacketsRecv == 0 {
+		return fmt.Errorf("no reply to %d echo requests", stats.PacketsSent)
+	}
+	ch <- prometheus.MustNewConstMetric(c.pingRttMs, prometheus.GaugeValue, float64(stats.AvgRtt.Microseconds())/1000.0)
+	return nil
+}
+
+// send the probe echo requests and wait for the replies until the timeout,
+// in the same socket mode as the ping collector
+func (c *ProbeCollector) ping(ctx context.Context) (*probing.Statistics, error) {
+	pinger, err := probing.NewPinger(c.target)
+	if err != nil {
+		return nil, err
+	}
+	pinger.Count = probePingCount
+	pinger.Interval = 100 * time.Millisecond
+	pinger.Timeout = c.timeout
+
+	if err := runPinger(ctx, pinger, c.pingMode); err != nil {
+		return nil, err
+	}
+	return pinger.Statistics(), nil
+}
+
+// open a tcp connection to the target (host:port)
+func (c *ProbeCollector) probeTCP(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// fetch the target url, succeeding on a 2xx or 3xx response
+func (c *ProbeCollector) probeHTTP(ctx context.Context, ch chan<- prometheus.Metric) error {
+	target := c.target
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	// redirects are not followed, so the status reflects the target itself
+	client := &http.Client{
+		Timeout: c.timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	ch <- prometheus.MustNewConstMetric(c.httpStatusCode, prometheus.GaugeValue, float64(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
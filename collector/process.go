@@ -0,0 +1,258 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clock ticks per second of the cpu times in /proc/<pid>/stat (USER_HZ, 100 on all linux architectures)
+const userHZ = 100
+
+// process count and per-daemon resource usage collector
+type ProcessCollector struct {
+	processes *prometheus.Desc
+	threads   *prometheus.Desc
+	instances *prometheus.Desc
+	cpu       *prometheus.Desc
+	rss       *prometheus.Desc
+	fds       *prometheus.Desc
+	fdsAlloc  *prometheus.Desc
+	fdsMax    *prometheus.Desc
+	config    *ProcessConfig
+}
+
+// process collector configuration
+type ProcessConfig struct {
+	Names []string
+}
+
+// create a new process collector
+func NewProcessCollector(config *ProcessConfig) *ProcessCollector {
+	return &ProcessCollector{
+		processes: prometheus.NewDesc(
+			"openwrt_processes",
+			"number of processes",
+			nil, nil,
+		),
+		threads: prometheus.NewDesc(
+			"openwrt_threads",
+			"number of threads of all processes",
+			nil, nil,
+		),
+		instances: prometheus.NewDesc(
+			"openwrt_process_instances",
+			"number of running processes with this name",
+			[]string{"process"}, nil,
+		),
+		cpu: prometheus.NewDesc(
+			"openwrt_process_cpu_seconds_total",
+			"total user and system cpu time of the running processes with this name in seconds",
+			[]string{"process"}, nil,
+		),
+		rss: prometheus.NewDesc(
+			"openwrt_process_resident_memory_bytes",
+			"resident memory of the running processes with this name in bytes",
+			[]string{"process"}, nil,
+		),
+		fds: prometheus.NewDesc(
+			"openwrt_process_open_fds",
+			"number of open file descriptors of the running processes with this name",
+			[]string{"process"}, nil,
+		),
+		fdsAlloc: prometheus.NewDesc(
+			"openwrt_file_descriptors_allocated",
+			"number of allocated file handles of all processes",
+			nil, nil,
+		),
+		fdsMax: prometheus.NewDesc(
+			"openwrt_file_descriptors_maximum",
+			"maximum number of file handles the kernel allocates (fs.file-max)",
+			nil, nil,
+		),
+		config: config,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ProcessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.processes
+	ch <- c.threads
+	ch <- c.instances
+	ch <- c.cpu
+	ch <- c.rss
+	ch <- c.fds
+	ch <- c.fdsAlloc
+	ch <- c.fdsMax
+}
+
+// collect implements prometheus.Collector
+func (c *ProcessCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := getProcessStats()
+	if err != nil {
+		logCollectError("process", err)
+		return
+	}
+
+	var threads float64
+	usage := make(map[string]*ProcessStat)
+	for _, name := range c.config.Names {
+		usage[name] = &ProcessStat{Name: name}
+	}
+	for _, stat := range stats {
+		threads += stat.Threads
+		if total, ok := usage[stat.Name]; ok {
+			total.Instances++
+			total.CPUSeconds += stat.CPUSeconds
+			total.RSSBytes += stat.RSSBytes
+			total.OpenFDs += countOpenFDs(stat.PID)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.processes,
+		prometheus.GaugeValue,
+		float64(len(stats)),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.threads,
+		prometheus.GaugeValue,
+		threads,
+	)
+
+	for _, total := range usage {
+		ch <- prometheus.MustNewConstMetric(
+			c.instances,
+			prometheus.GaugeValue,
+			total.Instances,
+			total.Name,
+		)
+		if total.Instances == 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.cpu,
+			prometheus.CounterValue,
+			total.CPUSeconds,
+			total.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.rss,
+			prometheus.GaugeValue,
+			total.RSSBytes,
+			total.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.fds,
+			prometheus.GaugeValue,
+			total.OpenFDs,
+			total.Name,
+		)
+	}
+
+	// format: <allocated> <free (always 0)> <maximum>
+	fileNr := strings.Fields(readSysfsString("/proc/sys/fs/file-nr"))
+	if len(fileNr) == 3 {
+		allocated, _ := strconv.ParseFloat(fileNr[0], 64)
+		maximum, _ := strconv.ParseFloat(fileNr[2], 64)
+		ch <- prometheus.MustNewConstMetric(
+			c.fdsAlloc,
+			prometheus.GaugeValue,
+			allocated,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.fdsMax,
+			prometheus.GaugeValue,
+			maximum,
+		)
+	}
+}
+
+// resource usage of a process, or the sum over all processes with the same name
+type ProcessStat struct {
+	PID        string
+	Name       string
+	Instances  float64
+	Threads    float64
+	CPUSeconds float64
+	RSSBytes   float64
+	OpenFDs    float64
+}
+
+// get resource usage of all processes from /proc/<pid>/stat
+func getProcessStats() ([]ProcessStat, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := float64(os.Getpagesize())
+	var stats []ProcessStat
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			// process exited while scanning
+			continue
+		}
+
+		// format: <pid> (<comm>) <state> <ppid> ..., comm may contain spaces and parentheses
+		line := string(data)
+		start := strings.IndexByte(line, '(')
+		end := strings.LastIndexByte(line, ')')
+		if start < 0 || end < start {
+			continue
+		}
+		fields := strings.Fields(line[end+1:])
+		if len(fields) < 22 {
+			continue
+		}
+
+		// fields after comm start at field 3 (state): utime 14, stime 15, num_threads 20, rss 24
+		utime, _ := strconv.ParseFloat(fields[11], 64)
+		stime, _ := strconv.ParseFloat(fields[12], 64)
+		threads, _ := strconv.ParseFloat(fields[17], 64)
+		rss, _ := strconv.ParseFloat(fields[21], 64)
+
+		stats = append(stats, ProcessStat{
+			PID:        entry.Name(),
+			Name:       line[start+1 : end],
+			Instances:  1,
+			Threads:    threads,
+			CPUSeconds: (utime + stime) / userHZ,
+			RSSBytes:   rss * pageSize,
+		})
+	}
+
+	return stats, nil
+}
+
+// count the open file descriptors of a process
+func countOpenFDs(pid string) float64 {
+	fds, err := os.ReadDir(filepath.Join("/proc", pid, "fd"))
+	if err != nil {
+		return 0
+	}
+	return float64(len(fds))
+}
+
+// load process configuration from environment variables
+func loadProcessConfig() *ProcessConfig {
+	config := &ProcessConfig{}
+
+	// process_names: comma-separated list of process names to export cpu and memory usage for
+	if namesEnv := os.Getenv("PROCESS_NAMES"); namesEnv != "" {
+		for _, name := range strings.Split(namesEnv, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.Names = append(config.Names, name)
+			}
+		}
+	}
+
+	return config
+}
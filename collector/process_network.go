@@ -0,0 +1,302 @@
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sock_diag netlink constants (linux/sock_diag.h, linux/inet_diag.h)
+const (
+	sockDiagByFamily  = 20
+	inetDiagInfo      = 2
+	inetDiagReqV2Len  = 56
+	inetDiagMsgLen    = 72
+	tcpInfoBytesAcked = 120
+	tcpInfoBytesRecv  = 128
+)
+
+// per-process network usage collector for router-local daemons
+type ProcessNetworkCollector struct {
+	sockets   *prometheus.Desc
+	txBytes   *prometheus.Desc
+	rxBytes   *prometheus.Desc
+	localTx   *prometheus.Desc
+	localRx   *prometheus.Desc
+	processes []string
+	enabled   bool
+
+	mu     sync.Mutex
+	last   map[uint32]tcpSocketBytes
+	totals map[string]*tcpSocketBytes
+	local  tcpSocketBytes
+	// byte totals are summed from the exporter start
+	created time.Time
+}
+
+// process network collector configuration
+type ProcessNetworkConfig struct {
+	Processes []string
+}
+
+// cumulative tcp bytes of a socket
+type tcpSocketBytes struct {
+	tx float64
+	rx float64
+}
+
+// create a new process network collector
+func NewProcessNetworkCollector(config *ProcessNetworkConfig) *ProcessNetworkCollector {
+	c := &ProcessNetworkCollector{
+		sockets: prometheus.NewDesc(
+			"openwrt_process_sockets",
+			"number of open sockets of a router-local process",
+			[]string{"process"}, nil,
+		),
+		txBytes: prometheus.NewDesc(
+			"openwrt_process_tcp_transmit_bytes_total",
+			"total tcp bytes sent and acknowledged by a router-local process",
+			[]string{"process"}, nil,
+		),
+		rxBytes: prometheus.NewDesc(
+			"openwrt_process_tcp_receive_bytes_total",
+			"total tcp bytes received by a router-local process",
+			[]string{"process"}, nil,
+		),
+		localTx: prometheus.NewDesc(
+			"openwrt_local_tcp_transmit_bytes_total",
+			"total tcp bytes sent by all router-local sockets (router-originated, not forwarded traffic)",
+			nil, nil,
+		),
+		localRx: prometheus.NewDesc(
+			"openwrt_local_tcp_receive_bytes_total",
+			"total tcp bytes received by all router-local sockets (router-originated, not forwarded traffic)",
+			nil, nil,
+		),
+		processes: config.Processes,
+		enabled:   len(config.Processes) > 0,
+		last:      make(map[uint32]tcpSocketBytes),
+		totals:    make(map[string]*tcpSocketBytes),
+		created:   time.Now(),
+	}
+	for _, process := range config.Processes {
+		c.totals[process] = &tcpSocketBytes{}
+	}
+
+	return c
+}
+
+// load process network configuration from environment variables
+func loadProcessNetworkConfig() *ProcessNetworkConfig {
+	config := &ProcessNetworkConfig{}
+
+	// process_network_names: comma-separated list of process names to account
+	if namesEnv := os.Getenv("PROCESS_NETWORK_NAMES"); namesEnv != "" {
+		for _, name := range strings.Split(namesEnv, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.Processes = append(config.Processes, name)
+			}
+		}
+	}
+
+	return config
+}
+
+// describe implements prometheus.Collector
+func (c *ProcessNetworkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sockets
+	ch <- c.txBytes
+	ch <- c.rxBytes
+	ch <- c.localTx
+	ch <- c.localRx
+}
+
+// collect implements prometheus.Collector
+func (c *ProcessNetworkCollector) Collect(ch chan<- prometheus.Metric) {
+	if !c.enabled {
+		return
+	}
+
+	sockets, err := getTCPSocketBytes()
+	if err != nil {
+		logCollectError("process network", err)
+		return
+	}
+	owners, socketCounts := getProcessSocketInodes(c.processes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// add the growth of every socket since the previous scrape, so closed sockets keep counting
+	for inode, current := range sockets {
+		previous := c.last[inode]
+		delta := tcpSocketBytes{tx: current.tx - previous.tx, rx: current.rx - previous.rx}
+		if delta.tx < 0 || delta.rx < 0 {
+			// inode reused by a new socket
+			delta = current
+		}
+
+		c.local.tx += delta.tx
+		c.local.rx += delta.rx
+		if process, ok := owners[inode]; ok {
+			c.totals[process].tx += delta.tx
+			c.totals[process].rx += delta.rx
+		}
+	}
+	c.last = sockets
+
+	for _, process := range c.processes {
+		ch <- prometheus.MustNewConstMetric(c.sockets, prometheus.GaugeValue, socketCounts[process], process)
+		ch <- newCreatedCounter(c.txBytes, c.totals[process].tx, c.created, process)
+		ch <- newCreatedCounter(c.rxBytes, c.totals[process].rx, c.created, process)
+	}
+	ch <- newCreatedCounter(c.localTx, c.local.tx, c.created)
+	ch <- newCreatedCounter(c.localRx, c.local.rx, c.created)
+}
+
+// map socket inodes of the named processes to the process name and count their sockets
+func getProcessSocketInodes(processes []string) (map[uint32]string, map[string]float64) {
+	owners := make(map[uint32]string)
+	counts := make(map[string]float64)
+
+	wanted := make(map[string]bool, len(processes))
+	for _, process := range processes {
+		wanted[process] = true
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return owners, counts
+	}
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		comm := readSysfsString(filepath.Join("/proc", entry.Name(), "comm"))
+		if !wanted[comm] {
+			continue
+		}
+
+		fdDir := filepath.Join("/proc", entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			// socket fds link to socket:[<inode>]
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			counts[comm]++
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 32)
+			if err == nil {
+				owners[uint32(inode)] = comm
+			}
+		}
+	}
+
+	return owners, counts
+}
+
+// get cumulative tcp bytes per socket inode from sock_diag netlink
+func getTCPSocketBytes() (map[uint32]tcpSocketBytes, error) {
+	sockets := make(map[uint32]tcpSocketBytes)
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		if err := dumpTCPSockets(family, sockets); err != nil {
+			return nil, err
+		}
+	}
+	return sockets, nil
+}
+
+// dump all tcp sockets of an address family with their tcp_info
+func dumpTCPSockets(family uint8, sockets map[uint32]tcpSocketBytes) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_INET_DIAG)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = syscall.Close(fd) }()
+
+	// nlmsghdr followed by inet_diag_req_v2
+	request := make([]byte, syscall.NLMSG_HDRLEN+inetDiagReqV2Len)
+	binary.NativeEndian.PutUint32(request[0:4], uint32(len(request)))
+	binary.NativeEndian.PutUint16(request[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(request[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	request[16] = family
+	request[17] = syscall.IPPROTO_TCP
+	request[18] = 1 << (inetDiagInfo - 1)
+	binary.NativeEndian.PutUint32(request[20:24], 0xffffffff)
+
+	if err := syscall.Sendto(fd, request, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		messages, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			switch message.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				return fmt.Errorf("sock_diag request failed")
+			}
+			if len(message.Data) < inetDiagMsgLen {
+				continue
+			}
+
+			inode := binary.NativeEndian.Uint32(message.Data[68:72])
+			if inode == 0 {
+				continue
+			}
+
+			info := findRtAttr(message.Data[inetDiagMsgLen:], inetDiagInfo)
+			if len(info) < tcpInfoBytesRecv+8 {
+				continue
+			}
+			sockets[inode] = tcpSocketBytes{
+				tx: float64(binary.NativeEndian.Uint64(info[tcpInfoBytesAcked:])),
+				rx: float64(binary.NativeEndian.Uint64(info[tcpInfoBytesRecv:])),
+			}
+		}
+	}
+}
+
+// find the payload of a netlink attribute by type
+func findRtAttr(data []byte, attrType uint16) []byte {
+	for len(data) >= syscall.SizeofRtAttr {
+		length := int(binary.NativeEndian.Uint16(data[0:2]))
+		if length < syscall.SizeofRtAttr || length > len(data) {
+			return nil
+		}
+		if binary.NativeEndian.Uint16(data[2:4]) == attrType {
+			return data[syscall.SizeofRtAttr:length]
+		}
+
+		// attributes are aligned to 4 bytes
+		aligned := (length + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if aligned > len(data) {
+			return nil
+		}
+		data = data[aligned:]
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// valid prometheus metric and label names
+	metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// unix socket push metrics collector
+type PushCollector struct {
+	config *PushConfig
+
+	mu      sync.Mutex
+	metrics map[string]*pushedMetric
+	labels  map[string][]string
+}
+
+// push collector configuration
+type PushConfig struct {
+	SocketPath string
+	TTL        time.Duration
+	MaxSeries  int
+}
+
+// metric sample received over the push socket
+type pushedMetric struct {
+	name        string
+	labelNames  []string
+	labelValues []string
+	value       float64
+	expires     time.Time
+}
+
+// create a new push collector
+func NewPushCollector(config *PushConfig) *PushCollector {
+	c := &PushCollector{
+		config:  config,
+		metrics: make(map[string]*pushedMetric),
+		labels:  make(map[string][]string),
+	}
+
+	if c.config.SocketPath != "" {
+		goBackground(c.listen)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+// pushed metrics are dynamic, so the collector is unchecked
+func (c *PushCollector) Describe(_ chan<- *prometheus.Desc) {}
+
+// collect implements prometheus.Collector
+func (c *PushCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.expire(now)
+
+	for _, m := range c.metrics {
+		desc := prometheus.NewDesc(m.name, "metric pushed via unix socket", m.labelNames, nil)
+		metric, err := prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.value, m.labelValues...)
+		if err != nil {
+			slog.Error("error exporting pushed metric", "metric", m.name, "err", err)
+			continue
+		}
+		ch <- metric
+	}
+}
+
+// drop expired samples and forget label sets of metric names without samples
+func (c *PushCollector) expire(now time.Time) {
+	names := make(map[string]bool)
+	for key, m := range c.metrics {
+		if now.After(m.expires) {
+			delete(c.metrics, key)
+			continue
+		}
+		names[m.name] = true
+	}
+	for name := range c.labels {
+		if !names[name] {
+			delete(c.labels, name)
+		}
+	}
+}
+
+// listen on the unix socket and accept push connections until ctx is cancelled
+func (c *PushCollector) listen(ctx context.Context) {
+	// remove stale socket from a previous run
+	_ = os.Remove(c.config.SocketPath)
+
+	listener, err := net.Listen("unix", c.config.SocketPath)
+	if err != nil {
+		slog.Error("error listening on push socket", "path", c.config.SocketPath, "err", err)
+		return
+	}
+	if err := os.Chmod(c.config.SocketPath, 0660); err != nil {
+		slog.Warn("failed to set push socket permissions", "err", err)
+	}
+
+	slog.Info("accepting pushed metrics", "path", c.config.SocketPath)
+
+	// closing the listener ends accept, and also removes the socket file
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("error accepting push connection", "err", err)
+			sleepContext(ctx, time.Second)
+			continue
+		}
+		go c.handle(conn)
+	}
+}
+
+// read metric lines from a push connection and reply with ok or an error per line
+func (c *PushCollector) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		reply := "ok\n"
+		if err := c.ingest(line); err != nil {
+			reply = fmt.Sprintf("error: %v\n", err)
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// validate and cache a single "<name> <value> [label=value ...]" line
+func (c *PushCollector) ingest(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return fmt.Errorf("expected \"<name> <value> [label=value ...]\"")
+	}
+
+	name := fields[0]
+	if !metricNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid metric name %q", name)
+	}
+
+	// built-in metric names are reserved to avoid breaking the exposition
+	if strings.HasPrefix(name, "openwrt_") {
+		return fmt.Errorf("metric name prefix \"openwrt_\" is reserved")
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", fields[1])
+	}
+
+	labels := make(map[string]string)
+	for _, field := range fields[2:] {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok || !labelNameRegexp.MatchString(key) {
+			return fmt.Errorf("invalid label %q", field)
+		}
+		labels[key] = strings.Trim(val, `"`)
+	}
+
+	labelNames := make([]string, 0, len(labels))
+	for key := range labels {
+		labelNames = append(labelNames, key)
+	}
+	sort.Strings(labelNames)
+
+	labelValues := make([]string, len(labelNames))
+	for i, key := range labelNames {
+		labelValues[i] = labels[key]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// all samples of a metric must share the same label names
+	if existing, ok := c.labels[name]; ok && strings.Join(existing, ",") != strings.Join(labelNames, ",") {
+		return fmt.Errorf("label names %v do not match existing %v for %s", labelNames, existing, name)
+	}
+
+	key := name + "{" + strings.Join(labelValues, "\xff") + "}"
+	if _, ok := c.metrics[key]; !ok && len(c.metrics) >= c.config.MaxSeries {
+		c.expire(time.Now())
+		if len(c.metrics) >= c.config.MaxSeries {
+			return fmt.Errorf("series limit %d reached", c.config.MaxSeries)
+		}
+	}
+
+	c.labels[name] = labelNames
+	c.metrics[key] = &pushedMetric{
+		name:        name,
+		labelNames:  labelNames,
+		labelValues: labelValues,
+		value:       value,
+		expires:     time.Now().Add(c.config.TTL),
+	}
+
+	return nil
+}
+
+// load push collector configuration from environment variables
+func loadPushConfig() *PushConfig {
+	config := &PushConfig{
+		TTL:       5 * time.Minute,
+		MaxSeries: 1000,
+	}
+
+	// push_socket: unix socket path for pushed metrics (disabled when empty)
+	config.SocketPath = os.Getenv("PUSH_SOCKET")
+
+	// push_ttl: how long pushed samples are exported after the last update
+	if ttlEnv := os.Getenv("PUSH_TTL"); ttlEnv != "" {
+		if ttl, err := time.ParseDuration(ttlEnv); err == nil && ttl > 0 {
+			config.TTL = ttl
+		}
+	}
+
+	// push_max_series: maximum number of cached pushed series
+	if maxEnv := os.Getenv("PUSH_MAX_SERIES"); maxEnv != "" {
+		if maxSeries, err := strconv.Atoi(maxEnv); err == nil && maxSeries > 0 {
+			config.MaxSeries = maxSeries
+		}
+	}
+
+	return config
+}
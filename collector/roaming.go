@@ -0,0 +1,205 @@
+//go:build !nowifi
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// roaming controller (usteer or dawn) collector
+type RoamingCollector struct {
+	nodeInfo   *prometheus.Desc
+	clients    *prometheus.Desc
+	load       *prometheus.Desc
+	roamSource *prometheus.Desc
+	roamTarget *prometheus.Desc
+}
+
+// create a new roaming collector
+func NewRoamingCollector() *RoamingCollector {
+	labels := []string{"controller", "node"}
+
+	return &RoamingCollector{
+		nodeInfo: prometheus.NewDesc(
+			"openwrt_roaming_node_info",
+			"access point node known to the roaming controller",
+			[]string{"controller", "node", "bssid", "ssid", "frequency"}, nil,
+		),
+		clients: prometheus.NewDesc(
+			"openwrt_roaming_node_clients",
+			"number of clients associated with an access point node",
+			labels, nil,
+		),
+		load: prometheus.NewDesc(
+			"openwrt_roaming_node_channel_load_percent",
+			"channel load reported for an access point node in percent",
+			labels, nil,
+		),
+		roamSource: prometheus.NewDesc(
+			"openwrt_roaming_node_roam_source_events_total",
+			"total number of roam events in which clients left this node, including steering suggestions (usteer only)",
+			labels, nil,
+		),
+		roamTarget: prometheus.NewDesc(
+			"openwrt_roaming_node_roam_target_events_total",
+			"total number of roam events in which clients transitioned to this node (usteer only)",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *RoamingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.nodeInfo
+	ch <- c.clients
+	ch <- c.load
+	ch <- c.roamSource
+	ch <- c.roamTarget
+}
+
+// collect implements prometheus.Collector
+func (c *RoamingCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *RoamingCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	nodes, err := getUsteerNodes(ctx)
+	if errors.Is(err, errUbusNotFound) {
+		nodes, err = getDawnNodes(ctx)
+	}
+	if err != nil {
+		// neither usteer nor dawn running is the normal case on single-ap setups
+		logCollectError("roaming", err)
+		return
+	}
+
+	for _, node := range nodes {
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeInfo,
+			prometheus.GaugeValue,
+			1,
+			node.Controller, node.Name, node.BSSID, node.SSID, strconv.Itoa(node.Frequency),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.clients,
+			prometheus.GaugeValue,
+			node.Clients,
+			node.Controller, node.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.load,
+			prometheus.GaugeValue,
+			node.Load,
+			node.Controller, node.Name,
+		)
+		if node.Controller == "usteer" {
+			ch <- prometheus.MustNewConstMetric(
+				c.roamSource,
+				prometheus.CounterValue,
+				node.RoamSource,
+				node.Controller, node.Name,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.roamTarget,
+				prometheus.CounterValue,
+				node.RoamTarget,
+				node.Controller, node.Name,
+			)
+		}
+	}
+}
+
+// access point node of a roaming controller
+type RoamingNode struct {
+	Controller string
+	Name       string
+	BSSID      string
+	SSID       string
+	Frequency  int
+	Clients    float64
+	Load       float64
+	RoamSource float64
+	RoamTarget float64
+}
+
+// usteer node from 'ubus call usteer local_info' and 'remote_info'
+type usteerNode struct {
+	BSSID      string  `json:"bssid"`
+	SSID       string  `json:"ssid"`
+	Freq       int     `json:"freq"`
+	NAssoc     float64 `json:"n_assoc"`
+	Load       float64 `json:"load"`
+	RoamEvents struct {
+		Source float64 `json:"source"`
+		Target float64 `json:"target"`
+	} `json:"roam_events"`
+}
+
+// get local and remote usteer nodes (remote nodes are named <ip>#<interface>)
+func getUsteerNodes(ctx context.Context) ([]RoamingNode, error) {
+	var nodes []RoamingNode
+	for _, method := range []string{"local_info", "remote_info"} {
+		var response map[string]usteerNode
+		if err := ubusCall(ctx, "usteer", method, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for name, node := range response {
+			nodes = append(nodes, RoamingNode{
+				Controller: "usteer",
+				Name:       name,
+				BSSID:      node.BSSID,
+				SSID:       node.SSID,
+				Frequency:  node.Freq,
+				Clients:    node.NAssoc,
+				Load:       node.Load,
+				RoamSource: node.RoamEvents.Source,
+				RoamTarget: node.RoamEvents.Target,
+			})
+		}
+	}
+	return nodes, nil
+}
+
+// get dawn access points from 'ubus call dawn get_network' (grouped by ssid, then bssid)
+func getDawnNodes(ctx context.Context) ([]RoamingNode, error) {
+	var response map[string]map[string]any
+	if err := ubusCall(ctx, "dawn", "get_network", nil, &response); err != nil {
+		return nil, err
+	}
+
+	var nodes []RoamingNode
+	for ssid, accessPoints := range response {
+		for bssid, value := range accessPoints {
+			ap, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			node := RoamingNode{
+				Controller: "dawn",
+				Name:       bssid,
+				BSSID:      bssid,
+				SSID:       ssid,
+			}
+			if freq := jsonNumber(ap["freq"]); freq != nil {
+				node.Frequency = int(*freq)
+			}
+			if clients := jsonNumber(ap["num_sta"]); clients != nil {
+				node.Clients = *clients
+			}
+
+			// dawn reports channel utilization on the 0-255 scale of the bss load element
+			if utilization := jsonNumber(ap["channel_utilization"]); utilization != nil {
+				node.Load = *utilization * 100 / 255
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
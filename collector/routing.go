@@ -0,0 +1,248 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// table name mapping used by iproute2
+const rtTablesFile = "/etc/iproute2/rt_tables"
+
+// policy routing collector
+type RoutingCollector struct {
+	rules     *prometheus.Desc
+	routes    *prometheus.Desc
+	pbrActive *prometheus.Desc
+	pbrTables []string
+}
+
+// routing collector configuration
+type RoutingConfig struct {
+	PBRTables []string
+}
+
+// create a new routing collector
+func NewRoutingCollector(config *RoutingConfig) *RoutingCollector {
+	return &RoutingCollector{
+		rules: prometheus.NewDesc(
+			"openwrt_ip_rules",
+			"number of policy routing rules",
+			[]string{"family"}, nil,
+		),
+		routes: prometheus.NewDesc(
+			"openwrt_routing_table_routes",
+			"number of routes in a routing table",
+			[]string{"family", "table"}, nil,
+		),
+		pbrActive: prometheus.NewDesc(
+			"openwrt_pbr_table_active",
+			"whether a policy routing table has routes and is referenced by an ip rule (1 = active)",
+			[]string{"table"}, nil,
+		),
+		pbrTables: config.PBRTables,
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *RoutingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rules
+	ch <- c.routes
+	ch <- c.pbrActive
+}
+
+// collect implements prometheus.Collector
+func (c *RoutingCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *RoutingCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	names := loadRoutingTableNames()
+
+	// tables referenced by rules and route counts per table, over both families
+	lookups := make(map[string]bool)
+	routes := make(map[string]float64)
+
+	for _, family := range []string{"inet", "inet6"} {
+		flag := "-4"
+		if family == "inet6" {
+			flag = "-6"
+		}
+
+		rules, err := getIPRuleLookups(ctx, flag, names)
+		if err != nil {
+			logCollectError("routing", err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.rules,
+			prometheus.GaugeValue,
+			float64(len(rules)),
+			family,
+		)
+		for _, table := range rules {
+			lookups[table] = true
+		}
+
+		counts, err := getRouteCounts(ctx, flag, names)
+		if err != nil {
+			logCollectError("routing", err)
+			return
+		}
+		for table, count := range counts {
+			routes[table] += count
+			ch <- prometheus.MustNewConstMetric(
+				c.routes,
+				prometheus.GaugeValue,
+				count,
+				family, table,
+			)
+		}
+	}
+
+	for _, table := range c.expectedPBRTables(names) {
+		ch <- prometheus.MustNewConstMetric(
+			c.pbrActive,
+			prometheus.GaugeValue,
+			boolToFloat64(routes[table] > 0 && lookups[table]),
+			table,
+		)
+	}
+}
+
+// load routing configuration from environment variables
+func loadRoutingConfig() *RoutingConfig {
+	config := &RoutingConfig{}
+
+	// pbr_tables: comma-separated list of policy routing tables expected to be in use
+	if tablesEnv := os.Getenv("PBR_TABLES"); tablesEnv != "" {
+		for _, table := range strings.Split(tablesEnv, ",") {
+			if table = strings.TrimSpace(table); table != "" {
+				config.PBRTables = append(config.PBRTables, table)
+			}
+		}
+	}
+
+	return config
+}
+
+// get configured pbr tables, or the pbr package tables (pbr_*) when pbr is enabled
+func (c *RoutingCollector) expectedPBRTables(names map[string]string) []string {
+	if len(c.pbrTables) > 0 {
+		return c.pbrTables
+	}
+
+	sections, err := loadUCIConfig("pbr")
+	if err != nil {
+		return nil
+	}
+	enabled := false
+	for _, section := range sections {
+		if section.Type == "pbr" && section.Option("enabled") == "1" {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil
+	}
+
+	var tables []string
+	for _, name := range names {
+		if strings.HasPrefix(name, "pbr_") {
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
+// load table id to name mapping from /etc/iproute2/rt_tables
+func loadRoutingTableNames() map[string]string {
+	names := map[string]string{"253": "default", "254": "main", "255": "local"}
+
+	file, err := os.Open(rtTablesFile)
+	if err != nil {
+		return names
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		names[fields[0]] = fields[1]
+	}
+
+	return names
+}
+
+// resolve a numeric table id to its name
+func routingTableName(table string, names map[string]string) string {
+	if name, ok := names[table]; ok {
+		return name
+	}
+	return table
+}
+
+// get the table looked up by each ip rule ("" for rules without a lookup)
+func getIPRuleLookups(ctx context.Context, flag string, names map[string]string) ([]string, error) {
+	output, err := runCommand(ctx, "ip", flag, "rule", "show")
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		// format: <priority>: from <selector> ... lookup <table>
+		table := ""
+		for i, field := range fields {
+			if (field == "lookup" || field == "table") && i+1 < len(fields) {
+				table = routingTableName(fields[i+1], names)
+			}
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, scanner.Err()
+}
+
+// count routes per table from 'ip route show table all'
+func getRouteCounts(ctx context.Context, flag string, names map[string]string) (map[string]float64, error) {
+	output, err := runCommand(ctx, "ip", flag, "route", "show", "table", "all")
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// continuation lines of multipath routes start with whitespace
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		table := "main"
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "table" && i+1 < len(fields) {
+				table = routingTableName(fields[i+1], names)
+			}
+		}
+		counts[table]++
+	}
+
+	return counts, scanner.Err()
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// socket usage collector for diagnosing socket exhaustion
+type SockstatCollector struct {
+	used         *prometheus.Desc
+	tcpInUse     *prometheus.Desc
+	tcpOrphan    *prometheus.Desc
+	tcpTimeWait  *prometheus.Desc
+	tcpAlloc     *prometheus.Desc
+	tcpMemory    *prometheus.Desc
+	tcpMemoryMax *prometheus.Desc
+	udpInUse     *prometheus.Desc
+	udpMemory    *prometheus.Desc
+}
+
+// create a new sockstat collector
+func NewSockstatCollector() *SockstatCollector {
+	return &SockstatCollector{
+		used: prometheus.NewDesc(
+			"openwrt_sockets_used",
+			"number of sockets in use",
+			nil, nil,
+		),
+		tcpInUse: prometheus.NewDesc(
+			"openwrt_tcp_sockets_in_use",
+			"number of tcp sockets in use",
+			[]string{"family"}, nil,
+		),
+		tcpOrphan: prometheus.NewDesc(
+			"openwrt_tcp_sockets_orphaned",
+			"number of tcp sockets no longer attached to a process",
+			nil, nil,
+		),
+		tcpTimeWait: prometheus.NewDesc(
+			"openwrt_tcp_sockets_time_wait",
+			"number of tcp sockets in time-wait state",
+			nil, nil,
+		),
+		tcpAlloc: prometheus.NewDesc(
+			"openwrt_tcp_sockets_allocated",
+			"number of allocated tcp sockets",
+			nil, nil,
+		),
+		tcpMemory: prometheus.NewDesc(
+			"openwrt_tcp_memory_bytes",
+			"memory used by tcp socket buffers in bytes",
+			nil, nil,
+		),
+		tcpMemoryMax: prometheus.NewDesc(
+			"openwrt_tcp_memory_limit_bytes",
+			"tcp socket buffer memory above which new allocations fail (net.ipv4.tcp_mem) in bytes",
+			nil, nil,
+		),
+		udpInUse: prometheus.NewDesc(
+			"openwrt_udp_sockets_in_use",
+			"number of udp sockets in use",
+			[]string{"family"}, nil,
+		),
+		udpMemory: prometheus.NewDesc(
+			"openwrt_udp_memory_bytes",
+			"memory used by udp socket buffers in bytes",
+			nil, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *SockstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.used
+	ch <- c.tcpInUse
+	ch <- c.tcpOrphan
+	ch <- c.tcpTimeWait
+	ch <- c.tcpAlloc
+	ch <- c.tcpMemory
+	ch <- c.tcpMemoryMax
+	ch <- c.udpInUse
+	ch <- c.udpMemory
+}
+
+// collect implements prometheus.Collector
+func (c *SockstatCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := getSockstat("/proc/net/sockstat")
+	if err != nil {
+		logCollectError("sockstat", err)
+		return
+	}
+
+	// ipv6 sockets, missing on kernels without ipv6
+	stats6, err := getSockstat("/proc/net/sockstat6")
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to read /proc/net/sockstat6", "err", err)
+	}
+
+	// socket memory is counted in pages
+	pageSize := float64(os.Getpagesize())
+
+	gauges := []struct {
+		desc  *prometheus.Desc
+		value float64
+		ok    bool
+	}{
+		{c.used, stats["sockets"]["used"], stats["sockets"] != nil},
+		{c.tcpOrphan, stats["TCP"]["orphan"], stats["TCP"] != nil},
+		{c.tcpTimeWait, stats["TCP"]["tw"], stats["TCP"] != nil},
+		{c.tcpAlloc, stats["TCP"]["alloc"], stats["TCP"] != nil},
+		{c.tcpMemory, stats["TCP"]["mem"] * pageSize, stats["TCP"] != nil},
+		{c.udpMemory, stats["UDP"]["mem"] * pageSize, stats["UDP"] != nil},
+	}
+	for _, gauge := range gauges {
+		if !gauge.ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			gauge.desc,
+			prometheus.GaugeValue,
+			gauge.value,
+		)
+	}
+
+	families := []struct {
+		name  string
+		stats map[string]map[string]float64
+		tcp   string
+		udp   string
+	}{
+		{"inet", stats, "TCP", "UDP"},
+		{"inet6", stats6, "TCP6", "UDP6"},
+	}
+	for _, family := range families {
+		if tcp, ok := family.stats[family.tcp]; ok {
+			ch <- prometheus.MustNewConstMetric(
+				c.tcpInUse,
+				prometheus.GaugeValue,
+				tcp["inuse"],
+				family.name,
+			)
+		}
+		if udp, ok := family.stats[family.udp]; ok {
+			ch <- prometheus.MustNewConstMetric(
+				c.udpInUse,
+				prometheus.GaugeValue,
+				udp["inuse"],
+				family.name,
+			)
+		}
+	}
+
+	// format: <min> <pressure> <max> in pages
+	if tcpMem := strings.Fields(readSysfsString("/proc/sys/net/ipv4/tcp_mem")); len(tcpMem) == 3 {
+		if limit, err := strconv.ParseFloat(tcpMem[2], 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(
+				c.tcpMemoryMax,
+				prometheus.GaugeValue,
+				limit*pageSize,
+			)
+		}
+	}
+}
+
+// get socket counts keyed by protocol and field name
+// format: "<protocol>: <field> <value> <field> <value> ..."
+func getSockstat(path string) (map[string]map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := make(map[string]map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		protocol, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		stats[protocol] = make(map[string]float64)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if value, err := strconv.ParseFloat(fields[i+1], 64); err == nil {
+				stats[protocol][fields[i]] = value
+			}
+		}
+	}
+
+	return stats, scanner.Err()
+}
@@ -0,0 +1,118 @@
+//go:build !nowifi
+
+package collector
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// wireless channel survey metrics collector
+type WirelessSurveyCollector struct {
+	activeTime   *prometheus.Desc
+	busyTime     *prometheus.Desc
+	receiveTime  *prometheus.Desc
+	transmitTime *prometheus.Desc
+	noise        *prometheus.Desc
+}
+
+// create a new wireless survey collector
+func NewWirelessSurveyCollector() *WirelessSurveyCollector {
+	labels := []string{"interface", "frequency", "in_use"}
+
+	return &WirelessSurveyCollector{
+		activeTime: prometheus.NewDesc(
+			"openwrt_wireless_survey_active_seconds_total",
+			"total time the radio was active on the channel in seconds",
+			labels, nil,
+		),
+		busyTime: prometheus.NewDesc(
+			"openwrt_wireless_survey_busy_seconds_total",
+			"total time the channel was sensed busy in seconds",
+			labels, nil,
+		),
+		receiveTime: prometheus.NewDesc(
+			"openwrt_wireless_survey_receive_seconds_total",
+			"total time the radio spent receiving on the channel in seconds",
+			labels, nil,
+		),
+		transmitTime: prometheus.NewDesc(
+			"openwrt_wireless_survey_transmit_seconds_total",
+			"total time the radio spent transmitting on the channel in seconds",
+			labels, nil,
+		),
+		noise: prometheus.NewDesc(
+			"openwrt_wireless_survey_noise_dbm",
+			"channel noise floor in dBm",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *WirelessSurveyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.activeTime
+	ch <- c.busyTime
+	ch <- c.receiveTime
+	ch <- c.transmitTime
+	ch <- c.noise
+}
+
+// collect implements prometheus.Collector
+func (c *WirelessSurveyCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *WirelessSurveyCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	surveys, err := getWirelessSurveys(ctx)
+	if err != nil {
+		logCollectError("wireless survey", err)
+		return
+	}
+
+	for _, survey := range surveys {
+		// channels without active time have not been visited by the radio
+		if survey.ActiveMs == 0 {
+			continue
+		}
+
+		labels := []string{survey.Interface, strconv.Itoa(survey.Frequency), strconv.FormatBool(survey.InUse)}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.activeTime,
+			prometheus.CounterValue,
+			survey.ActiveMs/1000.0,
+			labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.busyTime,
+			prometheus.CounterValue,
+			survey.BusyMs/1000.0,
+			labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.receiveTime,
+			prometheus.CounterValue,
+			survey.ReceiveMs/1000.0,
+			labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.transmitTime,
+			prometheus.CounterValue,
+			survey.TransmitMs/1000.0,
+			labels...,
+		)
+
+		if survey.Noise != 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.noise,
+				prometheus.GaugeValue,
+				survey.Noise,
+				labels...,
+			)
+		}
+	}
+}
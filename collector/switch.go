@@ -0,0 +1,369 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// phys_port_name of dsa user ports (p0, p1, ...)
+var dsaPortNamePattern = regexp.MustCompile(`^p([0-9]+)$`)
+
+// physical switch port collector for dsa and swconfig switches
+type SwitchCollector struct {
+	up         *prometheus.Desc
+	speed      *prometheus.Desc
+	fullDuplex *prometheus.Desc
+	rxBytes    *prometheus.Desc
+	txBytes    *prometheus.Desc
+	rxPackets  *prometheus.Desc
+	txPackets  *prometheus.Desc
+}
+
+// create a new switch collector
+func NewSwitchCollector() *SwitchCollector {
+	labels := []string{"switch", "port", "role"}
+	return &SwitchCollector{
+		up: prometheus.NewDesc(
+			"openwrt_switch_port_up",
+			"whether the switch port has a link (1 = up)",
+			labels, nil,
+		),
+		speed: prometheus.NewDesc(
+			"openwrt_switch_port_speed_mbps",
+			"negotiated link speed of the switch port in megabits per second",
+			labels, nil,
+		),
+		fullDuplex: prometheus.NewDesc(
+			"openwrt_switch_port_full_duplex",
+			"whether the switch port negotiated full duplex (1 = full, 0 = half)",
+			labels, nil,
+		),
+		rxBytes: prometheus.NewDesc(
+			"openwrt_switch_port_receive_bytes_total",
+			"total number of bytes received on the switch port",
+			labels, nil,
+		),
+		txBytes: prometheus.NewDesc(
+			"openwrt_switch_port_transmit_bytes_total",
+			"total number of bytes transmitted on the switch port",
+			labels, nil,
+		),
+		rxPackets: prometheus.NewDesc(
+			"openwrt_switch_port_receive_packets_total",
+			"total number of packets received on the switch port",
+			labels, nil,
+		),
+		txPackets: prometheus.NewDesc(
+			"openwrt_switch_port_transmit_packets_total",
+			"total number of packets transmitted on the switch port",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *SwitchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.speed
+	ch <- c.fullDuplex
+	ch <- c.rxBytes
+	ch <- c.txBytes
+	ch <- c.rxPackets
+	ch <- c.txPackets
+}
+
+// collect implements prometheus.Collector
+func (c *SwitchCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *SwitchCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	ports, err := getSwitchPorts(ctx)
+	if err != nil {
+		logCollectError("switch", err)
+		return
+	}
+
+	for _, port := range ports {
+		labels := []string{port.Switch, port.Port, port.Role}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.up,
+			prometheus.GaugeValue,
+			boolToFloat64(port.Up),
+			labels...,
+		)
+		if port.Speed > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.speed,
+				prometheus.GaugeValue,
+				port.Speed,
+				labels...,
+			)
+		}
+		switch port.Duplex {
+		case "full":
+			ch <- prometheus.MustNewConstMetric(c.fullDuplex, prometheus.GaugeValue, 1, labels...)
+		case "half":
+			ch <- prometheus.MustNewConstMetric(c.fullDuplex, prometheus.GaugeValue, 0, labels...)
+		}
+
+		counters := []struct {
+			desc  *prometheus.Desc
+			value *float64
+		}{
+			{c.rxBytes, port.RxBytes},
+			{c.txBytes, port.TxBytes},
+			{c.rxPackets, port.RxPackets},
+			{c.txPackets, port.TxPackets},
+		}
+		for _, counter := range counters {
+			if counter.value == nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				counter.desc,
+				prometheus.CounterValue,
+				*counter.value,
+				labels...,
+			)
+		}
+	}
+}
+
+// physical switch port state (nil counters are not reported by the switch)
+type SwitchPort struct {
+	Switch    string
+	Port      string
+	Role      string
+	Up        bool
+	Speed     float64
+	Duplex    string
+	RxBytes   *float64
+	TxBytes   *float64
+	RxPackets *float64
+	TxPackets *float64
+}
+
+// get switch ports from dsa port netdevs, falling back to swconfig on older targets
+func getSwitchPorts(ctx context.Context) ([]SwitchPort, error) {
+	ports, err := getDSAPorts()
+	if err != nil || len(ports) > 0 {
+		return ports, err
+	}
+
+	ports, err = getSwconfigPorts(ctx)
+	if isMissingCommand(err) {
+		return nil, nil
+	}
+	return ports, err
+}
+
+// get dsa user ports, which are regular netdevs (lan1, wan, ...) with a phys_port_name
+func getDSAPorts() ([]SwitchPort, error) {
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return nil, err
+	}
+
+	// dsa ports are attached to a conduit interface, which names the switch
+	names := make(map[string]string)
+	for _, entry := range entries {
+		names[readSysfsString(filepath.Join("/sys/class/net", entry.Name(), "ifindex"))] = entry.Name()
+	}
+
+	var stats map[string]NetworkInterface
+	var ports []SwitchPort
+	for _, entry := range entries {
+		dir := filepath.Join("/sys/class/net", entry.Name())
+		match := dsaPortNamePattern.FindStringSubmatch(readSysfsString(filepath.Join(dir, "phys_port_name")))
+		if match == nil || readSysfsString(filepath.Join(dir, "phys_switch_id")) == "" {
+			continue
+		}
+
+		if stats == nil {
+			stats = make(map[string]NetworkInterface)
+			interfaces, err := getNetworkInterfaces()
+			if err != nil {
+				slog.Warn("failed to get switch port statistics", "err", err)
+			}
+			for _, iface := range interfaces {
+				stats[iface.Name] = iface
+			}
+		}
+
+		port := SwitchPort{
+			Switch: names[readSysfsString(filepath.Join(dir, "iflink"))],
+			Port:   match[1],
+			Role:   entry.Name(),
+			Up:     readSysfsString(filepath.Join(dir, "operstate")) == "up",
+			Duplex: readSysfsString(filepath.Join(dir, "duplex")),
+		}
+		if speed, err := strconv.ParseFloat(readSysfsString(filepath.Join(dir, "speed")), 64); err == nil {
+			port.Speed = speed
+		}
+		if iface, ok := stats[entry.Name()]; ok {
+			rxBytes, txBytes := float64(iface.RxBytes), float64(iface.TxBytes)
+			rxPackets, txPackets := float64(iface.RxPackets), float64(iface.TxPackets)
+			port.RxBytes, port.TxBytes = &rxBytes, &txBytes
+			port.RxPackets, port.TxPackets = &rxPackets, &txPackets
+		}
+		ports = append(ports, port)
+	}
+
+	return ports, nil
+}
+
+// get swconfig switch ports from 'swconfig list' and 'swconfig dev <switch> show'
+func getSwconfigPorts(ctx context.Context) ([]SwitchPort, error) {
+	output, err := runCommand(ctx, "swconfig", "list")
+	if err != nil {
+		return nil, err
+	}
+
+	roles := getSwconfigPortRoles()
+
+	// format: Found: switch0 - mt7530
+	var ports []SwitchPort
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "Found:" {
+			continue
+		}
+		switchName := fields[1]
+
+		show, err := runCommand(ctx, "swconfig", "dev", switchName, "show")
+		if err != nil {
+			return nil, err
+		}
+		for _, port := range parseSwconfigShow(switchName, string(show)) {
+			port.Role = roles[switchName+"/"+port.Port]
+			ports = append(ports, port)
+		}
+	}
+
+	return ports, scanner.Err()
+}
+
+// parse the per-port link state and mib counters of 'swconfig dev <switch> show'
+func parseSwconfigShow(switchName, output string) []SwitchPort {
+	var ports []SwitchPort
+	var current *SwitchPort
+	mib := make(map[string]float64)
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		// mib counter names differ per switch driver, these are used by the ar8xxx (qca) driver
+		current.RxBytes = mibCounter(mib, "RxGoodByte")
+		current.TxBytes = mibCounter(mib, "TxByte")
+		current.RxPackets = mibCounter(mib, "RxUnicast", "RxMulti", "RxBroad")
+		current.TxPackets = mibCounter(mib, "TxUnicast", "TxMulti", "TxBroad")
+		ports = append(ports, *current)
+		mib = make(map[string]float64)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// format: Port 1:
+		if strings.HasPrefix(line, "Port ") && strings.HasSuffix(line, ":") {
+			finish()
+			current = &SwitchPort{
+				Switch: switchName,
+				Port:   strings.TrimSuffix(strings.TrimPrefix(line, "Port "), ":"),
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		// format: link: port:1 link:up speed:1000baseT full-duplex txflow rxflow auto
+		if strings.HasPrefix(line, "link:") {
+			for _, field := range strings.Fields(strings.TrimPrefix(line, "link:")) {
+				switch {
+				case field == "link:up":
+					current.Up = true
+				case strings.HasPrefix(field, "speed:"):
+					speed, _ := strconv.ParseFloat(strings.TrimRight(strings.TrimPrefix(field, "speed:"), "baseTXFMbps"), 64)
+					current.Speed = speed
+				case field == "full-duplex":
+					current.Duplex = "full"
+				case field == "half-duplex":
+					current.Duplex = "half"
+				}
+			}
+			continue
+		}
+
+		// mib counter lines follow the "mib:" attribute, format: RxGoodByte  : 123456
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				mib[strings.TrimSpace(key)] = number
+			}
+		}
+	}
+	finish()
+
+	return ports
+}
+
+// sum of the named mib counters, nil if any of them is missing
+func mibCounter(mib map[string]float64, names ...string) *float64 {
+	var total float64
+	for _, name := range names {
+		value, ok := mib[name]
+		if !ok {
+			return nil
+		}
+		total += value
+	}
+	return &total
+}
+
+// get the roles of swconfig ports keyed by "<switch>/<port>" from the untagged
+// members of the switch_vlan sections in /etc/config/network
+func getSwconfigPortRoles() map[string]string {
+	roles := make(map[string]string)
+
+	sections, err := loadUCIConfig("network")
+	if err != nil {
+		return roles
+	}
+
+	for _, section := range sections {
+		if section.Type != "switch_vlan" {
+			continue
+		}
+		role := section.Option("description")
+		if role == "" {
+			role = "vlan" + section.Option("vlan")
+		}
+		for _, port := range section.List("ports") {
+			// tagged members (cpu port, trunks) carry several vlans
+			if strings.HasSuffix(port, "t") {
+				continue
+			}
+			key := section.Option("device") + "/" + port
+			if roles[key] != "" {
+				roles[key] += ","
+			}
+			roles[key] += role
+		}
+	}
+
+	return roles
+}
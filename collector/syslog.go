@@ -0,0 +1,198 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// timestamp layout of logread lines, e.g. "Thu Oct 15 10:00:00 2026"
+const logreadTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+// system log line and event counter collector
+type SyslogCollector struct {
+	lines  *prometheus.Desc
+	events *prometheus.Desc
+	config *SyslogConfig
+
+	mu          sync.Mutex
+	lineCounts  map[syslogLevel]float64
+	eventCounts map[string]float64
+}
+
+// syslog collector configuration
+type SyslogConfig struct {
+	Enabled bool
+	Events  []SyslogEvent
+}
+
+// named regular expression counted on every matching log line
+type SyslogEvent struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// facility and severity of a log line
+type syslogLevel struct {
+	facility string
+	severity string
+}
+
+// create a new syslog collector
+func NewSyslogCollector(config *SyslogConfig) *SyslogCollector {
+	c := &SyslogCollector{
+		lines: prometheus.NewDesc(
+			"openwrt_syslog_lines_total",
+			"total number of system log lines per facility and severity",
+			[]string{"facility", "severity"}, nil,
+		),
+		events: prometheus.NewDesc(
+			"openwrt_syslog_events_total",
+			"total number of system log lines matching a configured event pattern",
+			[]string{"event"}, nil,
+		),
+		config:      config,
+		lineCounts:  make(map[syslogLevel]float64),
+		eventCounts: make(map[string]float64),
+	}
+
+	for _, event := range config.Events {
+		c.eventCounts[event.Name] = 0
+	}
+
+	if c.config.Enabled {
+		goBackground(c.follow)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *SyslogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lines
+	ch <- c.events
+}
+
+// collect implements prometheus.Collector
+func (c *SyslogCollector) Collect(ch chan<- prometheus.Metric) {
+	if !c.config.Enabled {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for level, count := range c.lineCounts {
+		ch <- prometheus.MustNewConstMetric(
+			c.lines,
+			prometheus.CounterValue,
+			count,
+			level.facility, level.severity,
+		)
+	}
+	for name, count := range c.eventCounts {
+		ch <- prometheus.MustNewConstMetric(
+			c.events,
+			prometheus.CounterValue,
+			count,
+			name,
+		)
+	}
+}
+
+// follow the system log, restarting logread if it exits
+func (c *SyslogCollector) follow(ctx context.Context) {
+	for {
+		if err := c.readLog(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("error following system log", "err", err)
+		}
+		if !sleepContext(ctx, 10*time.Second) {
+			return
+		}
+	}
+}
+
+// count lines from 'logread -f' until it exits, skipping the backlog logread prints first
+func (c *SyslogCollector) readLog(ctx context.Context) error {
+	started := time.Now().Truncate(time.Second)
+	cmd, stdout, err := startCommand(ctx, "logread", "-f")
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// format: <weekday> <month> <day> <time> <year> <facility>.<severity> <tag>: <message>
+		if len(line) <= len(logreadTimeLayout) {
+			continue
+		}
+		timestamp, err := time.ParseInLocation(logreadTimeLayout, line[:len(logreadTimeLayout)], time.Local)
+		if err == nil && timestamp.Before(started) {
+			continue
+		}
+		fields := strings.Fields(line[len(logreadTimeLayout):])
+		if len(fields) == 0 {
+			continue
+		}
+		facility, severity, ok := strings.Cut(fields[0], ".")
+		if !ok {
+			continue
+		}
+
+		c.mu.Lock()
+		c.lineCounts[syslogLevel{facility: facility, severity: severity}]++
+		for _, event := range c.config.Events {
+			if event.Pattern.MatchString(line) {
+				c.eventCounts[event.Name]++
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	return cmd.Wait()
+}
+
+// load syslog configuration from environment variables
+func loadSyslogConfig() *SyslogConfig {
+	config := &SyslogConfig{}
+
+	// syslog_enabled: follow the system log in the background
+	if enabledEnv := os.Getenv("SYSLOG_ENABLED"); enabledEnv != "" {
+		if enabled, err := strconv.ParseBool(enabledEnv); err == nil {
+			config.Enabled = enabled
+		}
+	}
+
+	// syslog_events: semicolon-separated list of <name>=<regex>
+	if eventsEnv := os.Getenv("SYSLOG_EVENTS"); eventsEnv != "" {
+		for _, entry := range strings.Split(eventsEnv, ";") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			name, expr, ok := strings.Cut(entry, "=")
+			pattern, err := regexp.Compile(expr)
+			if !ok || err != nil || strings.TrimSpace(name) == "" {
+				slog.Warn("invalid SYSLOG_EVENTS entry", "entry", entry)
+				continue
+			}
+			config.Events = append(config.Events, SyslogEvent{
+				Name:    strings.TrimSpace(name),
+				Pattern: pattern,
+			})
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,224 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// board, firmware, kernel and uptime collector
+type SystemCollector struct {
+	info     *prometheus.Desc
+	bootTime *prometheus.Desc
+	uptime   *prometheus.Desc
+	entropy  *prometheus.Desc
+}
+
+// create a new system info collector
+func NewSystemCollector() *SystemCollector {
+	return &SystemCollector{
+		info: prometheus.NewDesc(
+			"openwrt_system_info",
+			"board, firmware release and kernel version of the router",
+			[]string{"board_name", "model", "release", "revision", "target", "kernel"}, nil,
+		),
+		bootTime: prometheus.NewDesc(
+			"openwrt_boot_time_seconds",
+			"time the system was booted",
+			nil, nil,
+		),
+		uptime: prometheus.NewDesc(
+			"openwrt_uptime_seconds",
+			"time since the system was booted in seconds",
+			nil, nil,
+		),
+		entropy: prometheus.NewDesc(
+			"openwrt_entropy_available_bits",
+			"bits of entropy available in the kernel random pool",
+			nil, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *SystemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.bootTime
+	ch <- c.uptime
+	ch <- c.entropy
+}
+
+// collect implements prometheus.Collector
+func (c *SystemCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *SystemCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	if info, err := getSystemInfo(ctx); err != nil {
+		logCollectError("system info", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(
+			c.info,
+			prometheus.GaugeValue,
+			1,
+			info.BoardName, info.Model, info.Release, info.Revision, info.Target, info.Kernel,
+		)
+	}
+
+	if bootTime, err := getBootTime(); err != nil {
+		logCollectError("boot time", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(
+			c.bootTime,
+			prometheus.GaugeValue,
+			float64(bootTime.Unix()),
+		)
+	}
+
+	if uptime, err := getUptime(); err != nil {
+		logCollectError("uptime", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(
+			c.uptime,
+			prometheus.GaugeValue,
+			uptime,
+		)
+	}
+
+	if entropy, err := strconv.ParseFloat(readSysfsString("/proc/sys/kernel/random/entropy_avail"), 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.entropy,
+			prometheus.GaugeValue,
+			entropy,
+		)
+	}
+}
+
+// board and firmware information
+type SystemInfo struct {
+	BoardName string
+	Model     string
+	Release   string
+	Revision  string
+	Target    string
+	Kernel    string
+}
+
+// ubus system board reply
+type ubusSystemBoard struct {
+	Kernel    string `json:"kernel"`
+	Model     string `json:"model"`
+	BoardName string `json:"board_name"`
+	Release   struct {
+		Version  string `json:"version"`
+		Revision string `json:"revision"`
+		Target   string `json:"target"`
+	} `json:"release"`
+}
+
+// get system info from 'ubus call system board', falling back to /etc/openwrt_release
+func getSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	var board ubusSystemBoard
+	if err := ubusCall(ctx, "system", "board", nil, &board); err == nil {
+		return &SystemInfo{
+			BoardName: board.BoardName,
+			Model:     board.Model,
+			Release:   board.Release.Version,
+			Revision:  board.Release.Revision,
+			Target:    board.Release.Target,
+			Kernel:    board.Kernel,
+		}, nil
+	}
+
+	release, err := readOpenWrtRelease("/etc/openwrt_release")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SystemInfo{
+		BoardName: readSysfsString("/tmp/sysinfo/board_name"),
+		Model:     readSysfsString("/tmp/sysinfo/model"),
+		Release:   release["DISTRIB_RELEASE"],
+		Revision:  release["DISTRIB_REVISION"],
+		Target:    release["DISTRIB_TARGET"],
+		Kernel:    readSysfsString("/proc/sys/kernel/osrelease"),
+	}, nil
+}
+
+// read the shell variables of /etc/openwrt_release
+// format: DISTRIB_RELEASE='23.05.3'
+func readOpenWrtRelease(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	release := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		release[key] = strings.Trim(value, `'"`)
+	}
+
+	return release, scanner.Err()
+}
+
+// get the system uptime in seconds from /proc/uptime
+func getUptime() (float64, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, errors.New("empty /proc/uptime")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// get the system boot time from the btime line of /proc/stat, which unlike
+// now minus uptime does not jitter between scrapes
+func getBootTime() (time.Time, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(btime, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Time{}, errors.New("btime not found in /proc/stat")
+}
+
+// counter metric that started counting at created, exposed as a _created series to openmetrics scrapers
+func newCreatedCounter(desc *prometheus.Desc, value float64, created time.Time, labelValues ...string) prometheus.Metric {
+	if created.IsZero() {
+		return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labelValues...)
+	}
+	return prometheus.MustNewConstMetricWithCreatedTimestamp(desc, prometheus.CounterValue, value, created, labelValues...)
+}
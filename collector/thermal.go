@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// thermal zone temperature collector
+type ThermalCollector struct {
+	temperature *prometheus.Desc
+}
+
+// create a new thermal collector
+func NewThermalCollector() *ThermalCollector {
+	return &ThermalCollector{
+		temperature: prometheus.NewDesc(
+			"openwrt_thermal_zone_temperature_celsius",
+			"thermal zone temperature in degrees celsius",
+			[]string{"zone", "type"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *ThermalCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.temperature
+}
+
+// collect implements prometheus.Collector
+func (c *ThermalCollector) Collect(ch chan<- prometheus.Metric) {
+	zones, err := getThermalZones()
+	if err != nil {
+		logCollectError("thermal", err)
+		return
+	}
+
+	for _, zone := range zones {
+		ch <- prometheus.MustNewConstMetric(
+			c.temperature,
+			prometheus.GaugeValue,
+			zone.Temperature,
+			zone.Zone,
+			zone.Type,
+		)
+	}
+}
+
+// thermal zone information
+type ThermalZone struct {
+	Zone        string
+	Type        string
+	Temperature float64
+}
+
+// get thermal zones from /sys/class/thermal
+func getThermalZones() ([]ThermalZone, error) {
+	paths, err := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []ThermalZone
+	for _, path := range paths {
+		// temperature is reported in millidegrees celsius
+		data, err := os.ReadFile(filepath.Join(path, "temp"))
+		if err != nil {
+			continue
+		}
+		milli, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			continue
+		}
+
+		zoneType := ""
+		if data, err := os.ReadFile(filepath.Join(path, "type")); err == nil {
+			zoneType = strings.TrimSpace(string(data))
+		}
+
+		zones = append(zones, ThermalZone{
+			Zone:        strings.TrimPrefix(filepath.Base(path), "thermal_zone"),
+			Type:        zoneType,
+			Temperature: milli / 1000.0,
+		})
+	}
+
+	return zones, nil
+}
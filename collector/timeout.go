@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// per-collector scrape deadlines
+type TimeoutConfig struct {
+	// deadline of every collector without its own (0 = no deadline)
+	Default time.Duration
+	// deadlines by collector name, overriding the default
+	Collectors map[string]time.Duration
+}
+
+// collector whose commands, requests and pings are cancelled with ctx, e.g. at its scrape deadline
+type ContextCollector interface {
+	prometheus.Collector
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// collector wrapping another one, e.g. to enforce its scrape deadline
+type wrappedCollector interface {
+	Unwrap() prometheus.Collector
+}
+
+// get the collector created by its constructor, removing the wrappers added by All
+func Unwrap(c prometheus.Collector) prometheus.Collector {
+	for {
+		wrapped, ok := c.(wrappedCollector)
+		if !ok {
+			return c
+		}
+		c = wrapped.Unwrap()
+	}
+}
+
+// collect with ctx if the collector supports it
+func collectContext(ctx context.Context, c prometheus.Collector, ch chan<- prometheus.Metric) {
+	if collector, ok := c.(ContextCollector); ok {
+		collector.CollectContext(ctx, ch)
+	} else {
+		c.Collect(ch)
+	}
+}
+
+// get the deadline of a collector
+func (c *TimeoutConfig) timeout(name string) time.Duration {
+	if timeout, ok := c.Collectors[name]; ok {
+		return timeout
+	}
+	return c.Default
+}
+
+// collector that stops waiting for another one at a deadline, so a hung command
+// only costs the metrics of that collector instead of the whole scrape
+type timeoutCollector struct {
+	name      string
+	collector prometheus.Collector
+	timeout   time.Duration
+}
+
+// wrap a collector with a deadline, unless the deadline is 0
+func withTimeout(name string, c prometheus.Collector, timeout time.Duration) prometheus.Collector {
+	if timeout <= 0 {
+		return c
+	}
+	return &timeoutCollector{name: name, collector: c, timeout: timeout}
+}
+
+// unwrap returns the collector enforcing no deadline
+func (c *timeoutCollector) Unwrap() prometheus.Collector {
+	return c.collector
+}
+
+// describe implements prometheus.Collector
+func (c *timeoutCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+// collect implements prometheus.Collector
+func (c *timeoutCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(backgroundCtx, c.timeout)
+	defer cancel()
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		defer close(metrics)
+		collectContext(ctx, c.collector, metrics)
+	}()
+
+	for {
+		select {
+		case metric, ok := <-metrics:
+			if !ok {
+				return
+			}
+			ch <- metric
+		case <-ctx.Done():
+			slog.Warn("collector exceeded its deadline, dropping its remaining metrics", "collector", c.name, "timeout", c.timeout)
+			// let the abandoned collect finish, it stops early if it honours the context
+			go func() {
+				for range metrics {
+				}
+			}()
+			return
+		}
+	}
+}
+
+// load per-collector deadlines from environment variables
+func loadTimeoutConfig() *TimeoutConfig {
+	config := &TimeoutConfig{
+		Default: 8 * time.Second,
+		// collector_timeouts: comma-separated name=duration deadlines overriding the default, e.g. opkg=30s
+		Collectors: parseCollectorDurations("COLLECTOR_TIMEOUTS"),
+	}
+
+	// collector_timeout: deadline of a collector within a scrape, 0 disables it
+	if timeoutEnv := os.Getenv("COLLECTOR_TIMEOUT"); timeoutEnv != "" {
+		if timeout, err := time.ParseDuration(timeoutEnv); err == nil && timeout >= 0 {
+			config.Default = timeout
+		} else {
+			slog.Warn("invalid COLLECTOR_TIMEOUT", "value", timeoutEnv)
+		}
+	}
+
+	return config
+}
+
+// parse an environment variable of comma-separated collector=duration entries
+func parseCollectorDurations(env string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	list := os.Getenv(env)
+	if list == "" {
+		return durations
+	}
+
+	for _, entry := range strings.Split(list, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		duration, err := time.ParseDuration(value)
+		if !ok || name == "" || err != nil || duration < 0 {
+			slog.Warn("invalid "+env+" entry", "entry", entry)
+			continue
+		}
+		durations[name] = duration
+	}
+
+	return durations
+}
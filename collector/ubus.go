@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// returned when the ubus object or method does not exist (service not running or not installed)
+var errUbusNotFound = errors.New("ubus object or method not found")
+
+// ubus cli exit codes (enum ubus_msg_status in libubus)
+var ubusStatusErrors = map[int]string{
+	1:  "invalid command",
+	2:  "invalid argument",
+	5:  "no data",
+	6:  "permission denied",
+	7:  "timeout",
+	8:  "not supported",
+	9:  "unknown error",
+	10: "connection failed",
+}
+
+// call a ubus method and decode its json reply into result (nil to discard)
+func ubusCall(ctx context.Context, object, method string, args any, result any) error {
+	cmdArgs := []string{"call", object, method}
+	if args != nil {
+		encoded, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		cmdArgs = append(cmdArgs, string(encoded))
+	}
+
+	output, err := runCommand(ctx, "ubus", cmdArgs...)
+	if err != nil {
+		return ubusError(object, method, err)
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(output, result); err != nil {
+		return fmt.Errorf("ubus call %s %s: %w", object, method, err)
+	}
+	return nil
+}
+
+// translate ubus cli exit codes into readable errors
+func ubusError(object, method string, err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	code := exitErr.ExitCode()
+	if code == 3 || code == 4 {
+		return fmt.Errorf("ubus call %s %s: %w", object, method, errUbusNotFound)
+	}
+	if status, ok := ubusStatusErrors[code]; ok {
+		return fmt.Errorf("ubus call %s %s: %s", object, method, status)
+	}
+	return fmt.Errorf("ubus call %s %s: %w", object, method, err)
+}
+
+// convert a json number or numeric string to a float pointer
+func jsonNumber(value any) *float64 {
+	switch v := value.(type) {
+	case float64:
+		return &v
+	case string:
+		if number, err := strconv.ParseFloat(v, 64); err == nil {
+			return &number
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// directory containing uci configuration files
+const uciConfigDir = "/etc/config"
+
+// uci configuration section
+type UCISection struct {
+	Type    string
+	Name    string
+	Options map[string][]string
+}
+
+// get the first value of an option
+func (s *UCISection) Option(name string) string {
+	values := s.Options[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// get all values of a list option, splitting legacy space-separated options
+func (s *UCISection) List(name string) []string {
+	var values []string
+	for _, value := range s.Options[name] {
+		values = append(values, strings.Fields(value)...)
+	}
+	return values
+}
+
+// load a uci configuration file by package name (e.g. "network", "firewall")
+func loadUCIConfig(pkg string) ([]UCISection, error) {
+	file, err := os.Open(filepath.Join(uciConfigDir, pkg))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var sections []UCISection
+	var current *UCISection
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		tokens := splitUCILine(scanner.Text())
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch tokens[0] {
+		case "config":
+			section := UCISection{Options: make(map[string][]string)}
+			if len(tokens) > 1 {
+				section.Type = tokens[1]
+			}
+			if len(tokens) > 2 {
+				section.Name = tokens[2]
+			}
+			sections = append(sections, section)
+			current = &sections[len(sections)-1]
+		case "option":
+			if current != nil && len(tokens) > 2 {
+				current.Options[tokens[1]] = []string{tokens[2]}
+			}
+		case "list":
+			if current != nil && len(tokens) > 2 {
+				current.Options[tokens[1]] = append(current.Options[tokens[1]], tokens[2])
+			}
+		}
+	}
+
+	return sections, scanner.Err()
+}
+
+// split a uci line into tokens, honouring single and double quotes and comments
+func splitUCILine(line string) []string {
+	var tokens []string
+	var token strings.Builder
+	var quote rune
+	inToken := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				token.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inToken = true
+		case r == '#':
+			if inToken {
+				tokens = append(tokens, token.String())
+			}
+			return tokens
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens
+}
@@ -0,0 +1,222 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// github releases api endpoint for the latest exporter release
+const latestReleaseURL = "https://api.github.com/repos/OVINC-CN/OpenWRTMetrics/releases/latest"
+
+// exporter update checker collector
+type UpdateCollector struct {
+	updateAvailable *prometheus.Desc
+	latestInfo      *prometheus.Desc
+	version         string
+	config          *UpdateConfig
+
+	mu     sync.Mutex
+	latest *Release
+}
+
+// update checker configuration
+type UpdateConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// github release information
+type Release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// github release asset information
+type ReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Digest             string `json:"digest"`
+}
+
+// create a new update collector for the running exporter version
+func NewUpdateCollector(version string, config *UpdateConfig) *UpdateCollector {
+	c := &UpdateCollector{
+		updateAvailable: prometheus.NewDesc(
+			"openwrt_exporter_update_available",
+			"whether a newer exporter release is available (1 = available)",
+			[]string{"version", "latest_version"}, nil,
+		),
+		latestInfo: prometheus.NewDesc(
+			"openwrt_exporter_latest_release_info",
+			"information about the latest exporter release",
+			[]string{"tag"}, nil,
+		),
+		version: version,
+		config:  config,
+	}
+
+	if c.config.Enabled {
+		goBackground(c.check)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *UpdateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.updateAvailable
+	ch <- c.latestInfo
+}
+
+// collect implements prometheus.Collector
+func (c *UpdateCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	latest := c.latest
+	c.mu.Unlock()
+
+	if latest == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.latestInfo,
+		prometheus.GaugeValue,
+		1,
+		latest.TagName,
+	)
+
+	// development builds cannot be compared against releases
+	newer, ok := IsNewerVersion(latest.TagName, c.version)
+	if !ok {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.updateAvailable,
+		prometheus.GaugeValue,
+		boolToFloat64(newer),
+		c.version,
+		strings.TrimPrefix(latest.TagName, "v"),
+	)
+}
+
+// periodically check the latest release
+func (c *UpdateCollector) check(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for running := true; running; running = nextTick(ctx, ticker) {
+		release, err := GetLatestRelease()
+		if err != nil {
+			slog.Error("error checking for exporter updates", "err", err)
+			continue
+		}
+
+		c.mu.Lock()
+		c.latest = release
+		c.mu.Unlock()
+	}
+}
+
+// get the latest exporter release from github
+func GetLatestRelease() (*Release, error) {
+	req, err := http.NewRequest(http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+
+	return &release, nil
+}
+
+// compare two semantic versions (with optional "v" prefix), reporting whether latest is newer than current
+func IsNewerVersion(latest string, current string) (bool, bool) {
+	latestParts, ok := parseVersion(latest)
+	if !ok {
+		return false, false
+	}
+	currentParts, ok := parseVersion(current)
+	if !ok {
+		return false, false
+	}
+
+	for i := range latestParts {
+		if latestParts[i] != currentParts[i] {
+			return latestParts[i] > currentParts[i], true
+		}
+	}
+
+	return false, true
+}
+
+// parse major, minor and patch numbers, ignoring any pre-release suffix
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "-")
+
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = value
+	}
+
+	return parts, true
+}
+
+// load update checker configuration from environment variables
+func loadUpdateConfig() *UpdateConfig {
+	config := &UpdateConfig{
+		Interval: 24 * time.Hour,
+	}
+
+	// update_check_enabled: query github releases for newer exporter versions
+	if enabledEnv := os.Getenv("UPDATE_CHECK_ENABLED"); enabledEnv != "" {
+		if enabled, err := strconv.ParseBool(enabledEnv); err == nil {
+			config.Enabled = enabled
+		}
+	}
+
+	// update_check_interval: interval between release checks
+	if intervalEnv := os.Getenv("UPDATE_CHECK_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.Interval = interval
+		}
+	}
+
+	return config
+}
@@ -1,8 +1,14 @@
+//go:build !noupnp
+
 package collector
 
 import (
 	"bufio"
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -10,20 +16,28 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const (
+	// upper bound of GetGenericPortMappingEntry requests per scrape
+	maxUPnPMappings = 1024
+	// upnp error code returned once the mapping index is past the last mapping
+	upnpErrorArrayIndexInvalid = 713
+)
+
 // UPnP port mapping metrics collector
 type UPnPCollector struct {
 	upnpInfo         *prometheus.Desc
 	upnpLeaseSeconds *prometheus.Desc
 	upnpMappingCount *prometheus.Desc
+	config           *UPnPConfig
 }
 
 // create a new UPnP collector
-func NewUPnPCollector() *UPnPCollector {
+func NewUPnPCollector(config *UPnPConfig) *UPnPCollector {
 	return &UPnPCollector{
 		upnpInfo: prometheus.NewDesc(
 			"openwrt_upnp_mapping_info",
-			"information about UPnP port mappings",
-			[]string{"protocol", "external_port", "internal_ip", "internal_port", "description"}, nil,
+			"information about UPnP port mappings (source is upnp, natpmp or pcp)",
+			[]string{"protocol", "external_port", "internal_ip", "internal_port", "description", "source"}, nil,
 		),
 		upnpLeaseSeconds: prometheus.NewDesc(
 			"openwrt_upnp_mapping_lease_seconds",
@@ -35,6 +49,7 @@ func NewUPnPCollector() *UPnPCollector {
 			"total number of active UPnP port mappings",
 			nil, nil,
 		),
+		config: config,
 	}
 }
 
@@ -47,9 +62,14 @@ func (c *UPnPCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // collect implements prometheus.Collector
 func (c *UPnPCollector) Collect(ch chan<- prometheus.Metric) {
-	mappings, err := getUPnPMappings()
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *UPnPCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	mappings, err := c.getMappings(ctx)
 	if err != nil {
-		log.Printf("error collecting upnp metrics: %v", err)
+		logCollectError("upnp", err)
 		return
 	}
 
@@ -68,37 +88,86 @@ func (c *UPnPCollector) Collect(ch chan<- prometheus.Metric) {
 			1,
 			mapping.Protocol,
 			mapping.ExternalPort,
-			mapping.InternalIP,
+			privateIP(mapping.InternalIP),
 			mapping.InternalPort,
 			mapping.Description,
+			mapping.Source,
 		)
 
-		// lease duration
+		// lease duration, unknown for mappings only found in the firewall
+		if mapping.LeaseSeconds == nil {
+			continue
+		}
 		ch <- prometheus.MustNewConstMetric(
 			c.upnpLeaseSeconds,
 			prometheus.GaugeValue,
-			mapping.LeaseSeconds,
+			*mapping.LeaseSeconds,
 			mapping.Protocol,
 			mapping.ExternalPort,
-			mapping.InternalIP,
+			privateIP(mapping.InternalIP),
 			mapping.InternalPort,
 			mapping.Description,
 		)
 	}
 }
 
+// get UPnP port mappings from the configured source
+func (c *UPnPCollector) getMappings(ctx context.Context) ([]UPnPMapping, error) {
+	if c.config.Mode == "soap" {
+		controlURL := c.config.ControlURL
+		if controlURL == "" {
+			controlURL, _ = getUPnPControlURL(ctx)
+		}
+		if controlURL != "" {
+			// the leases file misses permanent and in-memory-only mappings
+			if mappings, err := getUPnPSOAPMappings(ctx, controlURL); err == nil {
+				return mappings, nil
+			}
+		}
+	}
+	return getUPnPMappings(ctx)
+}
+
 // UPnP port mapping information
 type UPnPMapping struct {
 	Protocol     string
 	ExternalPort string
 	InternalIP   string
 	InternalPort string
-	LeaseSeconds float64
+	LeaseSeconds *float64
 	Description  string
+	Source       string
+}
+
+// get UPnP port mappings from the miniupnpd leases file, completed with the
+// redirects miniupnpd installed in the firewall that are missing from it
+func getUPnPMappings(ctx context.Context) ([]UPnPMapping, error) {
+	mappings, leaseErr := getUPnPLeaseMappings()
+
+	redirects, err := getUPnPFirewallMappings(ctx)
+	if err != nil {
+		// without a leases file there is nothing else to report
+		if leaseErr != nil {
+			return nil, leaseErr
+		}
+		return mappings, nil
+	}
+
+	known := make(map[string]bool, len(mappings))
+	for _, mapping := range mappings {
+		known[mapping.Protocol+"/"+mapping.ExternalPort] = true
+	}
+	for _, redirect := range redirects {
+		if !known[redirect.Protocol+"/"+redirect.ExternalPort] {
+			mappings = append(mappings, redirect)
+		}
+	}
+
+	return mappings, nil
 }
 
 // get UPnP port mappings from miniupnpd leases file
-func getUPnPMappings() ([]UPnPMapping, error) {
+func getUPnPLeaseMappings() ([]UPnPMapping, error) {
 	// try common locations for miniupnpd leases file
 	leasePaths := []string{
 		"/var/run/miniupnpd.leases",
@@ -160,6 +229,7 @@ func parseMiniUPnPDLeases(file *os.File) ([]UPnPMapping, error) {
 
 			// clean up description
 			description = strings.TrimSpace(description)
+			source := upnpMappingSource(description)
 			if description == "" {
 				description = "unknown"
 			}
@@ -169,11 +239,166 @@ func parseMiniUPnPDLeases(file *os.File) ([]UPnPMapping, error) {
 				ExternalPort: externalPort,
 				InternalIP:   internalIP,
 				InternalPort: internalPort,
-				LeaseSeconds: leaseSeconds,
+				LeaseSeconds: &leaseSeconds,
 				Description:  description,
+				Source:       source,
 			})
 		}
 	}
 
 	return mappings, scanner.Err()
 }
+
+// get the dnat redirects miniupnpd maintains in the fw4 upnp_prerouting chain,
+// which include mappings it never wrote to the leases file
+func getUPnPFirewallMappings(ctx context.Context) ([]UPnPMapping, error) {
+	output, err := runCommand(ctx, "nft", "-j", "list", "chain", "inet", "fw4", "upnp_prerouting")
+	if err != nil {
+		return nil, err
+	}
+
+	var chain struct {
+		Nftables []struct {
+			Rule *struct {
+				Comment string                       `json:"comment"`
+				Expr    []map[string]json.RawMessage `json:"expr"`
+			} `json:"rule"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &chain); err != nil {
+		return nil, err
+	}
+
+	// miniupnpd rules: [iif <wan>] [ip daddr <ext ip>] <proto> dport <port> dnat ip to <ip>:<port> comment <description>
+	var mappings []UPnPMapping
+	for _, item := range chain.Nftables {
+		if item.Rule == nil {
+			continue
+		}
+
+		mapping := UPnPMapping{
+			Description: strings.TrimSpace(item.Rule.Comment),
+		}
+		for _, expr := range item.Rule.Expr {
+			if raw, ok := expr["match"]; ok {
+				var match struct {
+					Left struct {
+						Payload *struct {
+							Protocol string `json:"protocol"`
+							Field    string `json:"field"`
+						} `json:"payload"`
+					} `json:"left"`
+					Right any `json:"right"`
+				}
+				if err := json.Unmarshal(raw, &match); err == nil && match.Left.Payload != nil && match.Left.Payload.Field == "dport" {
+					mapping.Protocol = strings.ToUpper(match.Left.Payload.Protocol)
+					mapping.ExternalPort = fmt.Sprint(match.Right)
+				}
+			}
+			if raw, ok := expr["dnat"]; ok {
+				var dnat struct {
+					Addr string `json:"addr"`
+					Port any    `json:"port"`
+				}
+				if err := json.Unmarshal(raw, &dnat); err == nil {
+					mapping.InternalIP = dnat.Addr
+					if dnat.Port != nil {
+						mapping.InternalPort = fmt.Sprint(dnat.Port)
+					}
+				}
+			}
+		}
+		if mapping.Protocol == "" || mapping.InternalIP == "" {
+			continue
+		}
+
+		// dnat without a port keeps the external port
+		if mapping.InternalPort == "" {
+			mapping.InternalPort = mapping.ExternalPort
+		}
+		mapping.Source = upnpMappingSource(mapping.Description)
+		if mapping.Description == "" {
+			mapping.Description = "unknown"
+		}
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, nil
+}
+
+// get the protocol that created a miniupnpd mapping from the description miniupnpd
+// assigns to nat-pmp ("NAT-PMP <port> <proto>") and pcp ("PCP MAP ...") mappings
+func upnpMappingSource(description string) string {
+	switch {
+	case strings.HasPrefix(description, "NAT-PMP "):
+		return "natpmp"
+	case strings.HasPrefix(description, "PCP "):
+		return "pcp"
+	default:
+		return "upnp"
+	}
+}
+
+// enumerate UPnP port mappings with the igd GetGenericPortMappingEntry action
+func getUPnPSOAPMappings(ctx context.Context, controlURL string) ([]UPnPMapping, error) {
+	client := &http.Client{Timeout: upnpSOAPTimeout}
+
+	var mappings []UPnPMapping
+	for index := 0; index < maxUPnPMappings; index++ {
+		entry, err := getGenericPortMappingEntry(ctx, client, controlURL, index)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Enabled == "0" {
+			continue
+		}
+
+		leaseSeconds, _ := strconv.ParseFloat(entry.LeaseDuration, 64)
+		description := strings.TrimSpace(entry.Description)
+		source := upnpMappingSource(description)
+		if description == "" {
+			description = "unknown"
+		}
+
+		mappings = append(mappings, UPnPMapping{
+			Protocol:     strings.ToUpper(entry.Protocol),
+			ExternalPort: entry.ExternalPort,
+			InternalIP:   entry.InternalClient,
+			InternalPort: entry.InternalPort,
+			LeaseSeconds: &leaseSeconds,
+			Description:  description,
+			Source:       source,
+		})
+	}
+
+	return mappings, nil
+}
+
+// port mapping returned by GetGenericPortMappingEntry
+type upnpPortMappingEntry struct {
+	ExternalPort   string `xml:"NewExternalPort"`
+	Protocol       string `xml:"NewProtocol"`
+	InternalPort   string `xml:"NewInternalPort"`
+	InternalClient string `xml:"NewInternalClient"`
+	Enabled        string `xml:"NewEnabled"`
+	Description    string `xml:"NewPortMappingDescription"`
+	LeaseDuration  string `xml:"NewLeaseDuration"`
+}
+
+// get the port mapping at an index, nil once the index is past the last mapping
+func getGenericPortMappingEntry(ctx context.Context, client *http.Client, controlURL string, index int) (*upnpPortMappingEntry, error) {
+	var entry upnpPortMappingEntry
+	arguments := fmt.Sprintf("<NewPortMappingIndex>%d</NewPortMappingIndex>", index)
+	err := upnpSOAPCall(ctx, client, controlURL, "GetGenericPortMappingEntry", arguments, &entry)
+	var upnpErr *upnpError
+	if errors.As(err, &upnpErr) && upnpErr.Code == upnpErrorArrayIndexInvalid {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
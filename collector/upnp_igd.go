@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// igd lookups shared with the external ip collector, so they are also built with the noupnp tag
+
+// timeout of a single igd soap request
+const upnpSOAPTimeout = 2 * time.Second
+
+// returned when miniupnpd is disabled or not listening, so the igd is not a source on this router
+var errIGDUnavailable = errors.New("upnp igd is not available")
+
+// UPnP mapping source configuration
+type UPnPConfig struct {
+	// "soap" queries miniupnpd over the igd control interface and falls back to
+	// the leases file, "leases" only reads the leases file
+	Mode string
+	// igd WANIPConnection control url, derived from the upnpd uci config when empty
+	ControlURL string
+}
+
+// get the igd WANIPConnection control url of miniupnpd from /etc/config/upnpd
+// miniupnpd only answers peers in its lan subnets, so the lan address is used instead of localhost
+func getUPnPControlURL(ctx context.Context) (string, error) {
+	sections, err := loadUCIConfig("upnpd")
+	if err != nil {
+		return "", err
+	}
+
+	port, lan := "5000", "lan"
+	for _, section := range sections {
+		if section.Type != "upnpd" {
+			continue
+		}
+		if section.Option("enabled") == "0" {
+			return "", fmt.Errorf("miniupnpd is disabled: %w", errIGDUnavailable)
+		}
+		if value := section.Option("port"); value != "" {
+			port = value
+		}
+		if value := section.Option("internal_iface"); value != "" {
+			lan = value
+		}
+	}
+
+	interfaces, err := getUbusNetworkInterfaces(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, iface := range interfaces {
+		if iface.Interface == lan && len(iface.IPv4Addresses) > 0 {
+			return fmt.Sprintf("http://%s/ctl/IPConn", net.JoinHostPort(iface.IPv4Addresses[0].Address, port)), nil
+		}
+	}
+
+	return "", fmt.Errorf("no ipv4 address on upnp interface %s", lan)
+}
+
+// upnp error returned in a soap fault
+type upnpError struct {
+	Code        int    `xml:"errorCode"`
+	Description string `xml:"errorDescription"`
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("igd error %d: %s", e.Code, e.Description)
+}
+
+// invoke a WANIPConnection action and decode its response element into response
+func upnpSOAPCall(ctx context.Context, client *http.Client, controlURL, action, arguments string, response any) error {
+	const service = "urn:schemas-upnp-org:service:WANIPConnection:1"
+	body := fmt.Sprintf(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:%s xmlns:u="%s">%s</u:%s></s:Body>
+</s:Envelope>`, action, service, arguments, action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, service, action))
+
+	resp, err := client.Do(req)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %w", errIGDUnavailable, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// faults are returned with status 500
+	var envelope struct {
+		Body struct {
+			Fault *struct {
+				Error upnpError `xml:"detail>UPnPError"`
+			} `xml:"Fault"`
+			Response []byte `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&envelope); err != nil {
+		return fmt.Errorf("unexpected igd response (%s): %w", resp.Status, err)
+	}
+	if envelope.Body.Fault != nil {
+		return &envelope.Body.Fault.Error
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected igd status %s", resp.Status)
+	}
+
+	return xml.Unmarshal(envelope.Body.Response, response)
+}
+
+// load UPnP configuration from environment variables
+func loadUPnPConfig() *UPnPConfig {
+	config := &UPnPConfig{
+		Mode:       "soap",
+		ControlURL: os.Getenv("UPNP_CONTROL_URL"),
+	}
+
+	// upnp_mode: "soap" (igd control interface with leases file fallback) or "leases"
+	if modeEnv := os.Getenv("UPNP_MODE"); modeEnv != "" {
+		switch modeEnv {
+		case "soap", "leases":
+			config.Mode = modeEnv
+		default:
+			slog.Warn("invalid UPNP_MODE", "value", modeEnv, "using", config.Mode)
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 802.1q vlan interface collector
+type VLANCollector struct {
+	info      *prometheus.Desc
+	rxBytes   *prometheus.Desc
+	txBytes   *prometheus.Desc
+	rxPackets *prometheus.Desc
+	txPackets *prometheus.Desc
+}
+
+// create a new vlan collector
+func NewVLANCollector() *VLANCollector {
+	return &VLANCollector{
+		info: prometheus.NewDesc(
+			"openwrt_vlan_info",
+			"802.1q vlan interface with its vlan id and parent interface",
+			[]string{"interface", "vlan_id", "parent"}, nil,
+		),
+		rxBytes: prometheus.NewDesc(
+			"openwrt_vlan_receive_bytes_total",
+			"total number of bytes received on vlan interface",
+			[]string{"interface", "vlan_id", "parent"}, nil,
+		),
+		txBytes: prometheus.NewDesc(
+			"openwrt_vlan_transmit_bytes_total",
+			"total number of bytes transmitted on vlan interface",
+			[]string{"interface", "vlan_id", "parent"}, nil,
+		),
+		rxPackets: prometheus.NewDesc(
+			"openwrt_vlan_receive_packets_total",
+			"total number of packets received on vlan interface",
+			[]string{"interface", "vlan_id", "parent"}, nil,
+		),
+		txPackets: prometheus.NewDesc(
+			"openwrt_vlan_transmit_packets_total",
+			"total number of packets transmitted on vlan interface",
+			[]string{"interface", "vlan_id", "parent"}, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *VLANCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.rxBytes
+	ch <- c.txBytes
+	ch <- c.rxPackets
+	ch <- c.txPackets
+}
+
+// collect implements prometheus.Collector
+func (c *VLANCollector) Collect(ch chan<- prometheus.Metric) {
+	vlans, err := getVLANs()
+	if err != nil {
+		// /proc/net/vlan only exists once the 8021q module is loaded
+		logCollectError("vlan", err)
+		return
+	}
+	if len(vlans) == 0 {
+		return
+	}
+
+	interfaces, err := getNetworkInterfaces()
+	if err != nil {
+		slog.Warn("failed to get vlan interface statistics", "err", err)
+	}
+	stats := make(map[string]NetworkInterface, len(interfaces))
+	for _, iface := range interfaces {
+		stats[iface.Name] = iface
+	}
+
+	for _, vlan := range vlans {
+		ch <- prometheus.MustNewConstMetric(
+			c.info,
+			prometheus.GaugeValue,
+			1,
+			vlan.Interface, vlan.ID, vlan.Parent,
+		)
+
+		iface, ok := stats[vlan.Interface]
+		if !ok {
+			continue
+		}
+		counters := []struct {
+			desc  *prometheus.Desc
+			value uint64
+		}{
+			{c.rxBytes, iface.RxBytes},
+			{c.txBytes, iface.TxBytes},
+			{c.rxPackets, iface.RxPackets},
+			{c.txPackets, iface.TxPackets},
+		}
+		for _, counter := range counters {
+			ch <- prometheus.MustNewConstMetric(
+				counter.desc,
+				prometheus.CounterValue,
+				float64(counter.value),
+				vlan.Interface, vlan.ID, vlan.Parent,
+			)
+		}
+	}
+}
+
+// 802.1q vlan interface
+type VLAN struct {
+	Interface string
+	ID        string
+	Parent    string
+}
+
+// get vlan interfaces from /proc/net/vlan/config
+// format: "<interface> | <vlan id> | <parent>" after two header lines
+func getVLANs() ([]VLAN, error) {
+	file, err := os.Open("/proc/net/vlan/config")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var vlans []VLAN
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// the "VLAN Dev name | VLAN ID" and "Name-Type: ..." headers have fewer columns
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) != 3 {
+			continue
+		}
+		vlans = append(vlans, VLAN{
+			Interface: strings.TrimSpace(fields[0]),
+			ID:        strings.TrimSpace(fields[1]),
+			Parent:    strings.TrimSpace(fields[2]),
+		})
+	}
+
+	return vlans, scanner.Err()
+}
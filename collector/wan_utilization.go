@@ -0,0 +1,261 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// wan uplink utilization collector
+type WANUtilizationCollector struct {
+	bandwidth   *prometheus.Desc
+	utilization *prometheus.Desc
+	config      *WANUtilizationConfig
+
+	mu      sync.Mutex
+	samples map[string][]trafficSample
+}
+
+// wan utilization configuration
+type WANUtilizationConfig struct {
+	Links          []WANLink
+	SampleInterval time.Duration
+	Window         time.Duration
+}
+
+// nominal bandwidth of a wan link in bits per second
+type WANLink struct {
+	Interface string
+	Download  float64
+	Upload    float64
+}
+
+// interface byte counters at a point in time
+type trafficSample struct {
+	time    time.Time
+	rxBytes uint64
+	txBytes uint64
+}
+
+// create a new wan utilization collector
+func NewWANUtilizationCollector(config *WANUtilizationConfig) *WANUtilizationCollector {
+	c := &WANUtilizationCollector{
+		bandwidth: prometheus.NewDesc(
+			"openwrt_wan_bandwidth_bits_per_second",
+			"configured nominal wan bandwidth in bits per second",
+			[]string{"interface", "direction"}, nil,
+		),
+		utilization: prometheus.NewDesc(
+			"openwrt_wan_utilization_percent",
+			"wan bandwidth utilization percentage over the given window",
+			[]string{"interface", "direction", "window"}, nil,
+		),
+		config:  config,
+		samples: make(map[string][]trafficSample),
+	}
+
+	if len(c.config.Links) > 0 {
+		goBackground(c.sample)
+	}
+
+	return c
+}
+
+// describe implements prometheus.Collector
+func (c *WANUtilizationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.bandwidth
+	ch <- c.utilization
+}
+
+// collect implements prometheus.Collector
+func (c *WANUtilizationCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	window := formatWindow(c.config.Window)
+	for _, link := range c.config.Links {
+		ch <- prometheus.MustNewConstMetric(
+			c.bandwidth,
+			prometheus.GaugeValue,
+			link.Download,
+			link.Interface, "download",
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.bandwidth,
+			prometheus.GaugeValue,
+			link.Upload,
+			link.Interface, "upload",
+		)
+
+		samples := c.samples[link.Interface]
+		if len(samples) < 2 {
+			continue
+		}
+
+		last := samples[len(samples)-1]
+		for _, w := range []struct {
+			name  string
+			first trafficSample
+		}{
+			{"instant", samples[len(samples)-2]},
+			{window, samples[0]},
+		} {
+			seconds := last.time.Sub(w.first.time).Seconds()
+			if seconds <= 0 || last.rxBytes < w.first.rxBytes || last.txBytes < w.first.txBytes {
+				continue
+			}
+
+			rxBits := float64(last.rxBytes-w.first.rxBytes) * 8 / seconds
+			txBits := float64(last.txBytes-w.first.txBytes) * 8 / seconds
+
+			if link.Download > 0 {
+				ch <- prometheus.MustNewConstMetric(
+					c.utilization,
+					prometheus.GaugeValue,
+					rxBits/link.Download*100,
+					link.Interface, "download", w.name,
+				)
+			}
+			if link.Upload > 0 {
+				ch <- prometheus.MustNewConstMetric(
+					c.utilization,
+					prometheus.GaugeValue,
+					txBits/link.Upload*100,
+					link.Interface, "upload", w.name,
+				)
+			}
+		}
+	}
+}
+
+// periodically sample wan interface counters
+func (c *WANUtilizationCollector) sample(ctx context.Context) {
+	ticker := time.NewTicker(c.config.SampleInterval)
+	defer ticker.Stop()
+
+	for running := true; running; running = nextTick(ctx, ticker) {
+		interfaces, err := getNetworkInterfaces()
+		if err != nil {
+			slog.Error("error sampling wan traffic", "err", err)
+			continue
+		}
+
+		now := time.Now()
+		c.mu.Lock()
+		for _, iface := range interfaces {
+			if !c.isWANInterface(iface.Name) {
+				continue
+			}
+
+			samples := append(c.samples[iface.Name], trafficSample{
+				time:    now,
+				rxBytes: iface.RxBytes,
+				txBytes: iface.TxBytes,
+			})
+
+			// keep only samples inside the window
+			for len(samples) > 2 && now.Sub(samples[1].time) >= c.config.Window {
+				samples = samples[1:]
+			}
+			c.samples[iface.Name] = samples
+		}
+		c.mu.Unlock()
+	}
+}
+
+// check whether an interface is a configured wan link
+func (c *WANUtilizationCollector) isWANInterface(name string) bool {
+	for _, link := range c.config.Links {
+		if link.Interface == name {
+			return true
+		}
+	}
+	return false
+}
+
+// load wan utilization configuration from environment variables or sqm config
+func loadWANUtilizationConfig() *WANUtilizationConfig {
+	config := &WANUtilizationConfig{
+		SampleInterval: 5 * time.Second,
+		Window:         5 * time.Minute,
+	}
+
+	// wan_bandwidth: comma-separated list of <interface>:<download_mbit>:<upload_mbit>
+	if bandwidthEnv := os.Getenv("WAN_BANDWIDTH"); bandwidthEnv != "" {
+		for _, entry := range strings.Split(bandwidthEnv, ",") {
+			fields := strings.Split(strings.TrimSpace(entry), ":")
+			if len(fields) != 3 || fields[0] == "" {
+				slog.Warn("invalid WAN_BANDWIDTH entry", "entry", entry)
+				continue
+			}
+			download, _ := strconv.ParseFloat(fields[1], 64)
+			upload, _ := strconv.ParseFloat(fields[2], 64)
+			config.Links = append(config.Links, WANLink{
+				Interface: fields[0],
+				Download:  download * 1000 * 1000,
+				Upload:    upload * 1000 * 1000,
+			})
+		}
+	} else {
+		config.Links = loadSQMLinks()
+	}
+
+	// wan_sample_interval: interval between wan traffic samples
+	if intervalEnv := os.Getenv("WAN_SAMPLE_INTERVAL"); intervalEnv != "" {
+		if interval, err := time.ParseDuration(intervalEnv); err == nil && interval > 0 {
+			config.SampleInterval = interval
+		}
+	}
+
+	return config
+}
+
+// load nominal wan bandwidth from enabled sqm queues (rates are in kbit/s)
+func loadSQMLinks() []WANLink {
+	sections, err := loadUCIConfig("sqm")
+	if err != nil {
+		return nil
+	}
+
+	var links []WANLink
+	for _, section := range sections {
+		if section.Type != "queue" || section.Option("enabled") != "1" || section.Option("interface") == "" {
+			continue
+		}
+		download, _ := strconv.ParseFloat(section.Option("download"), 64)
+		upload, _ := strconv.ParseFloat(section.Option("upload"), 64)
+		links = append(links, WANLink{
+			Interface: section.Option("interface"),
+			Download:  download * 1000,
+			Upload:    upload * 1000,
+		})
+	}
+
+	return links
+}
+
+// format a window duration as a short label (e.g. "5m", "1h")
+func formatWindow(window time.Duration) string {
+	hours := int64(window / time.Hour)
+	minutes := int64(window % time.Hour / time.Minute)
+	seconds := int64(window % time.Minute / time.Second)
+
+	// zero components are only dropped after a larger unit, "1h0m30s" keeps its minutes
+	var label string
+	if hours > 0 {
+		label += strconv.FormatInt(hours, 10) + "h"
+	}
+	if minutes > 0 || (hours > 0 && seconds > 0) {
+		label += strconv.FormatInt(minutes, 10) + "m"
+	}
+	if seconds > 0 || label == "" {
+		label += strconv.FormatInt(seconds, 10) + "s"
+	}
+	return label
+}
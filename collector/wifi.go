@@ -0,0 +1,233 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// radio, survey and interface lookups shared with the device, presence and energy collectors,
+// so they are also built with the nowifi tag
+
+// wireless radio information
+type WirelessRadio struct {
+	Interface string
+	Phy       string
+	SSID      string
+	Band      string
+	HWMode    string
+	HTMode    string
+	Mode      string
+	Country   string
+	Channel   int
+	Frequency int
+	Bandwidth int
+	TxPower   int
+	Noise     int
+}
+
+// iwinfo info response from ubus
+type iwinfoInfo struct {
+	Phy       string `json:"phy"`
+	SSID      string `json:"ssid"`
+	Mode      string `json:"mode"`
+	Country   string `json:"country"`
+	Channel   int    `json:"channel"`
+	Frequency int    `json:"frequency"`
+	TxPower   int    `json:"txpower"`
+	Noise     int    `json:"noise"`
+	HWMode    string `json:"hwmode"`
+	HTMode    string `json:"htmode"`
+}
+
+// get wireless radios from ubus iwinfo
+func getWirelessRadios(ctx context.Context) ([]WirelessRadio, error) {
+	var devices struct {
+		Devices []string `json:"devices"`
+	}
+	if err := ubusCall(ctx, "iwinfo", "devices", nil, &devices); err != nil {
+		return nil, err
+	}
+
+	var radios []WirelessRadio
+	for _, device := range devices.Devices {
+		info, err := getIwinfoInfo(ctx, device)
+		if err != nil {
+			slog.Error("error getting iwinfo", "device", device, "err", err)
+			continue
+		}
+
+		radios = append(radios, WirelessRadio{
+			Interface: device,
+			Phy:       info.Phy,
+			SSID:      info.SSID,
+			Band:      wirelessBand(info.Frequency),
+			HWMode:    info.HWMode,
+			HTMode:    info.HTMode,
+			Mode:      info.Mode,
+			Country:   info.Country,
+			Channel:   info.Channel,
+			Frequency: info.Frequency,
+			Bandwidth: htmodeBandwidth(info.HTMode),
+			TxPower:   info.TxPower,
+			Noise:     info.Noise,
+		})
+	}
+
+	return radios, nil
+}
+
+// get iwinfo information for a single wireless device
+func getIwinfoInfo(ctx context.Context, device string) (*iwinfoInfo, error) {
+	var info iwinfoInfo
+	if err := ubusCall(ctx, "iwinfo", "info", map[string]string{"device": device}, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// map frequency in MHz to a wireless band name
+func wirelessBand(frequency int) string {
+	switch {
+	case frequency >= 2400 && frequency < 2500:
+		return "2.4GHz"
+	case frequency >= 5150 && frequency < 5925:
+		return "5GHz"
+	case frequency >= 5925 && frequency < 7125:
+		return "6GHz"
+	case frequency >= 57000:
+		return "60GHz"
+	default:
+		return ""
+	}
+}
+
+// parse channel bandwidth in MHz from htmode (e.g. HT20, HT40+, VHT80+80, HE160)
+func htmodeBandwidth(htmode string) int {
+	width := strings.TrimLeft(htmode, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	width = strings.TrimSuffix(width, "-")
+
+	bandwidth := 0
+	for _, part := range strings.Split(width, "+") {
+		if part == "" {
+			continue
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		bandwidth += value
+	}
+
+	return bandwidth
+}
+
+// wireless channel survey information
+type WirelessSurvey struct {
+	Interface  string
+	Frequency  int
+	InUse      bool
+	Noise      float64
+	ActiveMs   float64
+	BusyMs     float64
+	ReceiveMs  float64
+	TransmitMs float64
+}
+
+// get channel surveys for all wireless interfaces
+func getWirelessSurveys(ctx context.Context) ([]WirelessSurvey, error) {
+	interfaces, err := getWirelessInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var surveys []WirelessSurvey
+	for _, iface := range interfaces {
+		output, err := runCommand(ctx, "iw", "dev", iface, "survey", "dump")
+		if err != nil {
+			slog.Error("error getting channel survey", "interface", iface, "err", err)
+			continue
+		}
+
+		ifaceSurveys, err := parseSurveyDump(iface, string(output))
+		if err != nil {
+			slog.Error("error parsing channel survey", "interface", iface, "err", err)
+			continue
+		}
+		surveys = append(surveys, ifaceSurveys...)
+	}
+
+	return surveys, nil
+}
+
+// list wireless interfaces from /sys/class/net
+func getWirelessInterfaces() ([]string, error) {
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join("/sys/class/net", entry.Name(), "phy80211")); err == nil {
+			interfaces = append(interfaces, entry.Name())
+		}
+	}
+
+	return interfaces, nil
+}
+
+// parse output of 'iw dev <if> survey dump' command
+func parseSurveyDump(iface string, output string) ([]WirelessSurvey, error) {
+	var surveys []WirelessSurvey
+	var current *WirelessSurvey
+	scanner := bufio.NewScanner(strings.NewReader(output))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// each channel starts with "Survey data from <if>"
+		if strings.HasPrefix(line, "Survey data from") {
+			surveys = append(surveys, WirelessSurvey{Interface: iface})
+			current = &surveys[len(surveys)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		number, _ := strconv.ParseFloat(fields[0], 64)
+
+		switch strings.TrimSpace(key) {
+		case "frequency":
+			current.Frequency = int(number)
+			current.InUse = strings.Contains(value, "[in use]")
+		case "noise":
+			current.Noise = number
+		case "channel active time":
+			current.ActiveMs = number
+		case "channel busy time":
+			current.BusyMs = number
+		case "channel receive time":
+			current.ReceiveMs = number
+		case "channel transmit time":
+			current.TransmitMs = number
+		}
+	}
+
+	return surveys, scanner.Err()
+}
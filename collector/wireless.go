@@ -0,0 +1,138 @@
+//go:build !nowifi
+
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// wireless radio metrics collector
+type WirelessCollector struct {
+	radioInfo      *prometheus.Desc
+	radioChannel   *prometheus.Desc
+	radioFrequency *prometheus.Desc
+	radioBandwidth *prometheus.Desc
+	radioTxPower   *prometheus.Desc
+	radioNoise     *prometheus.Desc
+}
+
+// create a new wireless collector
+func NewWirelessCollector() *WirelessCollector {
+	labels := []string{"interface", "phy"}
+
+	return &WirelessCollector{
+		radioInfo: prometheus.NewDesc(
+			"openwrt_wireless_radio_info",
+			"information about wireless radios",
+			[]string{"interface", "phy", "ssid", "band", "hwmode", "htmode", "mode", "country"}, nil,
+		),
+		radioChannel: prometheus.NewDesc(
+			"openwrt_wireless_radio_channel",
+			"current wireless channel",
+			labels, nil,
+		),
+		radioFrequency: prometheus.NewDesc(
+			"openwrt_wireless_radio_frequency_mhz",
+			"current wireless frequency in MHz",
+			labels, nil,
+		),
+		radioBandwidth: prometheus.NewDesc(
+			"openwrt_wireless_radio_bandwidth_mhz",
+			"current wireless channel bandwidth in MHz",
+			labels, nil,
+		),
+		radioTxPower: prometheus.NewDesc(
+			"openwrt_wireless_radio_txpower_dbm",
+			"current wireless transmit power in dBm",
+			labels, nil,
+		),
+		radioNoise: prometheus.NewDesc(
+			"openwrt_wireless_radio_noise_dbm",
+			"current wireless noise floor in dBm",
+			labels, nil,
+		),
+	}
+}
+
+// describe implements prometheus.Collector
+func (c *WirelessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.radioInfo
+	ch <- c.radioChannel
+	ch <- c.radioFrequency
+	ch <- c.radioBandwidth
+	ch <- c.radioTxPower
+	ch <- c.radioNoise
+}
+
+// collect implements prometheus.Collector
+func (c *WirelessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// collect implements ContextCollector
+func (c *WirelessCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	radios, err := getWirelessRadios(ctx)
+	if err != nil {
+		logCollectError("wireless", err)
+		return
+	}
+
+	for _, radio := range radios {
+		ch <- prometheus.MustNewConstMetric(
+			c.radioInfo,
+			prometheus.GaugeValue,
+			1,
+			radio.Interface,
+			radio.Phy,
+			radio.SSID,
+			radio.Band,
+			radio.HWMode,
+			radio.HTMode,
+			radio.Mode,
+			radio.Country,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.radioChannel,
+			prometheus.GaugeValue,
+			float64(radio.Channel),
+			radio.Interface, radio.Phy,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.radioFrequency,
+			prometheus.GaugeValue,
+			float64(radio.Frequency),
+			radio.Interface, radio.Phy,
+		)
+
+		// bandwidth is only known when htmode can be parsed
+		if radio.Bandwidth > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.radioBandwidth,
+				prometheus.GaugeValue,
+				float64(radio.Bandwidth),
+				radio.Interface, radio.Phy,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.radioTxPower,
+			prometheus.GaugeValue,
+			float64(radio.TxPower),
+			radio.Interface, radio.Phy,
+		)
+
+		// noise is reported as 0 by drivers that do not support it
+		if radio.Noise != 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.radioNoise,
+				prometheus.GaugeValue,
+				float64(radio.Noise),
+				radio.Interface, radio.Phy,
+			)
+		}
+	}
+}
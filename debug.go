@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// register pprof profiles and runtime variables (memstats, cmdline) under /debug/
+func registerDebug(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+}
+
+// serve the debug endpoints on their own listener until ctx is cancelled
+func serveDebug(ctx context.Context, listener net.Listener) {
+	mux := http.NewServeMux()
+	registerDebug(mux)
+	server := &http.Server{Handler: mux}
+
+	// profiles take up to their requested duration, so they are cut off instead of awaited
+	stop := context.AfterFunc(ctx, func() { _ = server.Close() })
+	defer stop()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("error serving debug endpoints", "err", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovinc/openwrt-metrics/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// run the diff subcommand: gather twice and print which series appeared, disappeared or changed
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	delay := flags.Duration("delay", 10*time.Second, "delay between the two gathers")
+	names := flags.String("collectors", "", "comma-separated collectors to compare (default: all)")
+	showChanged := flags.Bool("changed", true, "also print series whose value changed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	collectors, err := collector.All(loadConfig())
+	if err != nil {
+		return err
+	}
+	include := parseCollectorNames(*names)
+	if err := validateCollectorNames(collectors, include); err != nil {
+		return err
+	}
+	registry := newViewRegistry(collectors, func(name string) bool {
+		return len(include) == 0 || include[name]
+	})
+
+	before, err := gatherSeries(registry)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "gathered %d series, waiting %s\n", len(before), *delay)
+	time.Sleep(*delay)
+	after, err := gatherSeries(registry)
+	if err != nil {
+		return err
+	}
+
+	printSeriesDiff(os.Stdout, before, after, *showChanged)
+	return nil
+}
+
+// gather a registry into a map of series (name and labels) to value
+func gatherSeries(gatherer prometheus.Gatherer) (map[string]float64, error) {
+	families, err := gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, err
+	}
+
+	series := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			labels := formatLabels(metric.Label)
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				series[family.GetName()+labels] = metric.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				series[family.GetName()+labels] = metric.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				series[family.GetName()+labels] = metric.GetUntyped().GetValue()
+			case dto.MetricType_SUMMARY:
+				series[family.GetName()+"_count"+labels] = float64(metric.GetSummary().GetSampleCount())
+				series[family.GetName()+"_sum"+labels] = metric.GetSummary().GetSampleSum()
+			case dto.MetricType_HISTOGRAM:
+				series[family.GetName()+"_count"+labels] = float64(metric.GetHistogram().GetSampleCount())
+				series[family.GetName()+"_sum"+labels] = metric.GetHistogram().GetSampleSum()
+			}
+		}
+	}
+	return series, nil
+}
+
+// format labels in exposition format, e.g. {interface="eth0"}
+func formatLabels(labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		parts = append(parts, label.GetName()+"="+strconv.Quote(label.GetValue()))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// print added (+), removed (-) and changed (~) series in name order, followed by a summary
+func printSeriesDiff(w io.Writer, before, after map[string]float64, showChanged bool) {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var added, removed, changed int
+	for _, key := range keys {
+		old, hadOld := before[key]
+		current, hasCurrent := after[key]
+		switch {
+		case !hadOld:
+			added++
+			fmt.Fprintf(w, "+ %s %v\n", key, current)
+		case !hasCurrent:
+			removed++
+			fmt.Fprintf(w, "- %s %v\n", key, old)
+		case old != current && !(math.IsNaN(old) && math.IsNaN(current)):
+			changed++
+			if showChanged {
+				fmt.Fprintf(w, "~ %s %v -> %v\n", key, old, current)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "%d added, %d removed, %d changed, %d series total\n", added, removed, changed, len(after))
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// file suffix of stored history snapshots (gzip compressed delimited protobuf)
+const historySuffix = ".pb.gz"
+
+// ring buffer of periodic metric snapshots stored on the router
+type historyRecorder struct {
+	gatherer prometheus.Gatherer
+	dir      string
+	interval time.Duration
+	maxSize  int64
+}
+
+// create a new history recorder
+func newHistoryRecorder(gatherer prometheus.Gatherer, dir string, interval time.Duration, maxSize int64) *historyRecorder {
+	return &historyRecorder{
+		gatherer: gatherer,
+		dir:      dir,
+		interval: interval,
+		maxSize:  maxSize,
+	}
+}
+
+// periodically record snapshots, dropping the oldest ones beyond the size limit
+func (h *historyRecorder) run(ctx context.Context) {
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		slog.Error("error creating history directory", "err", err)
+		return
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.record(time.Now()); err != nil {
+			slog.Error("error recording history snapshot", "err", err)
+		}
+		if err := h.trim(); err != nil {
+			slog.Error("error trimming history", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// gather the registry and write a timestamped snapshot file
+func (h *historyRecorder) record(now time.Time) error {
+	families, err := h.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+
+	timestamp := now.UnixMilli()
+	path := filepath.Join(h.dir, strconv.FormatInt(timestamp, 10)+historySuffix)
+	tmp := path + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(file)
+	encoder := expfmt.NewEncoder(zw, expfmt.NewFormat(expfmt.TypeProtoDelim))
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.TimestampMs = &timestamp
+		}
+		if err = encoder.Encode(family); err != nil {
+			break
+		}
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// history snapshot file
+type historySnapshot struct {
+	path      string
+	timestamp int64
+	size      int64
+}
+
+// list stored snapshots, oldest first
+func (h *historyRecorder) snapshots() ([]historySnapshot, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []historySnapshot
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), historySuffix)
+		if !ok {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, historySnapshot{
+			path:      filepath.Join(h.dir, entry.Name()),
+			timestamp: timestamp,
+			size:      info.Size(),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].timestamp < snapshots[j].timestamp
+	})
+	return snapshots, nil
+}
+
+// remove the oldest snapshots until the ring buffer fits its size limit
+func (h *historyRecorder) trim() error {
+	snapshots, err := h.snapshots()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, snapshot := range snapshots {
+		total += snapshot.size
+	}
+
+	for _, snapshot := range snapshots {
+		if total <= h.maxSize {
+			break
+		}
+		if err := os.Remove(snapshot.path); err != nil {
+			return err
+		}
+		total -= snapshot.size
+	}
+	return nil
+}
+
+// http handler serving the last n hours as gzip compressed openmetrics
+func (h *historyRecorder) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hours := 1.0
+		if hoursParam := r.URL.Query().Get("hours"); hoursParam != "" {
+			parsed, err := strconv.ParseFloat(hoursParam, 64)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "invalid hours parameter", http.StatusBadRequest)
+				return
+			}
+			hours = parsed
+		}
+
+		snapshots, err := h.snapshots()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error reading history: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// nothing older than the oldest retained snapshot can be served, so larger ranges are capped to it
+		now := time.Now()
+		if len(snapshots) > 0 {
+			hours = min(hours, now.Sub(time.UnixMilli(snapshots[0].timestamp)).Hours())
+		}
+		since := now.Add(-time.Duration(hours * float64(time.Hour))).UnixMilli()
+		for len(snapshots) > 0 && snapshots[0].timestamp < since {
+			snapshots = snapshots[1:]
+		}
+
+		w.Header().Set("Content-Type", string(expfmt.NewFormat(expfmt.TypeOpenMetrics)))
+		w.Header().Set("Content-Encoding", "gzip")
+
+		zw := gzip.NewWriter(w)
+		encoder := expfmt.NewEncoder(zw, expfmt.NewFormat(expfmt.TypeOpenMetrics))
+		err = mergeHistorySnapshots(snapshots, func(family *dto.MetricFamily) error {
+			return encoder.Encode(family)
+		})
+		if err != nil {
+			slog.Error("error encoding history", "err", err)
+		}
+		if closer, ok := encoder.(expfmt.Closer); ok {
+			_ = closer.Close()
+		}
+		_ = zw.Close()
+	})
+}
+
+// merge snapshots into one family per metric name and pass them to emit in name order;
+// snapshots hold their families sorted by name as gathered, so they are decoded side by side
+// and only the samples of one family are held in memory at a time
+func mergeHistorySnapshots(snapshots []historySnapshot, emit func(*dto.MetricFamily) error) error {
+	var readers []*historyReader
+	defer func() {
+		for _, reader := range readers {
+			reader.close()
+		}
+	}()
+	for _, snapshot := range snapshots {
+		reader, err := openHistorySnapshot(snapshot.path)
+		if err != nil {
+			slog.Warn("skipping history snapshot", "path", snapshot.path, "err", err)
+			continue
+		}
+		readers = append(readers, reader)
+	}
+
+	for {
+		// the smallest pending name across all snapshots is the next family
+		var name string
+		found := false
+		for _, reader := range readers {
+			if reader.next != nil && (!found || reader.next.GetName() < name) {
+				name = reader.next.GetName()
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+
+		var family *dto.MetricFamily
+		for _, reader := range readers {
+			if reader.next == nil || reader.next.GetName() != name {
+				continue
+			}
+			if family == nil {
+				family = reader.next
+			} else if family.GetType() == reader.next.GetType() {
+				family.Metric = append(family.Metric, reader.next.Metric...)
+			}
+			reader.advance()
+		}
+
+		// keep the samples of each series together, in time order
+		sort.SliceStable(family.Metric, func(i, j int) bool {
+			return labelSignature(family.Metric[i]) < labelSignature(family.Metric[j])
+		})
+		if err := emit(family); err != nil {
+			return err
+		}
+	}
+}
+
+// decoder of a snapshot file, positioned at its next family
+type historyReader struct {
+	path    string
+	file    *os.File
+	zr      *gzip.Reader
+	decoder expfmt.Decoder
+	next    *dto.MetricFamily
+}
+
+// open a snapshot file and decode its first family
+func openHistorySnapshot(path string) (*historyReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	reader := &historyReader{
+		path:    path,
+		file:    file,
+		zr:      zr,
+		decoder: expfmt.NewDecoder(zr, expfmt.NewFormat(expfmt.TypeProtoDelim)),
+	}
+	reader.advance()
+	return reader, nil
+}
+
+// decode the next family, closing the file at its end
+func (r *historyReader) advance() {
+	family := &dto.MetricFamily{}
+	if err := r.decoder.Decode(family); err != nil {
+		if !errors.Is(err, io.EOF) {
+			slog.Warn("skipping rest of history snapshot", "path", r.path, "err", err)
+		}
+		r.close()
+		return
+	}
+	r.next = family
+}
+
+// close the snapshot file
+func (r *historyReader) close() {
+	r.next = nil
+	if r.file == nil {
+		return
+	}
+	_ = r.zr.Close()
+	_ = r.file.Close()
+	r.file = nil
+}
+
+// build a sortable signature of a metric's labels
+func labelSignature(metric *dto.Metric) string {
+	var b strings.Builder
+	for _, label := range metric.Label {
+		b.WriteString(label.GetName())
+		b.WriteByte('=')
+		b.WriteString(label.GetValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
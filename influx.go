@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// escapes of measurement names and of tag keys and values in line protocol
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// periodic writer of gathered metrics to an influxdb write endpoint in line protocol
+type influxPusher struct {
+	gatherer prometheus.Gatherer
+	url      string
+	interval time.Duration
+	token    string
+	host     string
+	client   *http.Client
+}
+
+// create a new influxdb pusher, the url is the v1 (/write?db=) or v2 (/api/v2/write?org=&bucket=) write endpoint
+func newInfluxPusher(gatherer prometheus.Gatherer, writeURL string, interval time.Duration, token string) (*influxPusher, error) {
+	u, err := url.Parse(writeURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported influxdb url scheme %q", u.Scheme)
+	}
+
+	// timestamps are written in seconds
+	query := u.Query()
+	query.Set("precision", "s")
+	u.RawQuery = query.Encode()
+
+	host, _ := os.Hostname()
+	return &influxPusher{
+		gatherer: gatherer,
+		url:      u.String(),
+		interval: interval,
+		token:    token,
+		host:     host,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// write metrics every interval until ctx is cancelled
+func (p *influxPusher) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.push(time.Now()); err != nil {
+			slog.Error("error writing metrics to influxdb", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// gather the registry and write all samples in one request
+func (p *influxPusher) push(now time.Time) error {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+
+	var body bytes.Buffer
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	for _, family := range families {
+		measurement := influxMeasurementEscaper.Replace(family.GetName())
+		for _, m := range family.GetMetric() {
+			value := sampleValue(family.GetType(), m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			// line: measurement,tag=value,... value=<float> <timestamp>, tags sorted by key as influxdb prefers
+			tags := map[string]string{"host": p.host}
+			for _, label := range m.GetLabel() {
+				tags[label.GetName()] = label.GetValue()
+			}
+			keys := make([]string, 0, len(tags))
+			for key := range tags {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			body.WriteString(measurement)
+			for _, key := range keys {
+				// influxdb rejects empty tag values
+				if tags[key] == "" {
+					continue
+				}
+				body.WriteString("," + influxTagEscaper.Replace(key) + "=" + influxTagEscaper.Replace(tags[key]))
+			}
+			body.WriteString(" value=" + strconv.FormatFloat(value, 'g', -1, 64) + " " + timestamp + "\n")
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Token "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -1,24 +1,64 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/ovinc/openwrt-metrics/collector"
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
-	listenAddress = flag.String("listen-address", ":9101", "address to listen on for metrics")
-	metricsPath   = flag.String("metrics-path", "/metrics", "path under which to expose metrics")
-	version       = flag.Bool("version", false, "show version information")
+	listenAddress    = flag.String("listen-address", ":9101", "address to listen on for metrics")
+	webConfigFile    = flag.String("web-config-file", "", "path to a web configuration file in the exporter-toolkit format, e.g. enabling tls")
+	metricsPath      = flag.String("metrics-path", "/metrics", "path under which to expose metrics")
+	streamInterval   = flag.Duration("stream-interval", 2*time.Second, "interval between websocket stream snapshots (1s-5s)")
+	lightCollectors  = flag.String("light-collectors", "network,network_role,wan_utilization,interface_ip,conntrack,memory,filesystem,thermal,hwmon,energy", "comma-separated collectors exposed on <metrics-path>/light")
+	fullCollectors   = flag.String("full-collectors", "", "comma-separated collectors exposed on <metrics-path>/full (default: all collectors not in the light view)")
+	scrapeWorkers    = flag.Int("scrape-workers", 4, "maximum number of collectors running in parallel during a scrape")
+	historyDir       = flag.String("history-dir", "", "directory for the on-router snapshot ring buffer (disabled if empty)")
+	historyInterval  = flag.Duration("history-interval", time.Minute, "interval between history snapshots")
+	historySize      = flag.Int64("history-size", 16*1024*1024, "maximum total size of history snapshots in bytes")
+	mqttBroker       = flag.String("mqtt-broker", "", "mqtt broker to publish metrics to, e.g. tcp://192.168.1.10:1883 or mqtts://broker:8883 (disabled if empty)")
+	mqttTopic        = flag.String("mqtt-topic", "openwrt", "topic prefix of published metrics")
+	mqttInterval     = flag.Duration("mqtt-interval", 30*time.Second, "interval between mqtt publishes")
+	mqttCollectors   = flag.String("mqtt-collectors", "", "comma-separated collectors published over mqtt (default: all collectors)")
+	mqttUsername     = flag.String("mqtt-username", "", "mqtt username, the password is read from MQTT_PASSWORD")
+	influxURL        = flag.String("influx-url", "", "influxdb write endpoint, e.g. http://host:8086/write?db=openwrt (v1) or http://host:8086/api/v2/write?org=home&bucket=openwrt (v2) (disabled if empty)")
+	influxInterval   = flag.Duration("influx-interval", 30*time.Second, "interval between influxdb writes")
+	influxCollectors = flag.String("influx-collectors", "", "comma-separated collectors written to influxdb (default: all collectors)")
+	enablePprof      = flag.Bool("web-enable-pprof", false, "expose pprof profiles and runtime variables under /debug/ for profiling the exporter")
+	pprofAddress     = flag.String("pprof-listen-address", "", "separate address serving the /debug/ endpoints instead of the metrics listener, e.g. 127.0.0.1:6060")
+	shutdownTimeout  = flag.Duration("shutdown-timeout", 5*time.Second, "maximum time to finish in-flight requests and stop background work on sigint or sigterm")
+	logLevel         = flag.String("log-level", "info", "minimum level of logged messages: debug, info, warn or error")
+	logFormat        = flag.String("log-format", "text", "format of logged messages: text (logfmt) or json")
+	privacyMode      = flag.String("privacy-mode", "", "anonymize client mac and ip labels: hash or truncate (overrides PRIVACY_MODE)")
+	version          = flag.Bool("version", false, "show version information")
+	selfUpdateFlag   = flag.Bool("self-update", false, "download the latest release for this architecture and replace the binary")
 	// Version is set via -ldflags at build time
 	Version = "dev"
 )
 
+// scrape handler options, openmetrics is served to scrapers asking for it, with
+// _created series for counters that know when they started counting
+var scrapeHandlerOpts = promhttp.HandlerOpts{
+	EnableOpenMetrics:                   true,
+	EnableOpenMetricsTextCreatedSamples: true,
+}
+
+// handlers of endpoints that can be compiled out with build tags, keyed by path and
+// registered by the init functions of their files
+var optionalHandlers = make(map[string]func(cfg *collector.Config) http.Handler)
+
 const homePage = `<html>
 <head><title>OpenWRT Exporter</title></head>
 <body>
@@ -30,29 +70,196 @@ const homePage = `<html>
 func main() {
 	flag.Parse()
 
+	// collectors whose source is not installed only log at debug level, so the default level keeps the router's syslog quiet
+	logger, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fatal("invalid logging flags", "err", err)
+	}
+	slog.SetDefault(logger)
+
 	if *version {
 		fmt.Printf("OpenWRT Exporter version %s\n", Version)
 		return
 	}
 
-	log.Printf("starting openwrt exporter version %s on %s", Version, *listenAddress)
+	if *selfUpdateFlag {
+		if err := selfUpdate(); err != nil {
+			fatal("self-update failed", "err", err)
+		}
+		return
+	}
+
+	if *privacyMode != "" && !collector.ValidPrivacyMode(*privacyMode) {
+		fatal("invalid privacy mode", "value", *privacyMode)
+	}
+
+	// 'diff' subcommand compares two gathers instead of serving metrics
+	if flag.Arg(0) == "diff" {
+		if err := runDiff(flag.Args()[1:]); err != nil {
+			fatal("diff failed", "err", err)
+		}
+		return
+	}
+
+	slog.Info("starting openwrt exporter", "version", Version, "address", *listenAddress)
+
+	// web configuration is checked before any collector starts background work
+	var web *webConfig
+	if *webConfigFile != "" {
+		var err error
+		if web, err = loadWebConfig(*webConfigFile); err != nil {
+			fatal("invalid web config file", "path", *webConfigFile, "err", err)
+		}
+	}
+
+	// create collectors, each usable by name in scrape views
+	// on sigint or sigterm, in-flight requests are finished before background work is stopped
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 
-	// create custom registry
-	registry := prometheus.NewRegistry()
+	// background work of the exporter itself, collectors track theirs
+	var background sync.WaitGroup
+	goBackground := func(run func(ctx context.Context)) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			run(ctx)
+		}()
+	}
 
-	// register collectors
-	registry.MustRegister(collector.NewNetworkCollector())
-	registry.MustRegister(collector.NewDeviceCollector())
-	registry.MustRegister(collector.NewInterfaceIPCollector())
-	registry.MustRegister(collector.NewPingCollector())
-	registry.MustRegister(collector.NewUPnPCollector())
+	cfg := loadConfig()
+	cfg.Context = ctx
+	collectors, err := collector.All(cfg)
+	if err != nil {
+		fatal("error creating collectors", "err", err)
+	}
+
+	light := parseCollectorNames(*lightCollectors)
+	full := parseCollectorNames(*fullCollectors)
+	published := parseCollectorNames(*mqttCollectors)
+	written := parseCollectorNames(*influxCollectors)
+	for _, names := range []map[string]bool{light, full, published, written} {
+		if err := validateCollectorNames(collectors, names); err != nil {
+			fatal("invalid scrape view", "err", err)
+		}
+	}
+
+	// main registry with all collectors, plus light and full scrape views
+	registry := newViewRegistry(collectors, func(string) bool { return true })
+	lightRegistry := newViewRegistry(collectors, func(name string) bool { return light[name] })
+	fullRegistry := newViewRegistry(collectors, func(name string) bool {
+		if len(full) > 0 {
+			return full[name]
+		}
+		return !light[name]
+	})
 
-	// setup http handler
-	http.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	// setup http handler, on a dedicated mux so debug endpoints are only served when enabled
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, promhttp.HandlerFor(registry, scrapeHandlerOpts))
+	mux.Handle(*metricsPath+"/light", promhttp.HandlerFor(lightRegistry, scrapeHandlerOpts))
+	mux.Handle(*metricsPath+"/full", promhttp.HandlerFor(fullRegistry, scrapeHandlerOpts))
+	// websocket live stream of metric snapshots
+	interval := min(max(*streamInterval, time.Second), 5*time.Second)
+	mux.Handle("/api/v1/stream", newSnapshotPoller(ctx, registry, interval).handler())
+
+	// json views of the device and interface data
+	registerAPI(mux, collectors)
+
+	// endpoints that can be compiled out, e.g. ad-hoc probes with per-request targets
+	for path, newHandler := range optionalHandlers {
+		mux.Handle(path, newHandler(cfg))
+	}
+
+	// optional profiling of the exporter, preferably on a listener only reachable from the router
+	if *enablePprof {
+		if *pprofAddress == "" {
+			registerDebug(mux)
+		} else {
+			listener, err := net.Listen("tcp", *pprofAddress)
+			if err != nil {
+				fatal("failed to listen for debug endpoints", "err", err)
+			}
+			slog.Info("serving debug endpoints", "address", listener.Addr().String())
+			goBackground(func(ctx context.Context) { serveDebug(ctx, listener) })
+		}
+	}
+
+	// optional ring buffer of snapshots, downloadable as openmetrics
+	if *historyDir != "" {
+		recorder := newHistoryRecorder(registry, *historyDir, *historyInterval, *historySize)
+		goBackground(recorder.run)
+		mux.Handle("/api/v1/history", recorder.handler())
+	}
+
+	// optional publishing to an mqtt broker, e.g. for home assistant
+	if *mqttBroker != "" {
+		mqttRegistry := newViewRegistry(collectors, func(name string) bool {
+			return len(published) == 0 || published[name]
+		})
+		publisher := newMQTTPublisher(mqttRegistry, *mqttBroker, *mqttTopic, *mqttInterval, *mqttUsername, os.Getenv("MQTT_PASSWORD"))
+		goBackground(publisher.run)
+	}
+
+	// optional writes to influxdb in line protocol
+	if *influxURL != "" {
+		influxRegistry := newViewRegistry(collectors, func(name string) bool {
+			return len(written) == 0 || written[name]
+		})
+		pusher, err := newInfluxPusher(influxRegistry, *influxURL, *influxInterval, os.Getenv("INFLUX_TOKEN"))
+		if err != nil {
+			fatal("invalid influxdb url", "err", err)
+		}
+		goBackground(pusher.run)
+	}
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte(fmt.Sprintf(homePage, *metricsPath)))
 	})
 
-	log.Printf("listening on %s, exposing metrics on %s", *listenAddress, *metricsPath)
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	slog.Info("listening", "address", *listenAddress, "metrics_path", *metricsPath)
+	server := &http.Server{Addr: *listenAddress, Handler: mux}
+	served := make(chan error, 1)
+	go func() { served <- serveWeb(server, web) }()
+
+	select {
+	case err := <-served:
+		fatal("error serving http", "err", err)
+	case <-signals.Done():
+	}
+	// a second signal terminates right away
+	stopSignals()
+
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("in-flight requests did not finish in time", "timeout", *shutdownTimeout, "err", err)
+	}
+
+	// cancel commands, probes, samplers, listeners and streams
+	stop()
+	stopped := make(chan struct{})
+	go func() {
+		collector.Wait()
+		background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		slog.Info("stopped")
+	case <-shutdownCtx.Done():
+		slog.Warn("background work did not stop in time", "timeout", *shutdownTimeout)
+	}
+}
+
+// load the collector config from the environment, with command line overrides applied
+func loadConfig() *collector.Config {
+	cfg := collector.LoadConfig(Version)
+	if *privacyMode != "" {
+		cfg.Privacy = &collector.PrivacyConfig{Mode: *privacyMode, Salt: cfg.Privacy.Salt}
+	}
+	return cfg
 }
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// mqtt 3.1.1 control packet types and connect flags
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttDisconnect = 0xe0
+
+	mqttFlagCleanSession = 0x02
+	mqttFlagWill         = 0x04
+	mqttFlagWillRetain   = 0x20
+	mqttFlagPassword     = 0x40
+	mqttFlagUsername     = 0x80
+
+	// publish flag keeping the last message on the broker for new subscribers
+	mqttRetain = 0x01
+)
+
+// timeout for connecting to the broker and writing a batch of messages
+const mqttTimeout = 10 * time.Second
+
+// periodic publisher of gathered metrics to an mqtt broker
+type mqttPublisher struct {
+	gatherer prometheus.Gatherer
+	broker   string
+	topic    string
+	interval time.Duration
+	username string
+	password string
+
+	conn net.Conn
+}
+
+// create a new mqtt publisher
+func newMQTTPublisher(gatherer prometheus.Gatherer, broker, topic string, interval time.Duration, username, password string) *mqttPublisher {
+	return &mqttPublisher{
+		gatherer: gatherer,
+		broker:   broker,
+		topic:    topic,
+		interval: interval,
+		username: username,
+		password: password,
+	}
+}
+
+// publish metrics every interval, reconnecting to the broker after errors, until ctx is cancelled
+func (p *mqttPublisher) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.publishMetrics(); err != nil {
+			slog.Error("error publishing metrics to mqtt broker", "broker", p.broker, "err", err)
+			if p.conn != nil {
+				_ = p.conn.Close()
+				p.conn = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			p.disconnect()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// announce the exporter as offline and disconnect cleanly, which discards the last will
+func (p *mqttPublisher) disconnect() {
+	if p.conn == nil {
+		return
+	}
+	defer func() { _ = p.conn.Close() }()
+
+	if err := p.conn.SetWriteDeadline(time.Now().Add(mqttTimeout)); err != nil {
+		return
+	}
+	if err := writeMQTTPublish(p.conn, p.topic+"/status", []byte("offline")); err != nil {
+		return
+	}
+	_ = writeMQTTPacket(p.conn, mqttDisconnect, nil)
+}
+
+// gather the registry and publish one retained message per metric family
+func (p *mqttPublisher) publishMetrics() error {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.conn.SetWriteDeadline(time.Now().Add(mqttTimeout)); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(p.conn)
+	for _, family := range families {
+		payload, err := json.Marshal(newSnapshotMetric(family))
+		if err != nil {
+			return err
+		}
+		if err := writeMQTTPublish(writer, p.topic+"/"+family.GetName(), payload); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// open a connection to the broker and announce the exporter as online
+func (p *mqttPublisher) connect() error {
+	address, useTLS, err := parseMQTTBroker(p.broker)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	if useTLS {
+		host, _, _ := net.SplitHostPort(address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.handshake(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	p.conn = conn
+	return nil
+}
+
+// send connect with an offline last will, wait for the connack and publish the online status
+func (p *mqttPublisher) handshake(conn net.Conn) error {
+	if err := conn.SetDeadline(time.Now().Add(mqttTimeout)); err != nil {
+		return err
+	}
+
+	// the broker drops clients silent for 1.5 keep alive periods, every interval brings a publish
+	keepAlive := min(max(2*p.interval/time.Second, 10), 65535)
+
+	hostname, _ := os.Hostname()
+	flags := byte(mqttFlagCleanSession | mqttFlagWill | mqttFlagWillRetain)
+	payload := mqttString("openwrt-exporter-" + hostname)
+	payload = append(payload, mqttString(p.topic+"/status")...)
+	payload = append(payload, mqttString("offline")...)
+	if p.username != "" {
+		flags |= mqttFlagUsername
+		payload = append(payload, mqttString(p.username)...)
+		if p.password != "" {
+			flags |= mqttFlagPassword
+			payload = append(payload, mqttString(p.password)...)
+		}
+	}
+
+	// variable header: protocol name, level 4 (3.1.1), flags, keep alive
+	body := append(mqttString("MQTT"), 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive))
+	body = append(body, payload...)
+	if err := writeMQTTPacket(conn, mqttConnect, body); err != nil {
+		return err
+	}
+
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		return err
+	}
+	if ack[0] != mqttConnAck || ack[1] != 2 {
+		return errors.New("unexpected reply to connect")
+	}
+	if ack[3] != 0 {
+		return fmt.Errorf("connection refused by broker (return code %d)", ack[3])
+	}
+
+	if err := writeMQTTPublish(conn, p.topic+"/status", []byte("online")); err != nil {
+		return err
+	}
+	return conn.SetDeadline(time.Time{})
+}
+
+// get the address of a broker url (tcp://host:1883, mqtts://host:8883 or host[:port]) and whether it uses tls
+func parseMQTTBroker(broker string) (string, bool, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("tcp://" + broker)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	useTLS := false
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported mqtt broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// write a retained qos 0 publish packet
+func writeMQTTPublish(w io.Writer, topic string, payload []byte) error {
+	return writeMQTTPacket(w, mqttPublish|mqttRetain, append(mqttString(topic), payload...))
+}
+
+// write a control packet with its variable length encoded remaining length
+func writeMQTTPacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// encode a length prefixed utf-8 string
+func mqttString(value string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(value))), value...)
+}
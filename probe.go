@@ -0,0 +1,65 @@
+//go:build !noprobes
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ovinc/openwrt-metrics/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// probe timeout when prometheus does not send its scrape timeout
+	defaultProbeTimeout = 10 * time.Second
+	// time left to send the response before the scrape times out
+	probeTimeoutOffset = 500 * time.Millisecond
+)
+
+// the probe endpoint is left out of builds with the noprobes tag
+func init() {
+	optionalHandlers["/probe"] = func(cfg *collector.Config) http.Handler { return probeHandler(cfg.Ping) }
+}
+
+// http handler running a single probe per request, /probe?module=ping&target=1.1.1.1
+func probeHandler(ping *collector.PingConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// stay within the scrape timeout like the blackbox exporter
+		timeout := defaultProbeTimeout
+		if header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); header != "" {
+			seconds, err := strconv.ParseFloat(header, 64)
+			if err != nil || seconds <= 0 {
+				http.Error(w, "invalid X-Prometheus-Scrape-Timeout-Seconds header", http.StatusBadRequest)
+				return
+			}
+			timeout = max(time.Duration(seconds*float64(time.Second))-probeTimeoutOffset, probeTimeoutOffset)
+		}
+
+		probe, err := collector.NewProbeCollector(query.Get("module"), query.Get("target"), timeout, ping)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(requestProbe{ProbeCollector: probe, ctx: r.Context()})
+		promhttp.HandlerFor(registry, scrapeHandlerOpts).ServeHTTP(w, r)
+	})
+}
+
+// probe run with the context of its request, so it stops when the client goes away
+type requestProbe struct {
+	*collector.ProbeCollector
+	ctx context.Context
+}
+
+// collect implements prometheus.Collector
+func (p requestProbe) Collect(ch chan<- prometheus.Metric) {
+	p.CollectContext(p.ctx, ch)
+}
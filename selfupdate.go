@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ovinc/openwrt-metrics/collector"
+)
+
+// expected elf machine and byte order for each supported architecture
+var elfArchitectures = map[string]struct {
+	machine elf.Machine
+	order   binary.ByteOrder
+}{
+	"amd64":  {elf.EM_X86_64, binary.LittleEndian},
+	"arm":    {elf.EM_ARM, binary.LittleEndian},
+	"arm64":  {elf.EM_AARCH64, binary.LittleEndian},
+	"mips":   {elf.EM_MIPS, binary.BigEndian},
+	"mipsle": {elf.EM_MIPS, binary.LittleEndian},
+}
+
+// download the latest release for this architecture and replace the running binary
+func selfUpdate() error {
+	release, err := collector.GetLatestRelease()
+	if err != nil {
+		return fmt.Errorf("failed to get latest release: %w", err)
+	}
+
+	if newer, ok := collector.IsNewerVersion(release.TagName, Version); ok && !newer {
+		slog.Info("already running the latest version", "version", Version)
+		return nil
+	}
+
+	latestVersion := strings.TrimPrefix(release.TagName, "v")
+	assetName := fmt.Sprintf("openwrt-exporter-%s-linux-%s", latestVersion, runtime.GOARCH)
+
+	var asset *collector.ReleaseAsset
+	for i := range release.Assets {
+		if release.Assets[i].Name == assetName {
+			asset = &release.Assets[i]
+			break
+		}
+	}
+	if asset == nil {
+		return fmt.Errorf("release %s has no asset %s", release.TagName, assetName)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return err
+	}
+
+	// download next to the executable so the final rename is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(executable), ".openwrt-exporter-update-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	slog.Info("downloading release", "url", asset.BrowserDownloadURL)
+	digest, err := downloadFile(asset.BrowserDownloadURL, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", assetName, err)
+	}
+
+	if err := verifyUpdate(tmp.Name(), asset, digest); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", assetName, err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		return err
+	}
+
+	slog.Info("updated, restart the service to apply", "path", executable, "from", Version, "to", latestVersion)
+	return nil
+}
+
+// download a url into a writer and return the sha256 digest of its content
+func downloadFile(url string, w io.Writer) (string, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), resp.Body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verify the downloaded binary digest and that it targets the running architecture
+func verifyUpdate(path string, asset *collector.ReleaseAsset, digest string) error {
+	if expected, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+		if !strings.EqualFold(expected, digest) {
+			return fmt.Errorf("sha256 mismatch: expected %s, got %s", expected, digest)
+		}
+	} else {
+		slog.Warn("release asset has no sha256 digest, skipping checksum verification")
+	}
+
+	arch, ok := elfArchitectures[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("unsupported architecture %s", runtime.GOARCH)
+	}
+
+	file, err := elf.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	if file.Machine != arch.machine || file.ByteOrder != arch.order {
+		return fmt.Errorf("binary is built for %s (%s), expected %s", file.Machine, file.ByteOrder, runtime.GOARCH)
+	}
+
+	return nil
+}
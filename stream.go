@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/websocket"
+)
+
+// metrics snapshot pushed to stream clients
+type snapshot struct {
+	Timestamp int64            `json:"timestamp"`
+	Metrics   []snapshotMetric `json:"metrics"`
+}
+
+// metric family within a snapshot
+type snapshotMetric struct {
+	Name    string           `json:"name"`
+	Help    string           `json:"help"`
+	Type    string           `json:"type"`
+	Samples []snapshotSample `json:"samples"`
+}
+
+// single sample within a metric family
+type snapshotSample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// background poller that gathers the registry and fans snapshots out to subscribers
+type snapshotPoller struct {
+	ctx      context.Context
+	gatherer prometheus.Gatherer
+	interval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[chan []byte]struct{}
+	running     bool
+}
+
+// create a new snapshot poller, streams are closed once ctx is cancelled
+func newSnapshotPoller(ctx context.Context, gatherer prometheus.Gatherer, interval time.Duration) *snapshotPoller {
+	return &snapshotPoller{
+		ctx:         ctx,
+		gatherer:    gatherer,
+		interval:    interval,
+		subscribers: make(map[chan []byte]struct{}),
+	}
+}
+
+// subscribe to snapshots, starting the poller if it is idle
+func (p *snapshotPoller) subscribe() chan []byte {
+	ch := make(chan []byte, 1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.subscribers[ch] = struct{}{}
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+
+	return ch
+}
+
+// unsubscribe from snapshots
+func (p *snapshotPoller) unsubscribe(ch chan []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.subscribers, ch)
+}
+
+// gather snapshots while there are subscribers
+func (p *snapshotPoller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.mu.Lock()
+		if len(p.subscribers) == 0 || p.ctx.Err() != nil {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		data, err := p.gather()
+		if err != nil {
+			slog.Error("error gathering stream snapshot", "err", err)
+		} else {
+			// drop snapshots for slow clients instead of blocking the poller
+			p.mu.Lock()
+			for ch := range p.subscribers {
+				select {
+				case ch <- data:
+				default:
+				}
+			}
+			p.mu.Unlock()
+		}
+
+		select {
+		case <-p.ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+// gather the registry once and encode it as json
+func (p *snapshotPoller) gather() ([]byte, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, err
+	}
+
+	snap := snapshot{Timestamp: time.Now().Unix()}
+	for _, family := range families {
+		snap.Metrics = append(snap.Metrics, newSnapshotMetric(family))
+	}
+
+	return json.Marshal(snap)
+}
+
+// convert a gathered metric family to its snapshot form
+func newSnapshotMetric(family *dto.MetricFamily) snapshotMetric {
+	metric := snapshotMetric{
+		Name: family.GetName(),
+		Help: family.GetHelp(),
+		Type: family.GetType().String(),
+	}
+
+	for _, m := range family.GetMetric() {
+		sample := snapshotSample{Value: sampleValue(family.GetType(), m)}
+		if len(m.GetLabel()) > 0 {
+			sample.Labels = make(map[string]string)
+			for _, label := range m.GetLabel() {
+				sample.Labels[label.GetName()] = label.GetValue()
+			}
+		}
+		metric.Samples = append(metric.Samples, sample)
+	}
+
+	return metric
+}
+
+// extract the value of a metric depending on its type
+func sampleValue(metricType dto.MetricType, m *dto.Metric) float64 {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue()
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue()
+	case dto.MetricType_SUMMARY:
+		return m.GetSummary().GetSampleSum()
+	case dto.MetricType_HISTOGRAM:
+		return m.GetHistogram().GetSampleSum()
+	default:
+		return 0
+	}
+}
+
+// websocket handler streaming snapshots to a client
+func (p *snapshotPoller) handler() websocket.Handler {
+	return func(ws *websocket.Conn) {
+		defer func() { _ = ws.Close() }()
+
+		ch := p.subscribe()
+		defer p.unsubscribe(ch)
+
+		// detect client disconnects by reading until error
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		for {
+			select {
+			case data := <-ch:
+				if err := websocket.Message.Send(ws, string(data)); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}
+}
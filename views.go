@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ovinc/openwrt-metrics/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// parse a comma-separated list of collector names into a set
+func parseCollectorNames(list string) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// build a registry for a scrape view from the named collectors it includes,
+// which run in parallel on at most -scrape-workers goroutines per gather
+func newViewRegistry(collectors []collector.NamedCollector, include func(name string) bool) *prometheus.Registry {
+	var included []prometheus.Collector
+	for _, c := range collectors {
+		if include(c.Name) {
+			included = append(included, c.Collector)
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector.NewConcurrentCollector(included, *scrapeWorkers))
+	return registry
+}
+
+// check that every name in a view refers to a known collector, collectors compiled out
+// with build tags are skipped with a warning so one config works with every build
+func validateCollectorNames(collectors []collector.NamedCollector, names map[string]bool) error {
+	known := make(map[string]bool, len(collectors))
+	for _, c := range collectors {
+		known[c.Name] = true
+	}
+	for name := range names {
+		switch {
+		case known[name]:
+		case collector.CompiledOut(name):
+			slog.Warn("collector is not included in this build, skipping it", "collector", name)
+		default:
+			return fmt.Errorf("unknown collector %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+
+	"go.yaml.in/yaml/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maximum number of cached successful basic auth checks
+const maxBasicAuthCache = 64
+
+// web configuration file, a subset of the prometheus exporter-toolkit format
+type webConfig struct {
+	TLSServerConfig webTLSConfig `yaml:"tls_server_config"`
+	// bcrypt password hashes by username
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+}
+
+// tls settings of the web configuration file
+type webTLSConfig struct {
+	CertFile   string     `yaml:"cert_file"`
+	KeyFile    string     `yaml:"key_file"`
+	MinVersion tlsVersion `yaml:"min_version"`
+	MaxVersion tlsVersion `yaml:"max_version"`
+	// client certificate verification
+	ClientAuthType    clientAuthType `yaml:"client_auth_type"`
+	ClientCAFile      string         `yaml:"client_ca_file"`
+	ClientAllowedSANs []string       `yaml:"client_allowed_sans"`
+}
+
+// client certificate policy written as in the go crypto/tls package
+type clientAuthType tls.ClientAuthType
+
+// client certificate policies by their name in the web configuration file
+var clientAuthTypes = map[string]clientAuthType{
+	"NoClientCert":               clientAuthType(tls.NoClientCert),
+	"RequestClientCert":          clientAuthType(tls.RequestClientCert),
+	"RequireAnyClientCert":       clientAuthType(tls.RequireAnyClientCert),
+	"VerifyClientCertIfGiven":    clientAuthType(tls.VerifyClientCertIfGiven),
+	"RequireAndVerifyClientCert": clientAuthType(tls.RequireAndVerifyClientCert),
+}
+
+// unmarshal implements yaml.Unmarshaler
+func (t *clientAuthType) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+	authType, ok := clientAuthTypes[name]
+	if !ok {
+		return fmt.Errorf("unknown client auth type %q", name)
+	}
+	*t = authType
+	return nil
+}
+
+// whether client certificates are verified against the client ca
+func (t clientAuthType) verifies() bool {
+	return t == clientAuthType(tls.VerifyClientCertIfGiven) || t == clientAuthType(tls.RequireAndVerifyClientCert)
+}
+
+// tls protocol version written as TLS10 to TLS13
+type tlsVersion uint16
+
+// tls versions by their name in the web configuration file
+var tlsVersions = map[string]tlsVersion{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// unmarshal implements yaml.Unmarshaler
+func (v *tlsVersion) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+	version, ok := tlsVersions[name]
+	if !ok {
+		return fmt.Errorf("unknown tls version %q", name)
+	}
+	*v = version
+	return nil
+}
+
+// load and validate a web configuration file, unknown keys are rejected so typos do not disable tls
+func loadWebConfig(path string) (*webConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &webConfig{
+		TLSServerConfig: webTLSConfig{MinVersion: tls.VersionTLS12},
+	}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := config.TLSServerConfig
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		return nil, errors.New("tls_server_config needs both cert_file and key_file")
+	}
+	if tlsConfig.MaxVersion != 0 && tlsConfig.MaxVersion < tlsConfig.MinVersion {
+		return nil, errors.New("tls_server_config max_version is lower than min_version")
+	}
+	if tlsConfig.ClientAuthType.verifies() && tlsConfig.ClientCAFile == "" {
+		return nil, errors.New("tls_server_config client_auth_type verifies client certificates but no client_ca_file is set")
+	}
+	if tlsConfig.ClientCAFile != "" && !tlsConfig.ClientAuthType.verifies() {
+		return nil, errors.New("tls_server_config client_ca_file is set but client_auth_type does not verify client certificates")
+	}
+	if len(tlsConfig.ClientAllowedSANs) > 0 && !tlsConfig.ClientAuthType.verifies() {
+		return nil, errors.New("tls_server_config client_allowed_sans needs a client_auth_type verifying client certificates")
+	}
+	if tlsConfig.CertFile == "" && (tlsConfig.ClientAuthType != 0 || tlsConfig.ClientCAFile != "") {
+		return nil, errors.New("tls_server_config client certificates need cert_file and key_file")
+	}
+	if tlsConfig.CertFile != "" {
+		if _, err := tlsConfig.load(); err != nil {
+			return nil, fmt.Errorf("tls_server_config: %w", err)
+		}
+	}
+	for username, hash := range config.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("basic_auth_users: password of %s is not a bcrypt hash: %w", username, err)
+		}
+	}
+
+	return config, nil
+}
+
+// serve http, or https when the web configuration sets a certificate
+func serveWeb(server *http.Server, config *webConfig) error {
+	if config == nil {
+		return server.ListenAndServe()
+	}
+
+	if len(config.BasicAuthUsers) > 0 {
+		handler := server.Handler
+		if handler == nil {
+			handler = http.DefaultServeMux
+		}
+		server.Handler = newBasicAuthHandler(handler, config.BasicAuthUsers)
+	}
+
+	if config.TLSServerConfig.CertFile == "" {
+		return server.ListenAndServe()
+	}
+
+	// the files are read again on every handshake, so renewed certificates and cas are used without a restart
+	tlsConfig := config.TLSServerConfig
+	server.TLSConfig = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return tlsConfig.load()
+		},
+	}
+	return server.ListenAndServeTLS("", "")
+}
+
+// build the tls configuration from the certificate, key and client ca files
+func (c webTLSConfig) load() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   uint16(c.MinVersion),
+		MaxVersion:   uint16(c.MaxVersion),
+		ClientAuth:   tls.ClientAuthType(c.ClientAuthType),
+	}
+
+	if c.ClientCAFile != "" {
+		content, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in %s", c.ClientCAFile)
+		}
+	}
+
+	// restrict verified client certificates to the allowed subject alternative names
+	if len(c.ClientAllowedSANs) > 0 {
+		config.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			if len(chains) == 0 {
+				return nil
+			}
+			client := chains[0][0]
+			names := append(slices.Clone(client.DNSNames), client.EmailAddresses...)
+			for _, uri := range client.URIs {
+				names = append(names, uri.String())
+			}
+			for _, ip := range client.IPAddresses {
+				names = append(names, ip.String())
+			}
+			for _, name := range names {
+				if slices.Contains(c.ClientAllowedSANs, name) {
+					return nil
+				}
+			}
+			return errors.New("client certificate has no allowed subject alternative name")
+		}
+	}
+
+	return config, nil
+}
+
+// handler requiring http basic authentication with bcrypt-hashed passwords
+type basicAuthHandler struct {
+	next  http.Handler
+	users map[string]string
+
+	// successful checks, bcrypt takes about a second per request on slow router cpus
+	mu    sync.Mutex
+	valid map[[sha256.Size]byte]bool
+}
+
+// create a new basic auth handler
+func newBasicAuthHandler(next http.Handler, users map[string]string) *basicAuthHandler {
+	return &basicAuthHandler{
+		next:  next,
+		users: users,
+		valid: make(map[[sha256.Size]byte]bool),
+	}
+}
+
+// serve implements http.Handler
+func (h *basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	if !ok || !h.authenticate(username, password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="OpenWRT Exporter", charset="UTF-8"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// check a username and password against the configured hashes
+func (h *basicAuthHandler) authenticate(username, password string) bool {
+	hash, known := h.users[username]
+	// unknown users are checked against a hash too, so response times do not reveal usernames
+	if !known {
+		hash = "$2a$10$ouBZT2SmG/ufmDvHTpOjM.52W.AuLd3gUBj.QEiiKrkje28KJX2om"
+	}
+
+	// the cache key covers the hash, so changed passwords are never served from the cache
+	key := sha256.Sum256([]byte(username + "\x00" + hash + "\x00" + password))
+	h.mu.Lock()
+	cached := h.valid[key]
+	h.mu.Unlock()
+	if cached {
+		return true
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil || !known {
+		return false
+	}
+
+	h.mu.Lock()
+	if len(h.valid) >= maxBasicAuthCache {
+		clear(h.valid)
+	}
+	h.valid[key] = true
+	h.mu.Unlock()
+	return true
+}